@@ -9,6 +9,7 @@ import (
 	"sipamit-be/api/app/repo"
 	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 	"time"
@@ -112,6 +113,9 @@ func (h *UserHandler) create(c echo.Context) error {
 
 	err = h.userRepo.InsertOne(user)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to insert user: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -257,6 +261,9 @@ func (h *UserHandler) editUserProfile(c echo.Context) error {
 
 	err = h.userRepo.UpdateOne(user)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update user: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -294,6 +301,10 @@ func (h *UserHandler) delete(c echo.Context) error {
 	}
 
 	user.IsDeleted = true
+	user.Deleted = &repo.ByAt{
+		ID: &nc.Claims.IDAsObjectID,
+		At: time.Now(),
+	}
 	err = h.userRepo.UpdateOne(user)
 	if err != nil {
 		log.Errorf("Failed to delete user: %v", err)
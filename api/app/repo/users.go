@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	_db "sipamit-be/internal/db"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
 	"time"
 )
@@ -18,6 +20,7 @@ type User struct {
 	Role      string        `json:"role" bson:"role"`
 	Inserted  ByAt          `json:"inserted,omitempty" bson:"inserted,omitempty"`
 	Updated   *ByAt         `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted   *ByAt         `json:"deleted,omitempty" bson:"deleted,omitempty"`
 	IsDeleted bool          `json:"-" bson:"is_deleted"`
 }
 
@@ -58,6 +61,11 @@ func NewUserRepository(db *mongo.Database) *UserCollRepository {
 	}
 }
 
+// EnsureIndexes creates the indexes of the users collection.
+func (r *UserCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("username"))
+}
+
 func (r *UserCollRepository) FindAll(cq *util.CommonQuery) (*[]User, error) {
 	var users []User
 	filter := bson.M{
@@ -170,3 +178,126 @@ func (r *UserCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
 	}
 	return count, nil
 }
+
+// FindAllDeleted returns the soft deleted users, latest deleted first.
+func (r *UserCollRepository) FindAllDeleted() (*[]User, error) {
+	var users []User
+	filter := bson.M{
+		"is_deleted": true,
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "deleted.at", Value: -1}, {Key: "_id", Value: -1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &users)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		return &[]User{}, nil
+	}
+	return &users, nil
+}
+
+func (r *UserCollRepository) FindDeletedByID(_id bson.ObjectID) (*User, error) {
+	var user *User
+	filter := bson.M{
+		"_id":        _id,
+		"is_deleted": true,
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FindDeletedBefore returns the ids of the users deleted before the given
+// date. Users deleted before the deleter was tracked are skipped until
+// InitDeletedAt dates them.
+func (r *UserCollRepository) FindDeletedBefore(before time.Time) ([]bson.ObjectID, error) {
+	filter := bson.M{
+		"is_deleted": true,
+		"deleted.at": bson.M{"$lt": before},
+	}
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var users []User
+	err = cur.All(context.TODO(), &users)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+func (r *UserCollRepository) Restore(_id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        _id,
+		"is_deleted": true,
+	}
+	update := bson.M{
+		"$set":   bson.M{"is_deleted": false},
+		"$unset": bson.M{"deleted": ""},
+	}
+
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// InitDeletedAt dates the users deleted before the deleter was tracked as
+// deleted now.
+func (r *UserCollRepository) InitDeletedAt() (int64, error) {
+	filter := bson.M{
+		"is_deleted": true,
+		"deleted.at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"deleted.at": time.Now()},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// Purge permanently removes a soft deleted user.
+func (r *UserCollRepository) Purge(_id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        _id,
+		"is_deleted": true,
+	}
+
+	res, err := r.coll.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"path/filepath"
+	"sipamit-be/api/attachment/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/storage"
+	"strings"
+)
+
+type AttachmentHandler struct {
+	attachmentRepo *repo.AttachmentCollRepository
+	docRepo        *repo3.DocRepository
+	storage        *storage.Storage
+}
+
+func NewAttachmentAPIHandler(e *echo.Echo, db *mongo.Database) *AttachmentHandler {
+	h := &AttachmentHandler{
+		attachmentRepo: repo.NewAttachmentRepository(db),
+		docRepo:        repo3.NewDocRepository(db),
+		storage:        storage.New(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	// leave room for the multipart encoding around the file
+	bodyLimit := middleware.BodyLimit(fmt.Sprintf("%dB", h.storage.MaxSize()+1<<20))
+
+	for _, device := range _const.Devices {
+		path := "/doc/" + _const.DeviceParam(device) + "/:id/attachments"
+
+		group.GET(path, func(c echo.Context) error {
+			return h.findAllByDoc(c, device)
+		})
+		group.POST(path, func(c echo.Context) error {
+			return h.create(c, device)
+		}, bodyLimit)
+	}
+
+	group.GET("/attachment/:id", h.download)
+	group.GET("/attachment/:id/thumbnail", h.thumbnail)
+
+	group.DELETE("/attachment/:id", h.delete)
+
+	return h
+}
+
+// findDoc returns the doc of the :id param.
+func (h *AttachmentHandler) findDoc(c echo.Context, device string) (*repo3.DeviceDoc, error) {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid document ID")
+	}
+
+	d, err := h.docRepo.FindOneByID(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Document not found")
+		}
+		log.Errorf("Failed to get %s doc: %v", device, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return d, nil
+}
+
+// findAllByDoc
+// @Tags Attachment
+// @Summary Get attachments of a document
+// @ID get-document-attachments
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Param checkpoint query string false "Only attachments of this checkpoint item"
+// @Router /api/doc/{type}/{id}/attachments [GET]
+// @Produce json
+// @Success 200
+func (h *AttachmentHandler) findAllByDoc(c echo.Context, device string) error {
+	d, err := h.findDoc(c, device)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := h.attachmentRepo.FindAllByDoc(device, d.ID, strings.TrimSpace(c.QueryParam("checkpoint")))
+	if err != nil {
+		log.Errorf("Failed to get attachments: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// create
+// @Tags Attachment
+// @Summary Attach a file to a document or one of its checkpoint items
+// @Description Submitted and approved documents take no new attachments.
+// @ID create-document-attachment
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Param file formData file true "Image or PDF file"
+// @Param checkpoint formData string false "Checkpoint item name"
+// @Accept multipart/form-data
+// @Router /api/doc/{type}/{id}/attachments [POST]
+// @Produce json
+// @Success 200
+func (h *AttachmentHandler) create(c echo.Context, device string) error {
+	nc := c.(*context.Context)
+
+	d, err := h.findDoc(c, device)
+	if err != nil {
+		return err
+	}
+
+	if d.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved document is read-only")
+	}
+	if d.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted document is under review, reject it to edit")
+	}
+
+	checkpoint := strings.TrimSpace(c.FormValue("checkpoint"))
+	if checkpoint != "" {
+		var found bool
+		for _, cp := range d.Checkpoint {
+			if strings.EqualFold(cp.Name, checkpoint) {
+				checkpoint = cp.Name
+				found = true
+				break
+			}
+		}
+		if !found {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown checkpoint")
+		}
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Failed to get attachment file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "File is required")
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		log.Errorf("Failed to open attachment file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	obj, err := h.storage.Put(file)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTooLarge):
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("File exceeds %d MB", h.storage.MaxSize()>>20))
+		case errors.Is(err, storage.ErrType):
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Only image and PDF files are allowed")
+		}
+		log.Errorf("Failed to store attachment: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	attachment := &repo.Attachment{
+		ID:          bson.NewObjectID(),
+		Device:      device,
+		DocID:       d.ID,
+		Checkpoint:  checkpoint,
+		Filename:    filepath.Base(fh.Filename),
+		ContentType: obj.ContentType,
+		Size:        obj.Size,
+		Key:         obj.Key,
+		ThumbKey:    obj.ThumbKey,
+		Thumbnail:   obj.ThumbKey != "",
+		Inserted:    nc.Claims.ByAt(),
+		IsDeleted:   false,
+	}
+
+	err = h.attachmentRepo.InsertOne(attachment)
+	if err != nil {
+		log.Errorf("Failed to create attachment: %v", err)
+		if err := h.storage.Delete(obj.Key, obj.ThumbKey); err != nil {
+			log.Errorf("Failed to delete stored attachment: %v", err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, attachment)
+}
+
+// download
+// @Tags Attachment
+// @Summary Download an attachment
+// @ID download-attachment
+// @Security ApiKeyAuth
+// @Param id path string true "Attachment ID"
+// @Router /api/attachment/{id} [GET]
+// @Produce octet-stream
+// @Success 200
+func (h *AttachmentHandler) download(c echo.Context) error {
+	attachment, err := h.findByID(c)
+	if err != nil {
+		return err
+	}
+
+	disposition := fmt.Sprintf(`inline; filename="%s"`, strings.ReplaceAll(attachment.Filename, `"`, ""))
+	return h.stream(c, attachment.Key, attachment.ContentType, disposition)
+}
+
+// thumbnail
+// @Tags Attachment
+// @Summary Download the thumbnail of an image attachment
+// @ID download-attachment-thumbnail
+// @Security ApiKeyAuth
+// @Param id path string true "Attachment ID"
+// @Router /api/attachment/{id}/thumbnail [GET]
+// @Produce jpeg
+// @Success 200
+func (h *AttachmentHandler) thumbnail(c echo.Context) error {
+	attachment, err := h.findByID(c)
+	if err != nil {
+		return err
+	}
+
+	if attachment.ThumbKey == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "Thumbnail not found")
+	}
+	return h.stream(c, attachment.ThumbKey, "image/jpeg", "inline")
+}
+
+// delete
+// @Tags Attachment
+// @Summary Delete an attachment
+// @Description Only the uploader or an admin can delete an attachment, and not one of a submitted or approved document.
+// @ID delete-attachment
+// @Security ApiKeyAuth
+// @Param id path string true "Attachment ID"
+// @Router /api/attachment/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *AttachmentHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	attachment, err := h.findByID(c)
+	if err != nil {
+		return err
+	}
+
+	uploader := attachment.Inserted.ID
+	if !nc.Claims.IsSuperAdminOrAdmin() && (uploader == nil || *uploader != nc.Claims.IDAsObjectID) {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the uploader or an admin can delete the attachment")
+	}
+
+	d, err := h.docRepo.FindOneByID(attachment.Device, attachment.DocID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Errorf("Failed to get %s doc: %v", attachment.Device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if d != nil && d.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved document is read-only")
+	}
+	if d != nil && d.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted document is under review, reject it to edit")
+	}
+
+	err = h.attachmentRepo.DeleteOneByID(attachment.ID)
+	if err != nil {
+		log.Errorf("Failed to delete attachment: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Attachment deleted")
+}
+
+func (h *AttachmentHandler) findByID(c echo.Context) (*repo.Attachment, error) {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	attachment, err := h.attachmentRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Attachment not found")
+		}
+		log.Errorf("Failed to get attachment: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return attachment, nil
+}
+
+func (h *AttachmentHandler) stream(c echo.Context, key, contentType, disposition string) error {
+	r, err := h.storage.Open(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "File not found")
+		}
+		log.Errorf("Failed to open attachment: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	defer r.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, disposition)
+	return c.Stream(http.StatusOK, contentType, r)
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/doc"
+)
+
+// Attachment is a file attached to a maintenance doc, or to one of its
+// checkpoint items when Checkpoint is set. The content lives in storage under
+// Key, and ThumbKey for image thumbnails.
+type Attachment struct {
+	ID          bson.ObjectID `json:"_id" bson:"_id"`
+	Device      string        `json:"device" bson:"device"`
+	DocID       bson.ObjectID `json:"doc_id" bson:"doc_id"`
+	Checkpoint  string        `json:"checkpoint,omitempty" bson:"checkpoint,omitempty"`
+	Filename    string        `json:"filename" bson:"filename"`
+	ContentType string        `json:"content_type" bson:"content_type"`
+	Size        int64         `json:"size" bson:"size"`
+	Key         string        `json:"-" bson:"key"`
+	ThumbKey    string        `json:"-" bson:"thumb_key,omitempty"`
+	Thumbnail   bool          `json:"thumbnail" bson:"thumbnail"`
+	Inserted    doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	IsDeleted   bool          `json:"-" bson:"is_deleted"`
+}
+
+type AttachmentCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewAttachmentRepository(db *mongo.Database) *AttachmentCollRepository {
+	return &AttachmentCollRepository{
+		coll: db.Collection("attachments"),
+	}
+}
+
+// FindAllByDoc returns the attachments of a doc, only those of one checkpoint
+// item when checkpoint is not empty.
+func (r *AttachmentCollRepository) FindAllByDoc(device string, docID bson.ObjectID, checkpoint string) (*[]Attachment, error) {
+	var attachments []Attachment
+	filter := bson.M{
+		"device":     device,
+		"doc_id":     docID,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if checkpoint != "" {
+		filter["checkpoint"] = checkpoint
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"inserted.at": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &attachments)
+	if err != nil {
+		return nil, err
+	}
+	if attachments == nil {
+		return &[]Attachment{}, nil
+	}
+	return &attachments, nil
+}
+
+func (r *AttachmentCollRepository) FindOneByID(id bson.ObjectID) (*Attachment, error) {
+	var attachment Attachment
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&attachment)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentCollRepository) InsertOne(attachment *Attachment) error {
+	_, err := r.coll.InsertOne(context.TODO(), attachment)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *AttachmentCollRepository) DeleteOneByID(id bson.ObjectID) error {
+	filter := bson.M{
+		"_id": id,
+	}
+
+	update := bson.M{
+		"$set": bson.M{"is_deleted": true},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindAllKeysByDoc returns the storage keys of every attachment of a doc,
+// deleted ones included.
+func (r *AttachmentCollRepository) FindAllKeysByDoc(device string, docID bson.ObjectID) ([]string, error) {
+	var attachments []Attachment
+	filter := bson.M{
+		"device": device,
+		"doc_id": docID,
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(attachments)*2)
+	for _, attachment := range attachments {
+		keys = append(keys, attachment.Key, attachment.ThumbKey)
+	}
+	return keys, nil
+}
+
+// DeleteAllByDoc permanently removes the attachment records of a doc. The
+// stored files are removed separately.
+func (r *AttachmentCollRepository) DeleteAllByDoc(device string, docID bson.ObjectID) error {
+	filter := bson.M{
+		"device": device,
+		"doc_id": docID,
+	}
+
+	_, err := r.coll.DeleteMany(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
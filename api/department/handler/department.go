@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"sipamit-be/api/department/repo"
+	repo2 "sipamit-be/api/device/repo"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+)
+
+type departmentForm struct {
+	Name       string              `json:"name" form:"name"`
+	CostCenter string              `json:"cost_center" form:"cost_center"`
+	Head       repo.DepartmentHead `json:"head" form:"head"`
+	Aliases    []string            `json:"aliases" form:"aliases"`
+}
+
+func newDepartmentForm(c echo.Context) (*departmentForm, error) {
+	f := new(departmentForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind department form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Name = strings.Join(strings.Fields(f.Name), " ")
+	if f.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	f.CostCenter = strings.TrimSpace(f.CostCenter)
+	f.Head.Name = strings.TrimSpace(f.Head.Name)
+	f.Head.Phone = strings.TrimSpace(f.Head.Phone)
+	f.Head.Email = strings.TrimSpace(f.Head.Email)
+	if f.Head.Email != "" && !strings.Contains(f.Head.Email, "@") {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid head email")
+	}
+
+	aliases := make([]string, 0, len(f.Aliases))
+	for _, alias := range f.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	f.Aliases = aliases
+	return f, nil
+}
+
+type DepartmentHandler struct {
+	departmentRepo *repo.DepartmentCollRepository
+	deviceRepo     *repo2.DeviceRepository
+}
+
+func NewDepartmentAPIHandler(e *echo.Echo, db *mongo.Database) *DepartmentHandler {
+	h := &DepartmentHandler{
+		departmentRepo: repo.NewDepartmentRepository(db),
+		deviceRepo:     repo2.NewDeviceRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/departments", h.findAll)
+	group.GET("/department/:id", h.findOne)
+
+	group.POST("/department", h.create, context.AdminOrSuperAdminOnly)
+
+	group.PUT("/department/:id", h.update, context.AdminOrSuperAdminOnly)
+
+	group.DELETE("/department/:id", h.delete, context.AdminOrSuperAdminOnly)
+
+	return h
+}
+
+// findAll
+// @Tags Department
+// @Summary Get all departments
+// @ID get-all-departments
+// @Security ApiKeyAuth
+// @Param q query string false "Search by name, cost center or alias"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Router /api/departments [GET]
+// @Produce json
+// @Success 200
+func (h *DepartmentHandler) findAll(c echo.Context) error {
+	cq := util.NewCommonQuery(c)
+
+	departments, err := h.departmentRepo.FindAll(cq)
+	if err != nil {
+		log.Errorf("Failed to get departments: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	total, err := h.departmentRepo.CountQuery(cq)
+	if err != nil {
+		log.Errorf("Failed to count departments: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(departments, total, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// findOne
+// @Tags Department
+// @Summary Get department by id
+// @ID get-department-by-id
+// @Security ApiKeyAuth
+// @Param id path string true "Department ID"
+// @Router /api/department/{id} [GET]
+// @Produce json
+// @Success 200
+func (h *DepartmentHandler) findOne(c echo.Context) error {
+	department, err := h.findDepartment(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, department)
+}
+
+// create
+// @Tags Department
+// @Summary Create new department
+// @ID create-department
+// @Security ApiKeyAuth
+// @Param body body departmentForm true "Department Form"
+// @Router /api/department [POST]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *DepartmentHandler) create(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := newDepartmentForm(c)
+	if err != nil {
+		return err
+	}
+
+	department := &repo.Department{
+		ID:         bson.NewObjectID(),
+		Name:       f.Name,
+		CostCenter: f.CostCenter,
+		Head:       f.Head,
+		Aliases:    f.Aliases,
+		Inserted:   nc.Claims.ByAt(),
+		IsDeleted:  false,
+	}
+
+	err = h.checkName(department)
+	if err != nil {
+		return err
+	}
+
+	err = h.departmentRepo.InsertOne(department)
+	if err != nil {
+		log.Errorf("Failed to create department: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, department)
+}
+
+// update
+// @Tags Department
+// @Summary Update department by id, renaming the departemen of its devices
+// @ID update-department
+// @Security ApiKeyAuth
+// @Param id path string true "Department ID"
+// @Param body body departmentForm true "Department Form"
+// @Router /api/department/{id} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *DepartmentHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	department, err := h.findDepartment(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	f, err := newDepartmentForm(c)
+	if err != nil {
+		return err
+	}
+
+	renamed := department.Name != f.Name
+
+	department.Name = f.Name
+	department.CostCenter = f.CostCenter
+	department.Head = f.Head
+	department.Aliases = f.Aliases
+	department.Updated = nc.Claims.ByAtPtr()
+
+	err = h.checkName(department)
+	if err != nil {
+		return err
+	}
+
+	err = h.departmentRepo.UpdateOneByID(department.ID, department)
+	if err != nil {
+		log.Errorf("Failed to update department: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if renamed {
+		err = h.deviceRepo.SetDepartemen(department.ID, department.Name)
+		if err != nil {
+			log.Errorf("Failed to rename departemen of %s devices: %v", department.Name, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+	return c.JSON(http.StatusOK, department)
+}
+
+// delete
+// @Tags Department
+// @Summary Delete a department without devices
+// @ID delete-department
+// @Security ApiKeyAuth
+// @Param id path string true "Department ID"
+// @Router /api/department/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *DepartmentHandler) delete(c echo.Context) error {
+	department, err := h.findDepartment(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	devices, err := h.deviceRepo.CountByDepartment(department.ID)
+	if err != nil {
+		log.Errorf("Failed to count %s devices: %v", department.Name, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if devices > 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Department still has %d devices", devices))
+	}
+
+	err = h.departmentRepo.DeleteOneByID(department.ID)
+	if err != nil {
+		log.Errorf("Failed to delete department: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Department deleted")
+}
+
+func (h *DepartmentHandler) findDepartment(id string) (*repo.Department, error) {
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid department ID")
+	}
+
+	department, err := h.departmentRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Department not found")
+		}
+		log.Errorf("Failed to get department: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return department, nil
+}
+
+// checkName rejects a department named like another one.
+func (h *DepartmentHandler) checkName(department *repo.Department) error {
+	exists, err := h.departmentRepo.ExistsName(department.Name, department.ID)
+	if err != nil {
+		log.Errorf("Failed to check department name: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if exists {
+		return echo.NewHTTPError(http.StatusConflict, "Department "+department.Name+" already exists")
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"regexp"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+	"time"
+)
+
+// DepartmentHead is the contact of the head of a department.
+type DepartmentHead struct {
+	Name  string `json:"name" bson:"name"`
+	Phone string `json:"phone,omitempty" bson:"phone,omitempty"`
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+}
+
+// Department is referenced by printers, telepons and UPS. Aliases are other
+// spellings of the name found in the free text departemen of devices.
+type Department struct {
+	ID         bson.ObjectID  `json:"_id" bson:"_id"`
+	Name       string         `json:"name" bson:"name"`
+	CostCenter string         `json:"cost_center" bson:"cost_center"`
+	Head       DepartmentHead `json:"head" bson:"head"`
+	Aliases    []string       `json:"aliases,omitempty" bson:"aliases,omitempty"`
+	Inserted   doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated    *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+	ModifiedAt time.Time      `json:"modified_at" bson:"modified_at"`
+	IsDeleted  bool           `json:"-" bson:"is_deleted"`
+}
+
+// Ref returns the id devices store to reference the department, or nil for
+// no department.
+func (d *Department) Ref() *bson.ObjectID {
+	if d == nil {
+		return nil
+	}
+	return &d.ID
+}
+
+type DepartmentCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewDepartmentRepository(db *mongo.Database) *DepartmentCollRepository {
+	return &DepartmentCollRepository{
+		coll: db.Collection("departments"),
+	}
+}
+
+func (r *DepartmentCollRepository) filter(cq *util.CommonQuery) bson.M {
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if len(cq.Q) > 0 {
+		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": bson.M{"$regex": pattern}},
+			bson.M{"cost_center": bson.M{"$regex": pattern}},
+			bson.M{"aliases": bson.M{"$regex": pattern}},
+		}
+	}
+	return filter
+}
+
+func (r *DepartmentCollRepository) FindAll(cq *util.CommonQuery) (*[]Department, error) {
+	var departments []Department
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"name": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), r.filter(cq), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &departments)
+	if err != nil {
+		return nil, err
+	}
+	if departments == nil {
+		return &[]Department{}, nil
+	}
+	return &departments, nil
+}
+
+func (r *DepartmentCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	count, err := r.coll.CountDocuments(context.TODO(), r.filter(cq))
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindAllSorted returns every department ordered by name.
+func (r *DepartmentCollRepository) FindAllSorted() ([]Department, error) {
+	var departments []Department
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &departments)
+	if err != nil {
+		return nil, err
+	}
+	return departments, nil
+}
+
+func (r *DepartmentCollRepository) FindOneByID(id bson.ObjectID) (*Department, error) {
+	var department Department
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&department)
+	if err != nil {
+		return nil, err
+	}
+	return &department, nil
+}
+
+// ExistsName reports whether another department already has the name,
+// ignoring case.
+func (r *DepartmentCollRepository) ExistsName(name string, exclude bson.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$ne": exclude},
+		"name":       bson.Regex{Pattern: "^" + regexp.QuoteMeta(strings.TrimSpace(name)) + "$", Options: "i"},
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *DepartmentCollRepository) InsertOne(department *Department) error {
+	department.ModifiedAt = doc.Stamp()
+	_, err := r.coll.InsertOne(context.TODO(), department)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DepartmentCollRepository) UpdateOneByID(id bson.ObjectID, department *Department) error {
+	department.ModifiedAt = doc.Stamp()
+
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": department,
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DepartmentCollRepository) DeleteOneByID(id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  true,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
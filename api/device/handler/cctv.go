@@ -7,15 +7,19 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type cctvForm struct {
-	Nama   string `form:"nama" json:"nama"`
-	Lokasi string `form:"lokasi" json:"lokasi" `
-	Kode   string `form:"kode" json:"kode"`
+	Nama       string `form:"nama" json:"nama"`
+	Lokasi     string `form:"lokasi" json:"lokasi" `
+	LocationID string `form:"location_id" json:"location_id"`
+	Kode       string `form:"kode" json:"kode"`
 }
 
 func newCCTVForm(c echo.Context) (*cctvForm, error) {
@@ -25,7 +29,7 @@ func newCCTVForm(c echo.Context) (*cctvForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" {
+	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" && f.LocationID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -33,12 +37,14 @@ func newCCTVForm(c echo.Context) (*cctvForm, error) {
 }
 
 type CCTVHandler struct {
-	cctvRepo *repo.CCTVCollRepository
+	cctvRepo     *repo.CCTVCollRepository
+	locationRepo *repo2.LocationCollRepository
 }
 
 func NewCCTVAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVHandler {
 	h := &CCTVHandler{
-		cctvRepo: repo.NewCCTVRepository(db),
+		cctvRepo:     repo.NewCCTVRepository(db),
+		locationRepo: repo2.NewLocationRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -61,6 +67,8 @@ func NewCCTVAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVHandler {
 // @ID get-all-cctvs
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -68,7 +76,15 @@ func NewCCTVAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVHandler {
 // @Produce json
 // @Success 200
 func (h *CCTVHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	cctvs, err := h.cctvRepo.FindAll(cq)
 	if err != nil {
@@ -138,6 +154,14 @@ func (h *CCTVHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nama is required")
 	}
@@ -149,16 +173,21 @@ func (h *CCTVHandler) create(c echo.Context) error {
 	}
 
 	cctv := &repo.CCTV{
-		ID:        bson.NewObjectID(),
-		Nama:      f.Nama,
-		Lokasi:    f.Lokasi,
-		Kode:      f.Kode,
-		Inserted:  nc.Claims.ByAt(),
-		IsDeleted: false,
+		ID:         bson.NewObjectID(),
+		Nama:       f.Nama,
+		Lokasi:     f.Lokasi,
+		Kode:       f.Kode,
+		LocationID: location.Ref(),
+		Status:     _const.DeviceActive,
+		Inserted:   nc.Claims.ByAt(),
+		IsDeleted:  false,
 	}
 
 	err = h.cctvRepo.InsertOne(cctv)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create cctv: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -199,6 +228,15 @@ func (h *CCTVHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "CCTV not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	cctv.LocationID = locationRef(location, f.Lokasi, cctv.Lokasi, cctv.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama != "" {
 		cctv.Nama = f.Nama
 	}
@@ -212,6 +250,9 @@ func (h *CCTVHandler) update(c echo.Context) error {
 	cctv.Updated = nc.Claims.ByAtPtr()
 	err = h.cctvRepo.UpdateOneByID(oId, cctv)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update cctv: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -228,6 +269,8 @@ func (h *CCTVHandler) update(c echo.Context) error {
 // @Param id path string true "CCTV ID"
 // @Success 200
 func (h *CCTVHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -241,7 +284,7 @@ func (h *CCTVHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "CCTV not found")
 	}
 
-	err = h.cctvRepo.DeleteOneByID(oId)
+	err = h.cctvRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete cctv: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
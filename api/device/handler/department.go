@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo3 "sipamit-be/api/department/repo"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+)
+
+// findDepartment returns the department of the department_id form field, or
+// nil when it is empty.
+func findDepartment(departmentRepo *repo3.DepartmentCollRepository, id string) (*repo3.Department, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid department ID")
+	}
+
+	department, err := departmentRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Department not found")
+		}
+		log.Errorf("Failed to get department: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return department, nil
+}
+
+// departmentRef returns the department referenced by a device updated with
+// the form: the one of department_id, none when the free text departemen is
+// changed, or the current one otherwise.
+func departmentRef(department *repo3.Department, departemen, current string, ref *bson.ObjectID) *bson.ObjectID {
+	if department != nil {
+		return &department.ID
+	}
+	if departemen != "" && !strings.EqualFold(strings.TrimSpace(departemen), strings.TrimSpace(current)) {
+		return nil
+	}
+	return ref
+}
+
+// scopeDepartment restricts the query to the department param.
+func scopeDepartment(departmentRepo *repo3.DepartmentCollRepository, cq *util.CommonQuery) error {
+	department, err := findDepartment(departmentRepo, cq.Department)
+	if err != nil {
+		return err
+	}
+	cq.DepartmentID = department.Ref()
+	return nil
+}
@@ -1,94 +1,247 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
+	repo3 "sipamit-be/api/department/repo"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
 	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"strings"
 )
 
+type statusForm struct {
+	Status string `json:"status" form:"status"`
+	Reason string `json:"reason" form:"reason"`
+}
+
+func newStatusForm(c echo.Context) (*statusForm, error) {
+	f := new(statusForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind status form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	if !_const.ValidDeviceStatus(f.Status) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid status")
+	}
+
+	f.Reason = strings.TrimSpace(f.Reason)
+	if f.Reason == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Reason is required")
+	}
+	return f, nil
+}
+
 type DeviceHandler struct {
-	cctvRepo    *repo.CCTVCollRepository
-	fpRepo      *repo.FingerPrintCollRepository
-	kph1Repo    *repo.KomputerPH1CollRepository
-	kph2Repo    *repo.KomputerPH2CollRepository
-	printerRepo *repo.PrinterCollRepository
-	teleponRepo *repo.TeleponCollRepository
-	toaRepo     *repo.TOACollRepository
-	upsRepo     *repo.UPSCollRepository
+	cctvRepo       *repo.CCTVCollRepository
+	fpRepo         *repo.FingerPrintCollRepository
+	kph1Repo       *repo.KomputerPH1CollRepository
+	kph2Repo       *repo.KomputerPH2CollRepository
+	printerRepo    *repo.PrinterCollRepository
+	teleponRepo    *repo.TeleponCollRepository
+	toaRepo        *repo.TOACollRepository
+	upsRepo        *repo.UPSCollRepository
+	deviceRepo     *repo.DeviceRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
 }
 
 func NewDeviceAPIHandler(e *echo.Echo, db *mongo.Database) *DeviceHandler {
 	h := &DeviceHandler{
-		cctvRepo:    repo.NewCCTVRepository(db),
-		fpRepo:      repo.NewFingerPrintRepository(db),
-		kph1Repo:    repo.NewKomputerPH1Repository(db),
-		kph2Repo:    repo.NewKomputerPH2Repository(db),
-		printerRepo: repo.NewPrinterRepository(db),
-		teleponRepo: repo.NewTeleponRepository(db),
-		toaRepo:     repo.NewTOARepository(db),
-		upsRepo:     repo.NewUPSRepository(db),
+		cctvRepo:       repo.NewCCTVRepository(db),
+		fpRepo:         repo.NewFingerPrintRepository(db),
+		kph1Repo:       repo.NewKomputerPH1Repository(db),
+		kph2Repo:       repo.NewKomputerPH2Repository(db),
+		printerRepo:    repo.NewPrinterRepository(db),
+		teleponRepo:    repo.NewTeleponRepository(db),
+		toaRepo:        repo.NewTOARepository(db),
+		upsRepo:        repo.NewUPSRepository(db),
+		deviceRepo:     repo.NewDeviceRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/device/count", h.count)
+	group.GET("/devices/search", h.search)
+
+	group.PUT("/device/:device/:id/status", h.updateStatus)
 
 	return h
 }
 
 // count
 // @Tags Device
-// @Summary Count all devices
+// @Summary Count devices in service, or in the given lifecycle status
 // @ID device-count
 // @Security ApiKeyAuth
 // @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param status query string false "Lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Location ID, including the locations under it"
+// @Param department query string false "Department ID"
 // @Router /api/device/count [GET]
 // @Produce json
 // @Success 200
 func (h *DeviceHandler) count(c echo.Context) error {
 	var total int64 = 0
-	param := util.NewCommonQuery(c)
+	param, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, param)
+	if err != nil {
+		return err
+	}
+
+	err = scopeDepartment(h.departmentRepo, param)
+	if err != nil {
+		return err
+	}
 
 	switch param.Device {
 	case _const.CCTV:
-		cctvs, _ := h.cctvRepo.Count()
+		cctvs, _ := h.cctvRepo.CountInventory(param)
 		total += cctvs
 	case _const.Fingerprint:
-		fps, _ := h.fpRepo.Count()
+		fps, _ := h.fpRepo.CountInventory(param)
 		total += fps
 	case _const.KomputerPH1:
-		kph1s, _ := h.kph1Repo.Count()
+		kph1s, _ := h.kph1Repo.CountInventory(param)
 		total += kph1s
 	case _const.KomputerPH2:
-		kph2s, _ := h.kph2Repo.Count()
+		kph2s, _ := h.kph2Repo.CountInventory(param)
 		total += kph2s
 	case _const.Printer:
-		printers, _ := h.printerRepo.Count()
+		printers, _ := h.printerRepo.CountInventory(param)
 		total += printers
 	case _const.Telepon:
-		telepons, _ := h.teleponRepo.Count()
+		telepons, _ := h.teleponRepo.CountInventory(param)
 		total += telepons
 	case _const.Toa:
-		toas, _ := h.toaRepo.Count()
+		toas, _ := h.toaRepo.CountInventory(param)
 		total += toas
 	case _const.Ups:
-		upss, _ := h.upsRepo.Count()
+		upss, _ := h.upsRepo.CountInventory(param)
 		total += upss
 	default:
-		cctvs, _ := h.cctvRepo.Count()
-		fps, _ := h.fpRepo.Count()
-		kph1s, _ := h.kph1Repo.Count()
-		kph2s, _ := h.kph2Repo.Count()
-		printers, _ := h.printerRepo.Count()
-		telepons, _ := h.teleponRepo.Count()
-		toas, _ := h.toaRepo.Count()
-		upss, _ := h.upsRepo.Count()
+		cctvs, _ := h.cctvRepo.CountInventory(param)
+		fps, _ := h.fpRepo.CountInventory(param)
+		kph1s, _ := h.kph1Repo.CountInventory(param)
+		kph2s, _ := h.kph2Repo.CountInventory(param)
+		printers, _ := h.printerRepo.CountInventory(param)
+		telepons, _ := h.teleponRepo.CountInventory(param)
+		toas, _ := h.toaRepo.CountInventory(param)
+		upss, _ := h.upsRepo.CountInventory(param)
 
 		total = cctvs + fps + kph1s + kph2s + printers + telepons + toas + upss
 	}
 	return c.JSON(http.StatusOK, map[string]int64{"total": total})
 }
+
+// search
+// @Tags Device
+// @Summary Search devices of every type by asset tag, nama, kode, no seri, ext, user, lokasi and departemen
+// @ID device-search
+// @Security ApiKeyAuth
+// @Param q query string true "Search text"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Router /api/devices/search [GET]
+// @Produce json
+// @Success 200
+func (h *DeviceHandler) search(c echo.Context) error {
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Search text is required")
+	}
+
+	param := util.NewCommonQuery(c)
+
+	types := _const.Devices
+	if param.Device != "" {
+		types = []string{param.Device}
+	}
+
+	results, total, err := h.deviceRepo.Search(q, types, param.Page, param.Limit)
+	if err != nil {
+		log.Errorf("Failed to search devices: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(results, total, param.Page, param.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// updateStatus
+// @Tags Device
+// @Summary Move a device to another lifecycle status
+// @Description Allowed transitions: active to in_repair, spare or retired; in_repair and spare back to active or to retired; retired to disposed.
+// @ID update-device-status
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Param body body statusForm true "Status Form"
+// @Router /api/device/{device}/{id}/status [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *DeviceHandler) updateStatus(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	f, err := newStatusForm(c)
+	if err != nil {
+		return err
+	}
+
+	status, err := h.deviceRepo.FindStatus(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, _const.DeviceLabels[device]+" not found")
+		}
+		log.Errorf("Failed to get %s status: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if !_const.CanTransition(status, f.Status) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Cannot move %s from %s to %s", _const.DeviceLabels[device], status, f.Status))
+	}
+
+	change := doc.StatusChange{
+		From:    status,
+		To:      f.Status,
+		Reason:  f.Reason,
+		Changed: nc.Claims.ByAt(),
+	}
+	err = h.deviceRepo.SetStatus(device, oId, change)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, _const.DeviceLabels[device]+" status was changed, please reload")
+		}
+		log.Errorf("Failed to update %s status: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, change)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	repo3 "sipamit-be/api/department/repo"
+	"sipamit-be/api/device/repo"
+	repo4 "sipamit-be/api/device_type/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/sheet"
+	"sipamit-be/internal/pkg/util"
+	"slices"
+	"time"
+)
+
+// exportDevice holds the fields every device type shares, read alongside the
+// schema fields of the type.
+type exportDevice struct {
+	AssetTag string    `bson:"asset_tag"`
+	Status   string    `bson:"status"`
+	Inserted doc.ByAt  `bson:"inserted"`
+	Updated  *doc.ByAt `bson:"updated"`
+}
+
+type ExportHandler struct {
+	deviceRepo     *repo.DeviceRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
+}
+
+func NewExportAPIHandler(e *echo.Echo, db *mongo.Database) *ExportHandler {
+	h := &ExportHandler{
+		deviceRepo:     repo.NewDeviceRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	for _, device := range _const.Devices {
+		group.GET("/"+_const.DeviceParam(device)+"/export", func(c echo.Context) error {
+			return h.export(c, device)
+		})
+	}
+
+	return h
+}
+
+// export
+// @Tags Export
+// @Summary Export the devices of a type matching the list filters as CSV or XLSX
+// @ID export-devices
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param format query string false "File format" enums(csv, xlsx) default(csv)
+// @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
+// @Param department query string false "Filter by department ID, for printer, telepon and ups"
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Router /api/{type}/export [GET]
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200
+func (h *ExportHandler) export(c echo.Context, device string) error {
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(_const.DepartmentDevices, device) {
+		err = scopeDepartment(h.departmentRepo, cq)
+		if err != nil {
+			return err
+		}
+	}
+
+	deviceType, _ := repo4.BuiltInType(device)
+	header := []string{"Asset Tag"}
+	for _, field := range deviceType.Fields {
+		header = append(header, field.Label)
+	}
+	header = append(header, "Status", "Created At", "Created By", "Updated At", "Updated By")
+
+	names := doc.UserNames{}
+	filename := fmt.Sprintf("%s_%s", device, time.Now().Format("2006-01-02"))
+	return sheet.Export(c, filename, header, func(write func([]string) error) error {
+		return h.deviceRepo.Each(device, cq, func(raw bson.Raw) error {
+			var d exportDevice
+			if err := bson.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+
+			row := []string{d.AssetTag}
+			for _, field := range deviceType.Fields {
+				value, _ := raw.Lookup(field.Name).StringValueOK()
+				row = append(row, value)
+			}
+
+			var updatedAt time.Time
+			if d.Updated != nil {
+				updatedAt = d.Updated.At
+			}
+			row = append(row,
+				doc.DeviceStatus(d.Status),
+				sheet.Time(d.Inserted.At),
+				names.FullName(&d.Inserted),
+				sheet.Time(updatedAt),
+				names.FullName(d.Updated),
+			)
+			return write(row)
+		})
+	})
+}
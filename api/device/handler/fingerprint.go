@@ -7,15 +7,19 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type fingerPrintForm struct {
-	Nama   string `form:"nama" json:"nama"`
-	Lokasi string `form:"lokasi" json:"lokasi" `
-	Kode   string `form:"kode" json:"kode"`
+	Nama       string `form:"nama" json:"nama"`
+	Lokasi     string `form:"lokasi" json:"lokasi" `
+	LocationID string `form:"location_id" json:"location_id"`
+	Kode       string `form:"kode" json:"kode"`
 }
 
 func newFingerPrintForm(c echo.Context) (*fingerPrintForm, error) {
@@ -25,7 +29,7 @@ func newFingerPrintForm(c echo.Context) (*fingerPrintForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" {
+	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" && f.LocationID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -33,12 +37,14 @@ func newFingerPrintForm(c echo.Context) (*fingerPrintForm, error) {
 }
 
 type FingerPrintHandler struct {
-	fpRepo *repo.FingerPrintCollRepository
+	fpRepo       *repo.FingerPrintCollRepository
+	locationRepo *repo2.LocationCollRepository
 }
 
 func NewFingerPrintAPIHandler(e *echo.Echo, db *mongo.Database) *FingerPrintHandler {
 	h := &FingerPrintHandler{
-		fpRepo: repo.NewFingerPrintRepository(db),
+		fpRepo:       repo.NewFingerPrintRepository(db),
+		locationRepo: repo2.NewLocationRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -61,6 +67,8 @@ func NewFingerPrintAPIHandler(e *echo.Echo, db *mongo.Database) *FingerPrintHand
 // @ID get-all-fingerprints
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -68,7 +76,15 @@ func NewFingerPrintAPIHandler(e *echo.Echo, db *mongo.Database) *FingerPrintHand
 // @Produce json
 // @Success 200
 func (h *FingerPrintHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	fingerprints, err := h.fpRepo.FindAll(cq)
 	if err != nil {
@@ -138,6 +154,14 @@ func (h *FingerPrintHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nama is required")
 	}
@@ -149,16 +173,21 @@ func (h *FingerPrintHandler) create(c echo.Context) error {
 	}
 
 	fp := &repo.FingerPrint{
-		ID:        bson.NewObjectID(),
-		Nama:      f.Nama,
-		Lokasi:    f.Lokasi,
-		Kode:      f.Kode,
-		Inserted:  nc.Claims.ByAt(),
-		IsDeleted: false,
+		ID:         bson.NewObjectID(),
+		Nama:       f.Nama,
+		Lokasi:     f.Lokasi,
+		Kode:       f.Kode,
+		LocationID: location.Ref(),
+		Status:     _const.DeviceActive,
+		Inserted:   nc.Claims.ByAt(),
+		IsDeleted:  false,
 	}
 
 	err = h.fpRepo.InsertOne(fp)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create fingerprint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -199,6 +228,15 @@ func (h *FingerPrintHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Fingerprint not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	fp.LocationID = locationRef(location, f.Lokasi, fp.Lokasi, fp.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama != "" {
 		fp.Nama = f.Nama
 	}
@@ -212,6 +250,9 @@ func (h *FingerPrintHandler) update(c echo.Context) error {
 	fp.Updated = nc.Claims.ByAtPtr()
 	err = h.fpRepo.UpdateOneByID(oId, fp)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update fingerprint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -228,6 +269,8 @@ func (h *FingerPrintHandler) update(c echo.Context) error {
 // @Param id path string true "Fingerprint ID"
 // @Success 200
 func (h *FingerPrintHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -241,7 +284,7 @@ func (h *FingerPrintHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Fingerprint not found")
 	}
 
-	err = h.fpRepo.DeleteOneByID(oId)
+	err = h.fpRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete fingerprint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
@@ -0,0 +1,403 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo3 "sipamit-be/api/department/repo"
+	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/device_type/repo"
+	repo4 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/sheet"
+	"sipamit-be/internal/pkg/util"
+	"slices"
+	"strings"
+)
+
+const (
+	importDryRun = "dry-run"
+	importCommit = "commit"
+)
+
+// importKeys are the fields identifying a device of each type, matching its
+// unique index. Komputer records have none.
+var importKeys = map[string][]string{
+	_const.CCTV:        {"kode"},
+	_const.Fingerprint: {"kode"},
+	_const.Printer:     {"no_seri"},
+	_const.Telepon:     {"lokasi", "ext"},
+	_const.Toa:         {"kode"},
+	_const.Ups:         {"no_seri"},
+}
+
+type importRowError struct {
+	Row    int                `json:"row"`
+	Errors []repo2.FieldError `json:"errors"`
+}
+
+// importUnmatched is the lokasi or departemen of a valid row matching no
+// location or department, or several ones, imported as free text without a
+// reference.
+type importUnmatched struct {
+	Row        int      `json:"row"`
+	Field      string   `json:"field"`
+	Value      string   `json:"value"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+type importReport struct {
+	Device    string            `json:"device"`
+	Mode      string            `json:"mode"`
+	Mapping   map[string]string `json:"mapping"`
+	Total     int               `json:"total"`
+	Valid     int               `json:"valid"`
+	Invalid   int               `json:"invalid"`
+	Inserted  int               `json:"inserted"`
+	Errors    []importRowError  `json:"errors"`
+	Unmatched []importUnmatched `json:"unmatched"`
+}
+
+// importRefs holds the locations and departments by the keys their lokasi and
+// departemen are matched with: the name, an alias or, for a location, the
+// path, as the lokasi and departemen migrations map existing devices.
+type importRefs struct {
+	locations   map[string][]*repo4.Location
+	departments map[string][]*repo3.Department
+}
+
+// resolve sets the location_id and department_id of a valid row from its
+// lokasi and departemen, replacing them by the name of the location and
+// department like the create handlers do, and returns the values left
+// unmatched.
+func (refs *importRefs) resolve(number int, values map[string]interface{}) []importUnmatched {
+	var unmatched []importUnmatched
+
+	if lokasi, _ := values["lokasi"].(string); lokasi != "" {
+		matches := refs.locations[util.NameKey(lokasi)]
+		if len(matches) == 1 {
+			values["location_id"] = &matches[0].ID
+			values["lokasi"] = matches[0].Name
+		} else {
+			u := importUnmatched{Row: number, Field: "lokasi", Value: lokasi}
+			for _, location := range matches {
+				u.Candidates = append(u.Candidates, location.Path)
+			}
+			unmatched = append(unmatched, u)
+		}
+	}
+
+	if departemen, _ := values["departemen"].(string); departemen != "" {
+		matches := refs.departments[util.NameKey(departemen)]
+		if len(matches) == 1 {
+			values["department_id"] = &matches[0].ID
+			values["departemen"] = matches[0].Name
+		} else {
+			u := importUnmatched{Row: number, Field: "departemen", Value: departemen}
+			for _, department := range matches {
+				u.Candidates = append(u.Candidates, department.Name)
+			}
+			unmatched = append(unmatched, u)
+		}
+	}
+	return unmatched
+}
+
+type ImportHandler struct {
+	deviceRepo     *repo.DeviceRepository
+	locationRepo   *repo4.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
+}
+
+func NewImportAPIHandler(e *echo.Echo, db *mongo.Database) *ImportHandler {
+	h := &ImportHandler{
+		deviceRepo:     repo.NewDeviceRepository(db),
+		locationRepo:   repo4.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.POST("/:type/import", h.importDevices)
+
+	return h
+}
+
+// normalizeHeader reads a column header or field label as a field name, e.g.
+// "No Seri" as "no_seri".
+func normalizeHeader(header string) string {
+	header = strings.ToLower(strings.Join(strings.Fields(header), " "))
+	return strings.NewReplacer(" ", "_", "-", "_", ".", "").Replace(header)
+}
+
+// importColumns resolves the column of every field of the type, from the
+// submitted mapping of field to header or else from a header matching the
+// field name or label.
+func importColumns(deviceType *repo2.DeviceType, header []string, mapping map[string]string) (map[string]int, error) {
+	headers := make(map[string]int, len(header))
+	for i, h := range header {
+		if _, ok := headers[normalizeHeader(h)]; !ok {
+			headers[normalizeHeader(h)] = i
+		}
+	}
+
+	var errs []repo2.FieldError
+	for name := range mapping {
+		if _, ok := deviceType.Field(name); !ok {
+			errs = append(errs, repo2.FieldError{Field: name, Message: "Unknown field"})
+		}
+	}
+
+	columns := make(map[string]int, len(deviceType.Fields))
+	for _, field := range deviceType.Fields {
+		if h, ok := mapping[field.Name]; ok {
+			i, ok := headers[normalizeHeader(h)]
+			if !ok {
+				errs = append(errs, repo2.FieldError{Field: field.Name, Message: fmt.Sprintf("Column %q not found", h)})
+				continue
+			}
+			columns[field.Name] = i
+			continue
+		}
+
+		if i, ok := headers[field.Name]; ok {
+			columns[field.Name] = i
+		} else if i, ok := headers[normalizeHeader(field.Label)]; ok {
+			columns[field.Name] = i
+		} else if field.Required {
+			errs = append(errs, repo2.FieldError{Field: field.Name, Message: "No column for " + field.Label})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, repo2.FieldValidationError{
+			Message: "Invalid column mapping",
+			Errors:  errs,
+		})
+	}
+	return columns, nil
+}
+
+// findRefs returns the locations, and the departments for the types
+// referencing one, keyed for matching.
+func (h *ImportHandler) findRefs(device string) (*importRefs, error) {
+	refs := &importRefs{
+		locations:   map[string][]*repo4.Location{},
+		departments: map[string][]*repo3.Department{},
+	}
+
+	locations, err := h.locationRepo.FindAllSorted()
+	if err != nil {
+		return nil, err
+	}
+	for i := range locations {
+		location := &locations[i]
+		keys := append([]string{location.Name, location.Path}, location.Aliases...)
+		for _, key := range keys {
+			key = util.NameKey(key)
+			if key != "" && !slices.Contains(refs.locations[key], location) {
+				refs.locations[key] = append(refs.locations[key], location)
+			}
+		}
+	}
+
+	if !slices.Contains(_const.DepartmentDevices, device) {
+		return refs, nil
+	}
+
+	departments, err := h.departmentRepo.FindAllSorted()
+	if err != nil {
+		return nil, err
+	}
+	for i := range departments {
+		department := &departments[i]
+		keys := append([]string{department.Name}, department.Aliases...)
+		for _, key := range keys {
+			key = util.NameKey(key)
+			if key != "" && !slices.Contains(refs.departments[key], department) {
+				refs.departments[key] = append(refs.departments[key], department)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// importDevices
+// @Tags Device
+// @Summary Import devices from a CSV or XLSX file
+// @Description The first row holds the column headers. Columns are matched to fields by name or label unless mapped. A dry run only validates the rows; a commit inserts the valid ones. Lokasi and departemen reference the location and department matching them by name, alias or path; the values matching none or several are listed as unmatched and imported as free text.
+// @ID import-devices
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param file formData file true "CSV or XLSX file"
+// @Param mapping formData string false "JSON object of field name to column header, e.g. {\"no_seri\":\"Serial Number\"}"
+// @Param mode formData string false "Import mode" enums(dry-run, commit) default(dry-run)
+// @Accept multipart/form-data
+// @Router /api/{type}/import [POST]
+// @Produce json
+// @Success 200
+func (h *ImportHandler) importDevices(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	device := _const.DeviceFromParam(c.Param("type"))
+	if device == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+	deviceType, _ := repo2.BuiltInType(device)
+
+	mode := strings.ToLower(strings.TrimSpace(c.FormValue("mode")))
+	if mode == "" {
+		mode = importDryRun
+	}
+	if mode != importDryRun && mode != importCommit {
+		return echo.NewHTTPError(http.StatusBadRequest, "Mode must be dry-run or commit")
+	}
+
+	mapping := map[string]string{}
+	if m := strings.TrimSpace(c.FormValue("mapping")); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid mapping")
+		}
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		log.Errorf("Failed to get import file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "File is required")
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		log.Errorf("Failed to open import file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	rows, err := sheet.Read(fh.Filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, sheet.ErrTooLarge):
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("File exceeds %d MB", sheet.MaxSize>>20))
+		case errors.Is(err, sheet.ErrType):
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, "Only CSV and XLSX files are allowed")
+		case errors.Is(err, sheet.ErrRange):
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("File has cells beyond row %d or column %d", sheet.MaxRows, sheet.MaxColumns))
+		}
+		log.Errorf("Failed to read import file: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	if len(rows) < 2 {
+		return echo.NewHTTPError(http.StatusBadRequest, "File has no rows")
+	}
+
+	columns, err := importColumns(deviceType, rows[0], mapping)
+	if err != nil {
+		return err
+	}
+
+	report := &importReport{
+		Device:    device,
+		Mode:      mode,
+		Mapping:   make(map[string]string, len(columns)),
+		Errors:    []importRowError{},
+		Unmatched: []importUnmatched{},
+	}
+	for name, i := range columns {
+		report.Mapping[name] = rows[0][i]
+	}
+
+	keys := importKeys[device]
+	taken := map[string]bool{}
+	if keys != nil {
+		taken, err = h.deviceRepo.TakenKeys(device, keys)
+		if err != nil {
+			log.Errorf("Failed to get %s keys: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+	seen := map[string]int{}
+
+	refs, err := h.findRefs(device)
+	if err != nil {
+		log.Errorf("Failed to get locations and departments: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	var valid []map[string]interface{}
+	for i, row := range rows[1:] {
+		number := i + 2
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		report.Total++
+
+		values := make(map[string]interface{}, len(columns))
+		for name, column := range columns {
+			values[name] = row[column]
+		}
+
+		var errs []repo2.FieldError
+		result, err := deviceType.Validate(values)
+		if err != nil {
+			var he *echo.HTTPError
+			var fve repo2.FieldValidationError
+			if !errors.As(err, &he) {
+				return err
+			}
+			if fve, _ = he.Message.(repo2.FieldValidationError); len(fve.Errors) == 0 {
+				return err
+			}
+			errs = fve.Errors
+		} else if keys != nil {
+			key := make([]string, len(keys))
+			for j, name := range keys {
+				key[j], _ = result[name].(string)
+			}
+			k := repo.ImportKey(key)
+			field := keys[len(keys)-1]
+			label := _const.DeviceLabels[device]
+			if f, ok := deviceType.Field(field); ok {
+				label = f.Label
+			}
+
+			switch {
+			case taken[k]:
+				errs = append(errs, repo2.FieldError{Field: field, Message: label + " already exists"})
+			case seen[k] != 0:
+				errs = append(errs, repo2.FieldError{Field: field, Message: fmt.Sprintf("%s duplicates row %d", label, seen[k])})
+			default:
+				seen[k] = number
+			}
+		}
+
+		if len(errs) > 0 {
+			report.Invalid++
+			report.Errors = append(report.Errors, importRowError{Row: number, Errors: errs})
+			continue
+		}
+		report.Valid++
+		report.Unmatched = append(report.Unmatched, refs.resolve(number, result)...)
+		valid = append(valid, result)
+	}
+
+	if mode == importCommit && len(valid) > 0 {
+		err = h.deviceRepo.InsertRows(device, valid, nc.Claims.ByAt())
+		if err != nil {
+			if err := index.DuplicateError(err); err != nil {
+				return err
+			}
+			log.Errorf("Failed to import %s: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		report.Inserted = len(valid)
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -7,20 +7,23 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type komputerPH1Form struct {
-	Nama     string `form:"nama" json:"nama"`
-	Merk     string `form:"merk" json:"merk"`
-	PC       string `form:"pc" json:"pc"`
-	Monitor  string `form:"monitor" json:"monitor"`
-	CPU      string `form:"cpu" json:"cpu"`
-	RAM      string `form:"ram" json:"ram"`
-	Internal string `form:"internal" json:"internal"`
-	Lokasi   string `form:"lokasi" json:"lokasi"`
+	Nama       string `form:"nama" json:"nama"`
+	Merk       string `form:"merk" json:"merk"`
+	PC         string `form:"pc" json:"pc"`
+	Monitor    string `form:"monitor" json:"monitor"`
+	CPU        string `form:"cpu" json:"cpu"`
+	RAM        string `form:"ram" json:"ram"`
+	Internal   string `form:"internal" json:"internal"`
+	Lokasi     string `form:"lokasi" json:"lokasi"`
+	LocationID string `form:"location_id" json:"location_id"`
 }
 
 func newKomputerPH1Form(c echo.Context) (*komputerPH1Form, error) {
@@ -30,7 +33,7 @@ func newKomputerPH1Form(c echo.Context) (*komputerPH1Form, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Merk == "" && f.PC == "" && f.Monitor == "" && f.CPU == "" && f.RAM == "" && f.Internal == "" && f.Lokasi == "" {
+	if f.Nama == "" && f.Merk == "" && f.PC == "" && f.Monitor == "" && f.CPU == "" && f.RAM == "" && f.Internal == "" && f.Lokasi == "" && f.LocationID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -38,12 +41,14 @@ func newKomputerPH1Form(c echo.Context) (*komputerPH1Form, error) {
 }
 
 type KomputerPH1Handler struct {
-	kph1Repo *repo.KomputerPH1CollRepository
+	kph1Repo     *repo.KomputerPH1CollRepository
+	locationRepo *repo2.LocationCollRepository
 }
 
 func NewKomputerPH1APIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1Handler {
 	h := &KomputerPH1Handler{
-		kph1Repo: repo.NewKomputerPH1Repository(db),
+		kph1Repo:     repo.NewKomputerPH1Repository(db),
+		locationRepo: repo2.NewLocationRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -66,6 +71,8 @@ func NewKomputerPH1APIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1Hand
 // @ID get-all-komputer-ph1s
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -73,7 +80,15 @@ func NewKomputerPH1APIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1Hand
 // @Produce json
 // @Success 200
 func (h *KomputerPH1Handler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	komputerPH1s, err := h.kph1Repo.FindAll(cq)
 	if err != nil {
@@ -143,6 +158,14 @@ func (h *KomputerPH1Handler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nama is required")
 	}
@@ -169,17 +192,19 @@ func (h *KomputerPH1Handler) create(c echo.Context) error {
 	}
 
 	kph1 := &repo.KomputerPH1{
-		ID:        bson.NewObjectID(),
-		Nama:      f.Nama,
-		Merk:      f.Merk,
-		PC:        f.PC,
-		Monitor:   f.Monitor,
-		CPU:       f.CPU,
-		RAM:       f.RAM,
-		Internal:  f.Internal,
-		Lokasi:    f.Lokasi,
-		Inserted:  nc.Claims.ByAt(),
-		IsDeleted: false,
+		ID:         bson.NewObjectID(),
+		Nama:       f.Nama,
+		Merk:       f.Merk,
+		PC:         f.PC,
+		Monitor:    f.Monitor,
+		CPU:        f.CPU,
+		RAM:        f.RAM,
+		Internal:   f.Internal,
+		Lokasi:     f.Lokasi,
+		LocationID: location.Ref(),
+		Status:     _const.DeviceActive,
+		Inserted:   nc.Claims.ByAt(),
+		IsDeleted:  false,
 	}
 
 	err = h.kph1Repo.InsertOne(kph1)
@@ -224,6 +249,15 @@ func (h *KomputerPH1Handler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "KomputerPH1 not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	kph1.LocationID = locationRef(location, f.Lokasi, kph1.Lokasi, kph1.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama != "" {
 		kph1.Nama = f.Nama
 	}
@@ -268,6 +302,8 @@ func (h *KomputerPH1Handler) update(c echo.Context) error {
 // @Param id path string true "KomputerPH1 ID"
 // @Success 200
 func (h *KomputerPH1Handler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -281,7 +317,7 @@ func (h *KomputerPH1Handler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "KomputerPH1 not found")
 	}
 
-	err = h.kph1Repo.DeleteOneByID(oId)
+	err = h.kph1Repo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete komputerPH1: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
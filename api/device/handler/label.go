@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo3 "sipamit-be/api/department/repo"
+	"sipamit-be/api/device/repo"
+	repo4 "sipamit-be/api/device_cp/repo"
+	repo5 "sipamit-be/api/device_doc/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/pdf"
+	"sipamit-be/internal/pkg/qr"
+	"sipamit-be/internal/pkg/util"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Label sheet layout, 3 columns of 8 labels on an A4 page.
+const (
+	labelMargin  = 28.0
+	labelColumns = 3
+	labelRows    = 8
+	labelInset   = 8.0
+	labelQRSize  = 80.0
+
+	// labelMax caps a sheet at 100 pages, the PDF being built in memory.
+	labelMax = 100 * labelColumns * labelRows
+)
+
+// labelDevice holds the fields of a device printed on its label. Telepon has
+// User where the other types have Nama.
+type labelDevice struct {
+	AssetTag   string `bson:"asset_tag"`
+	Nama       string `bson:"nama"`
+	User       string `bson:"user"`
+	Lokasi     string `bson:"lokasi"`
+	Departemen string `bson:"departemen"`
+}
+
+type scanResult struct {
+	Device     string            `json:"device"`
+	Label      string            `json:"label"`
+	Record     interface{}       `json:"record"`
+	Checkpoint *repo4.Checkpoint `json:"checkpoint"`
+	LastDoc    *repo5.DeviceDoc  `json:"last_doc"`
+}
+
+type LabelHandler struct {
+	deviceRepo     *repo.DeviceRepository
+	checkpointRepo *repo4.CheckpointCollRepository
+	docRepo        *repo5.DocRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
+}
+
+func NewLabelAPIHandler(e *echo.Echo, db *mongo.Database) *LabelHandler {
+	h := &LabelHandler{
+		deviceRepo:     repo.NewDeviceRepository(db),
+		checkpointRepo: repo4.NewCheckpointRepository(db),
+		docRepo:        repo5.NewDocRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/device/:device/:id/qr", h.qrCode)
+	group.GET("/devices/labels", h.labels)
+	group.GET("/scan/:tag", h.scan)
+
+	return h
+}
+
+// qrCode
+// @Tags Label
+// @Summary Get the QR code of a device asset tag
+// @ID get-device-qr
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Param format query string false "Image format" enums(png, svg) default(png)
+// @Param size query int false "Image size in pixels" default(256)
+// @Router /api/device/{device}/{id}/qr [GET]
+// @Produce png
+// @Produce image/svg+xml
+// @Success 200
+func (h *LabelHandler) qrCode(c echo.Context) error {
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	size := 256
+	if s := c.QueryParam("size"); s != "" {
+		size, err = strconv.Atoi(s)
+		if err != nil || size < 64 || size > 1024 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Size must be between 64 and 1024")
+		}
+	}
+
+	summary, err := h.deviceRepo.FindOneSummary(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, _const.DeviceLabels[device]+" not found")
+		}
+		log.Errorf("Failed to get %s: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if summary.AssetTag == "" {
+		return echo.NewHTTPError(http.StatusNotFound, _const.DeviceLabels[device]+" has no asset tag yet")
+	}
+
+	switch strings.ToLower(c.QueryParam("format")) {
+	case "", "png":
+		image, err := qr.PNG(summary.AssetTag, size)
+		if err != nil {
+			log.Errorf("Failed to render %s qr code: %v", summary.AssetTag, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		return c.Blob(http.StatusOK, "image/png", image)
+	case "svg":
+		image, err := qr.SVG(summary.AssetTag, size)
+		if err != nil {
+			log.Errorf("Failed to render %s qr code: %v", summary.AssetTag, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		return c.Blob(http.StatusOK, "image/svg+xml", image)
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, "Format must be png or svg")
+}
+
+// labels
+// @Tags Label
+// @Summary Get a printable PDF sheet of asset tag labels of the filtered devices
+// @Description At most 2400 labels, 100 pages, are printed at once. More devices matching the filters is a 400.
+// @ID get-device-labels
+// @Security ApiKeyAuth
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param q query string false "Search by nama"
+// @Param status query string false "Lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Location ID, including the locations under it"
+// @Param department query string false "Department ID"
+// @Router /api/devices/labels [GET]
+// @Produce application/pdf
+// @Success 200
+func (h *LabelHandler) labels(c echo.Context) error {
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	err = scopeDepartment(h.departmentRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	types := _const.Devices
+	if cq.Device != "" {
+		types = []string{cq.Device}
+	}
+	if cq.DepartmentID != nil {
+		types = slices.DeleteFunc(slices.Clone(types), func(device string) bool {
+			return !slices.Contains(_const.DepartmentDevices, device)
+		})
+	}
+
+	errTooMany := echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("More than %d devices to label, narrow the filters", labelMax))
+
+	doc := pdf.New()
+	count := 0
+	for _, device := range types {
+		err = h.deviceRepo.Each(device, cq, func(raw bson.Raw) error {
+			var d labelDevice
+			if err := bson.Unmarshal(raw, &d); err != nil {
+				return err
+			}
+			if d.AssetTag == "" {
+				return nil
+			}
+			if count == labelMax {
+				return errTooMany
+			}
+
+			summary := repo.DeviceSummary{
+				Device:     device,
+				AssetTag:   d.AssetTag,
+				Nama:       d.Nama,
+				Lokasi:     d.Lokasi,
+				Departemen: d.Departemen,
+			}
+			if device == _const.Telepon {
+				summary.Nama = d.User
+			}
+
+			cell := count % (labelColumns * labelRows)
+			if cell == 0 {
+				doc.AddPage()
+			}
+			count++
+
+			err := renderLabel(doc, cell, summary)
+			if err != nil {
+				return fmt.Errorf("render %s label: %w", summary.AssetTag, err)
+			}
+			return nil
+		})
+		if errors.Is(err, errTooMany) {
+			return errTooMany
+		}
+		if err != nil {
+			log.Errorf("Failed to get %s labels: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+	if count == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Devices not found")
+	}
+
+	var buf bytes.Buffer
+	if err = doc.Write(&buf); err != nil {
+		log.Errorf("Failed to write pdf: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="labels.pdf"`)
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// renderLabel draws the label of a device in the given cell of the page: its
+// QR code, with the asset tag, type, nama and lokasi beside it. The border is
+// a cutting guide.
+func renderLabel(doc *pdf.Document, cell int, summary repo.DeviceSummary) error {
+	width := (pdf.PageWidth - 2*labelMargin) / labelColumns
+	height := (pdf.PageHeight - 2*labelMargin) / labelRows
+	x := labelMargin + float64(cell%labelColumns)*width
+	y := labelMargin + float64(cell/labelColumns)*height
+
+	doc.Rect(x, y, width, height)
+
+	err := qr.Draw(doc, x+labelInset, y+(height-labelQRSize)/2, labelQRSize, summary.AssetTag)
+	if err != nil {
+		return err
+	}
+
+	textX := x + labelInset + labelQRSize + labelInset
+	textWidth := width - labelQRSize - 3*labelInset
+	lineY := y + labelInset + 12
+
+	doc.Text(textX, lineY, 11, true, summary.AssetTag)
+	lineY += 13
+	doc.Text(textX, lineY, 8, false, _const.DeviceLabels[summary.Device])
+	lineY += 12
+
+	nama := pdf.Wrap(summary.Nama, textWidth, 8, true)
+	if len(nama) > 3 {
+		nama = nama[:3]
+	}
+	for _, line := range nama {
+		doc.Text(textX, lineY, 8, true, line)
+		lineY += 10
+	}
+
+	lokasi := summary.Lokasi
+	if lokasi == "" {
+		lokasi = summary.Departemen
+	}
+	for _, line := range pdf.Wrap(lokasi, textWidth, 7, false) {
+		if lineY > y+height-labelInset {
+			break
+		}
+		doc.Text(textX, lineY, 7, false, line)
+		lineY += 9
+	}
+	return nil
+}
+
+// scan
+// @Tags Label
+// @Summary Resolve a scanned asset tag to its device, checkpoint template and last maintenance doc
+// @ID scan-asset-tag
+// @Security ApiKeyAuth
+// @Param tag path string true "Asset tag, e.g. PRN-00042"
+// @Router /api/scan/{tag} [GET]
+// @Produce json
+// @Success 200
+func (h *LabelHandler) scan(c echo.Context) error {
+	tag := strings.ToUpper(strings.TrimSpace(c.Param("tag")))
+
+	device, oId, err := h.deviceRepo.FindOneByTag(tag)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("No device tagged %s", tag))
+		}
+		log.Errorf("Failed to find device tagged %s: %v", tag, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	record, err := h.deviceRepo.FindOne(device, oId)
+	if err != nil {
+		log.Errorf("Failed to get %s: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := &scanResult{
+		Device: device,
+		Label:  _const.DeviceLabels[device],
+		Record: record,
+	}
+
+	result.Checkpoint, err = h.checkpointRepo.FindByDevice(device)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Errorf("Failed to get %s checkpoint: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result.LastDoc, err = h.docRepo.FindLastByDevice(device, oId)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Errorf("Failed to get last %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
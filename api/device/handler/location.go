@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+)
+
+// findLocation returns the location of the location_id form field, or nil
+// when it is empty.
+func findLocation(locationRepo *repo2.LocationCollRepository, id string) (*repo2.Location, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid location ID")
+	}
+
+	location, err := locationRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Location not found")
+		}
+		log.Errorf("Failed to get location: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return location, nil
+}
+
+// locationRef returns the location referenced by a device updated with the
+// form: the one of location_id, none when the free text lokasi is changed, or
+// the current one otherwise.
+func locationRef(location *repo2.Location, lokasi, current string, ref *bson.ObjectID) *bson.ObjectID {
+	if location != nil {
+		return &location.ID
+	}
+	if lokasi != "" && !strings.EqualFold(strings.TrimSpace(lokasi), strings.TrimSpace(current)) {
+		return nil
+	}
+	return ref
+}
+
+// scopeLocation restricts the query to the location param and every location
+// under it.
+func scopeLocation(locationRepo *repo2.LocationCollRepository, cq *util.CommonQuery) error {
+	if cq.Location == "" {
+		return nil
+	}
+
+	oId, err := bson.ObjectIDFromHex(cq.Location)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid location ID")
+	}
+
+	cq.LocationIDs, err = locationRepo.FindSubtreeIDs(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Location not found")
+		}
+		log.Errorf("Failed to get location: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return nil
+}
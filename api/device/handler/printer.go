@@ -6,17 +6,23 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
+	repo3 "sipamit-be/api/department/repo"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type printerForm struct {
-	Nama        string `form:"nama" json:"nama"`
-	Departemen  string `form:"departemen" json:"departemen"`
-	TipePrinter string `form:"tipe_printer" json:"tipe_printer"`
-	NoSeri      string `form:"no_seri" json:"no_seri"`
+	Nama         string `form:"nama" json:"nama"`
+	Departemen   string `form:"departemen" json:"departemen"`
+	DepartmentID string `form:"department_id" json:"department_id"`
+	TipePrinter  string `form:"tipe_printer" json:"tipe_printer"`
+	NoSeri       string `form:"no_seri" json:"no_seri"`
+	LocationID   string `form:"location_id" json:"location_id"`
 }
 
 func newPrinterForm(c echo.Context) (*printerForm, error) {
@@ -26,7 +32,7 @@ func newPrinterForm(c echo.Context) (*printerForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Departemen == "" && f.TipePrinter == "" && f.NoSeri == "" {
+	if f.Nama == "" && f.Departemen == "" && f.TipePrinter == "" && f.NoSeri == "" && f.LocationID == "" && f.DepartmentID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -34,12 +40,16 @@ func newPrinterForm(c echo.Context) (*printerForm, error) {
 }
 
 type PrinterHandler struct {
-	printerRepo *repo.PrinterCollRepository
+	printerRepo    *repo.PrinterCollRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
 }
 
 func NewPrinterAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterHandler {
 	h := &PrinterHandler{
-		printerRepo: repo.NewPrinterRepository(db),
+		printerRepo:    repo.NewPrinterRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -62,6 +72,9 @@ func NewPrinterAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterHandler {
 // @ID get-all-printers
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
+// @Param department query string false "Filter by department ID"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -69,7 +82,20 @@ func NewPrinterAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterHandler {
 // @Produce json
 // @Success 200
 func (h *PrinterHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	err = scopeDepartment(h.departmentRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	printers, err := h.printerRepo.FindAll(cq)
 	if err != nil {
@@ -139,6 +165,19 @@ func (h *PrinterHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Nama == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nama is required")
 	}
@@ -153,17 +192,23 @@ func (h *PrinterHandler) create(c echo.Context) error {
 	}
 
 	printer := &repo.Printer{
-		ID:          bson.NewObjectID(),
-		Nama:        f.Nama,
-		Departemen:  f.Departemen,
-		TipePrinter: f.TipePrinter,
-		NoSeri:      f.NoSeri,
-		Inserted:    nc.Claims.ByAt(),
-		IsDeleted:   false,
+		ID:           bson.NewObjectID(),
+		Nama:         f.Nama,
+		Departemen:   f.Departemen,
+		TipePrinter:  f.TipePrinter,
+		NoSeri:       f.NoSeri,
+		LocationID:   location.Ref(),
+		DepartmentID: department.Ref(),
+		Status:       _const.DeviceActive,
+		Inserted:     nc.Claims.ByAt(),
+		IsDeleted:    false,
 	}
 
 	err = h.printerRepo.InsertOne(printer)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create printer: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -204,6 +249,23 @@ func (h *PrinterHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Printer not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		printer.LocationID = &location.ID
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	printer.DepartmentID = departmentRef(department, f.Departemen, printer.Departemen, printer.DepartmentID)
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Nama != "" {
 		printer.Nama = f.Nama
 	}
@@ -220,6 +282,9 @@ func (h *PrinterHandler) update(c echo.Context) error {
 	printer.Updated = nc.Claims.ByAtPtr()
 	err = h.printerRepo.UpdateOneByID(oId, printer)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update printer: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -236,6 +301,8 @@ func (h *PrinterHandler) update(c echo.Context) error {
 // @Param id path string true "Printer ID"
 // @Success 200
 func (h *PrinterHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -249,7 +316,7 @@ func (h *PrinterHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Printer not found")
 	}
 
-	err = h.printerRepo.DeleteOneByID(oId)
+	err = h.printerRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete printer: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
@@ -6,19 +6,25 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
+	repo3 "sipamit-be/api/department/repo"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type teleponForm struct {
-	Lokasi     string `form:"lokasi" json:"lokasi"`
-	Departemen string `form:"departemen" json:"departemen"`
-	User       string `form:"user" json:"user"`
-	Ext        string `form:"ext" json:"ext"`
-	Merk       string `form:"merk" json:"merk"`
-	Tipe       string `form:"tipe" json:"tipe"`
+	Lokasi       string `form:"lokasi" json:"lokasi"`
+	LocationID   string `form:"location_id" json:"location_id"`
+	Departemen   string `form:"departemen" json:"departemen"`
+	DepartmentID string `form:"department_id" json:"department_id"`
+	User         string `form:"user" json:"user"`
+	Ext          string `form:"ext" json:"ext"`
+	Merk         string `form:"merk" json:"merk"`
+	Tipe         string `form:"tipe" json:"tipe"`
 }
 
 func newTeleponForm(c echo.Context) (*teleponForm, error) {
@@ -28,7 +34,7 @@ func newTeleponForm(c echo.Context) (*teleponForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Lokasi == "" && f.Departemen == "" && f.User == "" && f.Ext == "" && f.Merk == "" && f.Tipe == "" {
+	if f.Lokasi == "" && f.Departemen == "" && f.User == "" && f.Ext == "" && f.Merk == "" && f.Tipe == "" && f.LocationID == "" && f.DepartmentID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -36,12 +42,16 @@ func newTeleponForm(c echo.Context) (*teleponForm, error) {
 }
 
 type TeleponHandler struct {
-	teleponRepo *repo.TeleponCollRepository
+	teleponRepo    *repo.TeleponCollRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
 }
 
 func NewTeleponAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponHandler {
 	h := &TeleponHandler{
-		teleponRepo: repo.NewTeleponRepository(db),
+		teleponRepo:    repo.NewTeleponRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -64,6 +74,9 @@ func NewTeleponAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponHandler {
 // @ID get-all-telepons
 // @Security ApiKeyAuth
 // @Param q query string false "Search by tipe"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
+// @Param department query string false "Filter by department ID"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -71,7 +84,20 @@ func NewTeleponAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponHandler {
 // @Produce json
 // @Success 200
 func (h *TeleponHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	err = scopeDepartment(h.departmentRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	telepons, err := h.teleponRepo.FindAll(cq)
 	if err != nil {
@@ -141,6 +167,22 @@ func (h *TeleponHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Lokasi == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Lokasi is required")
 	}
@@ -161,19 +203,25 @@ func (h *TeleponHandler) create(c echo.Context) error {
 	}
 
 	telepon := &repo.Telepon{
-		ID:         bson.NewObjectID(),
-		Lokasi:     f.Lokasi,
-		Departemen: f.Departemen,
-		User:       f.User,
-		Ext:        f.Ext,
-		Merk:       f.Merk,
-		Tipe:       f.Tipe,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:           bson.NewObjectID(),
+		Lokasi:       f.Lokasi,
+		Departemen:   f.Departemen,
+		User:         f.User,
+		Ext:          f.Ext,
+		Merk:         f.Merk,
+		Tipe:         f.Tipe,
+		LocationID:   location.Ref(),
+		DepartmentID: department.Ref(),
+		Status:       _const.DeviceActive,
+		Inserted:     nc.Claims.ByAt(),
+		IsDeleted:    false,
 	}
 
 	err = h.teleponRepo.InsertOne(telepon)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create telepon: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -214,6 +262,24 @@ func (h *TeleponHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Telepon not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	telepon.LocationID = locationRef(location, f.Lokasi, telepon.Lokasi, telepon.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	telepon.DepartmentID = departmentRef(department, f.Departemen, telepon.Departemen, telepon.DepartmentID)
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Lokasi != "" {
 		telepon.Lokasi = f.Lokasi
 	}
@@ -236,6 +302,9 @@ func (h *TeleponHandler) update(c echo.Context) error {
 	telepon.Updated = nc.Claims.ByAtPtr()
 	err = h.teleponRepo.UpdateOneByID(oId, telepon)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update telepon: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -252,6 +321,8 @@ func (h *TeleponHandler) update(c echo.Context) error {
 // @Param id path string true "Telepon ID"
 // @Success 200
 func (h *TeleponHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -265,7 +336,7 @@ func (h *TeleponHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Telepon not found")
 	}
 
-	err = h.teleponRepo.DeleteOneByID(oId)
+	err = h.teleponRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete telepon: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
@@ -7,16 +7,20 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type toaForm struct {
-	Nama   string `form:"nama" json:"nama"`
-	Lokasi string `form:"lokasi" json:"lokasi"`
-	Kode   string `form:"kode" json:"kode"`
-	Posisi string `form:"posisi" json:"posisi"`
+	Nama       string `form:"nama" json:"nama"`
+	Lokasi     string `form:"lokasi" json:"lokasi"`
+	LocationID string `form:"location_id" json:"location_id"`
+	Kode       string `form:"kode" json:"kode"`
+	Posisi     string `form:"posisi" json:"posisi"`
 }
 
 func newTOAForm(c echo.Context) (*toaForm, error) {
@@ -26,7 +30,7 @@ func newTOAForm(c echo.Context) (*toaForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" && f.Posisi == "" {
+	if f.Nama == "" && f.Lokasi == "" && f.Kode == "" && f.Posisi == "" && f.LocationID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -34,12 +38,14 @@ func newTOAForm(c echo.Context) (*toaForm, error) {
 }
 
 type TOAHandler struct {
-	toaRepo *repo.TOACollRepository
+	toaRepo      *repo.TOACollRepository
+	locationRepo *repo2.LocationCollRepository
 }
 
 func NewTOAAPIHandler(e *echo.Echo, db *mongo.Database) *TOAHandler {
 	h := &TOAHandler{
-		toaRepo: repo.NewTOARepository(db),
+		toaRepo:      repo.NewTOARepository(db),
+		locationRepo: repo2.NewLocationRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -62,6 +68,8 @@ func NewTOAAPIHandler(e *echo.Echo, db *mongo.Database) *TOAHandler {
 // @ID get-all-toas
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -69,7 +77,15 @@ func NewTOAAPIHandler(e *echo.Echo, db *mongo.Database) *TOAHandler {
 // @Produce json
 // @Success 200
 func (h *TOAHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	toas, err := h.toaRepo.FindAll(cq)
 	if err != nil {
@@ -139,6 +155,14 @@ func (h *TOAHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Nama is required")
 	}
@@ -153,17 +177,22 @@ func (h *TOAHandler) create(c echo.Context) error {
 	}
 
 	toa := &repo.TOA{
-		ID:        bson.NewObjectID(),
-		Nama:      f.Nama,
-		Lokasi:    f.Lokasi,
-		Kode:      f.Kode,
-		Posisi:    f.Posisi,
-		Inserted:  nc.Claims.ByAt(),
-		IsDeleted: false,
+		ID:         bson.NewObjectID(),
+		Nama:       f.Nama,
+		Lokasi:     f.Lokasi,
+		Kode:       f.Kode,
+		Posisi:     f.Posisi,
+		LocationID: location.Ref(),
+		Status:     _const.DeviceActive,
+		Inserted:   nc.Claims.ByAt(),
+		IsDeleted:  false,
 	}
 
 	err = h.toaRepo.InsertOne(toa)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create toa: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -204,6 +233,15 @@ func (h *TOAHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "TOA not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	toa.LocationID = locationRef(location, f.Lokasi, toa.Lokasi, toa.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
 	if f.Nama != "" {
 		toa.Nama = f.Nama
 	}
@@ -220,6 +258,9 @@ func (h *TOAHandler) update(c echo.Context) error {
 	toa.Updated = nc.Claims.ByAtPtr()
 	err = h.toaRepo.UpdateOneByID(oId, toa)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update toa: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -236,6 +277,8 @@ func (h *TOAHandler) update(c echo.Context) error {
 // @Param id path string true "TOA ID"
 // @Success 200
 func (h *TOAHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -249,7 +292,7 @@ func (h *TOAHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "TOA not found")
 	}
 
-	err = h.toaRepo.DeleteOneByID(oId)
+	err = h.toaRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete toa: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
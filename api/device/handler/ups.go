@@ -6,18 +6,24 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
+	repo3 "sipamit-be/api/department/repo"
 	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
 )
 
 type upsForm struct {
-	Nama       string `form:"nama" json:"nama"`
-	Departemen string `form:"departemen" json:"departemen"`
-	Tipe       string `form:"tipe" json:"tipe"`
-	NoSeri     string `form:"no_seri" json:"no_seri"`
-	Lokasi     string `form:"lokasi" json:"lokasi"`
+	Nama         string `form:"nama" json:"nama"`
+	Departemen   string `form:"departemen" json:"departemen"`
+	DepartmentID string `form:"department_id" json:"department_id"`
+	Tipe         string `form:"tipe" json:"tipe"`
+	NoSeri       string `form:"no_seri" json:"no_seri"`
+	Lokasi       string `form:"lokasi" json:"lokasi"`
+	LocationID   string `form:"location_id" json:"location_id"`
 }
 
 func newUPSForm(c echo.Context) (*upsForm, error) {
@@ -27,7 +33,7 @@ func newUPSForm(c echo.Context) (*upsForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
-	if f.Nama == "" && f.Departemen == "" && f.Tipe == "" && f.NoSeri == "" && f.Lokasi == "" {
+	if f.Nama == "" && f.Departemen == "" && f.Tipe == "" && f.NoSeri == "" && f.Lokasi == "" && f.LocationID == "" && f.DepartmentID == "" {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
@@ -35,12 +41,16 @@ func newUPSForm(c echo.Context) (*upsForm, error) {
 }
 
 type UPSHandler struct {
-	upsRepo *repo.UPSCollRepository
+	upsRepo        *repo.UPSCollRepository
+	locationRepo   *repo2.LocationCollRepository
+	departmentRepo *repo3.DepartmentCollRepository
 }
 
 func NewUPSAPIHandler(e *echo.Echo, db *mongo.Database) *UPSHandler {
 	h := &UPSHandler{
-		upsRepo: repo.NewUPSRepository(db),
+		upsRepo:        repo.NewUPSRepository(db),
+		locationRepo:   repo2.NewLocationRepository(db),
+		departmentRepo: repo3.NewDepartmentRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -63,6 +73,9 @@ func NewUPSAPIHandler(e *echo.Echo, db *mongo.Database) *UPSHandler {
 // @ID get-all-ups
 // @Security ApiKeyAuth
 // @Param q query string false "Search by nama"
+// @Param status query string false "Filter by lifecycle status" enums(active, in_repair, spare, retired, disposed)
+// @Param location query string false "Filter by location ID, including the locations under it"
+// @Param department query string false "Filter by department ID"
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
@@ -70,7 +83,20 @@ func NewUPSAPIHandler(e *echo.Echo, db *mongo.Database) *UPSHandler {
 // @Produce json
 // @Success 200
 func (h *UPSHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	cq, err := util.NewStatusQuery(c, _const.ValidDeviceStatus)
+	if err != nil {
+		return err
+	}
+
+	err = scopeLocation(h.locationRepo, cq)
+	if err != nil {
+		return err
+	}
+
+	err = scopeDepartment(h.departmentRepo, cq)
+	if err != nil {
+		return err
+	}
 
 	ups, err := h.upsRepo.FindAll(cq)
 	if err != nil {
@@ -140,6 +166,22 @@ func (h *UPSHandler) create(c echo.Context) error {
 		return err
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Lokasi == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Lokasi is required")
 	}
@@ -157,18 +199,24 @@ func (h *UPSHandler) create(c echo.Context) error {
 	}
 
 	ups := &repo.UPS{
-		ID:         bson.NewObjectID(),
-		Nama:       f.Nama,
-		Departemen: f.Departemen,
-		Tipe:       f.Tipe,
-		NoSeri:     f.NoSeri,
-		Lokasi:     f.Lokasi,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:           bson.NewObjectID(),
+		Nama:         f.Nama,
+		Departemen:   f.Departemen,
+		Tipe:         f.Tipe,
+		NoSeri:       f.NoSeri,
+		Lokasi:       f.Lokasi,
+		LocationID:   location.Ref(),
+		DepartmentID: department.Ref(),
+		Status:       _const.DeviceActive,
+		Inserted:     nc.Claims.ByAt(),
+		IsDeleted:    false,
 	}
 
 	err = h.upsRepo.InsertOne(ups)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to create ups: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -209,6 +257,24 @@ func (h *UPSHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "UPS not found")
 	}
 
+	location, err := findLocation(h.locationRepo, f.LocationID)
+	if err != nil {
+		return err
+	}
+	ups.LocationID = locationRef(location, f.Lokasi, ups.Lokasi, ups.LocationID)
+	if location != nil {
+		f.Lokasi = location.Name
+	}
+
+	department, err := findDepartment(h.departmentRepo, f.DepartmentID)
+	if err != nil {
+		return err
+	}
+	ups.DepartmentID = departmentRef(department, f.Departemen, ups.Departemen, ups.DepartmentID)
+	if department != nil {
+		f.Departemen = department.Name
+	}
+
 	if f.Nama != "" {
 		ups.Nama = f.Nama
 	}
@@ -228,6 +294,9 @@ func (h *UPSHandler) update(c echo.Context) error {
 	ups.Updated = nc.Claims.ByAtPtr()
 	err = h.upsRepo.UpdateOneByID(oId, ups)
 	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
 		log.Errorf("Failed to update ups: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -244,6 +313,8 @@ func (h *UPSHandler) update(c echo.Context) error {
 // @Param id path string true "UPS ID"
 // @Success 200
 func (h *UPSHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -257,7 +328,7 @@ func (h *UPSHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "UPS not found")
 	}
 
-	err = h.upsRepo.DeleteOneByID(oId)
+	err = h.upsRepo.DeleteOneByID(oId, nc.Claims.ByAt())
 	if err != nil {
 		log.Errorf("Failed to delete ups: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
@@ -4,18 +4,28 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type CCTV struct {
-	ID        bson.ObjectID `json:"_id" bson:"_id"`
-	Nama      string        `json:"nama" bson:"nama"`
-	Lokasi    string        `json:"lokasi" bson:"lokasi"`
-	Kode      string        `json:"kode" bson:"kode"`
-	Inserted  doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated   *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	Kode          string             `json:"kode" bson:"kode"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type CCTVCollRepository struct {
@@ -28,8 +38,13 @@ func NewCCTVRepository(db *mongo.Database) *CCTVCollRepository {
 	}
 }
 
-func (r *CCTVCollRepository) FindAll(cq *util.CommonQuery) (*[]CCTV, error) {
-	var cctvs []CCTV
+// EnsureIndexes creates the indexes of the cctvs collection.
+func (r *CCTVCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("kode"), index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *CCTVCollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -39,6 +54,20 @@ func (r *CCTVCollRepository) FindAll(cq *util.CommonQuery) (*[]CCTV, error) {
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+	return filter
+}
+
+func (r *CCTVCollRepository) FindAll(cq *util.CommonQuery) (*[]CCTV, error) {
+	var cctvs []CCTV
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -75,22 +104,48 @@ func (r *CCTVCollRepository) FindOneByID(id bson.ObjectID) (*CCTV, error) {
 }
 
 func (r *CCTVCollRepository) InsertOne(cctv *CCTV) error {
-	_, err := r.coll.InsertOne(context.TODO(), cctv)
+	tag, err := nextTag(r.coll.Database(), _const.CCTV)
+	if err != nil {
+		return err
+	}
+
+	cctv.AssetTag = tag
+	cctv.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), cctv)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *CCTVCollRepository) InsertMany(cctvs []CCTV) error {
-	_, err := r.coll.InsertMany(context.TODO(), cctvs)
+	tags, err := nextTags(r.coll.Database(), _const.CCTV, len(cctvs))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range cctvs {
+		cctvs[i].AssetTag = tags[i]
+		cctvs[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), cctvs)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(cctvs))
+		for i := range cctvs {
+			ids[i] = cctvs[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *CCTVCollRepository) UpdateOneByID(id bson.ObjectID, cctv *CCTV) error {
+	cctv.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -98,6 +153,9 @@ func (r *CCTVCollRepository) UpdateOneByID(id bson.ObjectID, cctv *CCTV) error {
 	update := bson.M{
 		"$set": cctv,
 	}
+	if cctv.LocationID == nil {
+		update["$unset"] = bson.M{"location_id": ""}
+	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
@@ -117,14 +175,25 @@ func (r *CCTVCollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *CCTVCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *CCTVCollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -134,13 +203,27 @@ func (r *CCTVCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
 	return count, nil
 }
 
-func (r *CCTVCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *CCTVCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *CCTVCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -149,3 +232,27 @@ func (r *CCTVCollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the cctvs written at or after since, or all of
+// them when since is zero.
+func (r *CCTVCollRepository) FindAllModifiedSince(since time.Time) (*[]CCTV, error) {
+	var cctvs []CCTV
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &cctvs)
+	if err != nil {
+		return nil, err
+	}
+	if cctvs == nil {
+		return &[]CCTV{}, nil
+	}
+	return &cctvs, nil
+}
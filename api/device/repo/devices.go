@@ -0,0 +1,745 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/util"
+	"time"
+)
+
+// DeviceSummary holds the identifying fields shared by every device type.
+// Telepon has no Nama or Kode, its User and Ext are used instead.
+type DeviceSummary struct {
+	Device       string         `json:"device"`
+	ID           bson.ObjectID  `json:"_id"`
+	AssetTag     string         `json:"asset_tag,omitempty"`
+	Nama         string         `json:"nama"`
+	Kode         string         `json:"kode,omitempty"`
+	NoSeri       string         `json:"no_seri,omitempty"`
+	Lokasi       string         `json:"lokasi"`
+	Departemen   string         `json:"departemen"`
+	Status       string         `json:"status"`
+	LocationID   *bson.ObjectID `json:"location_id,omitempty"`
+	DepartmentID *bson.ObjectID `json:"department_id,omitempty"`
+	Inserted     time.Time      `json:"-"`
+}
+
+// Scheduled reports whether the device is due for maintenance. Only active
+// devices are, those in repair, spare, retired or disposed are not. The
+// schedules and the compliance report both count the scheduled devices.
+func (s DeviceSummary) Scheduled() bool {
+	return s.Status == _const.DeviceActive
+}
+
+// DeviceRepository queries the eight device collections together.
+type DeviceRepository struct {
+	cctv        *CCTVCollRepository
+	fingerprint *FingerPrintCollRepository
+	kph1        *KomputerPH1CollRepository
+	kph2        *KomputerPH2CollRepository
+	printer     *PrinterCollRepository
+	telepon     *TeleponCollRepository
+	toa         *TOACollRepository
+	ups         *UPSCollRepository
+}
+
+func NewDeviceRepository(db *mongo.Database) *DeviceRepository {
+	return &DeviceRepository{
+		cctv:        NewCCTVRepository(db),
+		fingerprint: NewFingerPrintRepository(db),
+		kph1:        NewKomputerPH1Repository(db),
+		kph2:        NewKomputerPH2Repository(db),
+		printer:     NewPrinterRepository(db),
+		telepon:     NewTeleponRepository(db),
+		toa:         NewTOARepository(db),
+		ups:         NewUPSRepository(db),
+	}
+}
+
+func (r *DeviceRepository) coll(device string) *mongo.Collection {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.coll
+	case _const.Fingerprint:
+		return r.fingerprint.coll
+	case _const.KomputerPH1:
+		return r.kph1.coll
+	case _const.KomputerPH2:
+		return r.kph2.coll
+	case _const.Printer:
+		return r.printer.coll
+	case _const.Telepon:
+		return r.telepon.coll
+	case _const.Toa:
+		return r.toa.coll
+	case _const.Ups:
+		return r.ups.coll
+	}
+	return nil
+}
+
+// FindAllModifiedSince returns the devices of the type written at or after
+// since, or all of them when since is zero.
+func (r *DeviceRepository) FindAllModifiedSince(device string, since time.Time) (interface{}, error) {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.FindAllModifiedSince(since)
+	case _const.Fingerprint:
+		return r.fingerprint.FindAllModifiedSince(since)
+	case _const.KomputerPH1:
+		return r.kph1.FindAllModifiedSince(since)
+	case _const.KomputerPH2:
+		return r.kph2.FindAllModifiedSince(since)
+	case _const.Printer:
+		return r.printer.FindAllModifiedSince(since)
+	case _const.Telepon:
+		return r.telepon.FindAllModifiedSince(since)
+	case _const.Toa:
+		return r.toa.FindAllModifiedSince(since)
+	case _const.Ups:
+		return r.ups.FindAllModifiedSince(since)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// FindDeletedSince returns the ids of the devices of the type deleted at or
+// after since.
+func (r *DeviceRepository) FindDeletedSince(device string, since time.Time) ([]bson.ObjectID, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindDeletedSince(coll, since)
+}
+
+// InitModifiedAt stamps the devices of the type written before modified_at
+// was tracked.
+func (r *DeviceRepository) InitModifiedAt(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+	return doc.InitModifiedAt(coll)
+}
+
+// FindAllSummary returns the summary of every device of the given type, or
+// of every type when device is empty.
+func (r *DeviceRepository) FindAllSummary(device string) ([]DeviceSummary, error) {
+	types := _const.Devices
+	if device != "" {
+		types = []string{device}
+	}
+
+	return r.FindAllSummaryQuery(types, util.NilCommonQuery())
+}
+
+// FindAllSummaryQuery returns the summary of the devices of the types
+// matching the list filters of cq.
+func (r *DeviceRepository) FindAllSummaryQuery(types []string, cq *util.CommonQuery) ([]DeviceSummary, error) {
+	var result []DeviceSummary
+	for _, t := range types {
+		summaries, err := r.findAllSummary(t, cq)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, summaries...)
+	}
+	return result, nil
+}
+
+// FindOneSummary returns mongo.ErrNoDocuments when the device does not exist.
+func (r *DeviceRepository) FindOneSummary(device string, id bson.ObjectID) (*DeviceSummary, error) {
+	switch device {
+	case _const.CCTV:
+		cctv, err := r.cctv.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:     _const.CCTV,
+			ID:         cctv.ID,
+			AssetTag:   cctv.AssetTag,
+			Status:     doc.DeviceStatus(cctv.Status),
+			LocationID: cctv.LocationID,
+			Nama:       cctv.Nama,
+			Kode:       cctv.Kode,
+			Lokasi:     cctv.Lokasi,
+			Inserted:   cctv.Inserted.At,
+		}, nil
+	case _const.Fingerprint:
+		fp, err := r.fingerprint.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:     _const.Fingerprint,
+			ID:         fp.ID,
+			AssetTag:   fp.AssetTag,
+			Status:     doc.DeviceStatus(fp.Status),
+			LocationID: fp.LocationID,
+			Nama:       fp.Nama,
+			Kode:       fp.Kode,
+			Lokasi:     fp.Lokasi,
+			Inserted:   fp.Inserted.At,
+		}, nil
+	case _const.KomputerPH1:
+		kph1, err := r.kph1.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:     _const.KomputerPH1,
+			ID:         kph1.ID,
+			AssetTag:   kph1.AssetTag,
+			Status:     doc.DeviceStatus(kph1.Status),
+			LocationID: kph1.LocationID,
+			Nama:       kph1.Nama,
+			Lokasi:     kph1.Lokasi,
+			Inserted:   kph1.Inserted.At,
+		}, nil
+	case _const.KomputerPH2:
+		kph2, err := r.kph2.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:     _const.KomputerPH2,
+			ID:         kph2.ID,
+			AssetTag:   kph2.AssetTag,
+			Status:     doc.DeviceStatus(kph2.Status),
+			LocationID: kph2.LocationID,
+			Nama:       kph2.Nama,
+			Lokasi:     kph2.Lokasi,
+			Inserted:   kph2.Inserted.At,
+		}, nil
+	case _const.Printer:
+		printer, err := r.printer.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:       _const.Printer,
+			ID:           printer.ID,
+			AssetTag:     printer.AssetTag,
+			Status:       doc.DeviceStatus(printer.Status),
+			LocationID:   printer.LocationID,
+			DepartmentID: printer.DepartmentID,
+			Nama:         printer.Nama,
+			NoSeri:       printer.NoSeri,
+			Departemen:   printer.Departemen,
+			Inserted:     printer.Inserted.At,
+		}, nil
+	case _const.Telepon:
+		telepon, err := r.telepon.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:       _const.Telepon,
+			ID:           telepon.ID,
+			AssetTag:     telepon.AssetTag,
+			Status:       doc.DeviceStatus(telepon.Status),
+			LocationID:   telepon.LocationID,
+			DepartmentID: telepon.DepartmentID,
+			Nama:         telepon.User,
+			Kode:         telepon.Ext,
+			Lokasi:       telepon.Lokasi,
+			Departemen:   telepon.Departemen,
+			Inserted:     telepon.Inserted.At,
+		}, nil
+	case _const.Toa:
+		toa, err := r.toa.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:     _const.Toa,
+			ID:         toa.ID,
+			AssetTag:   toa.AssetTag,
+			Status:     doc.DeviceStatus(toa.Status),
+			LocationID: toa.LocationID,
+			Nama:       toa.Nama,
+			Kode:       toa.Kode,
+			Lokasi:     toa.Lokasi,
+			Inserted:   toa.Inserted.At,
+		}, nil
+	case _const.Ups:
+		ups, err := r.ups.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return &DeviceSummary{
+			Device:       _const.Ups,
+			ID:           ups.ID,
+			AssetTag:     ups.AssetTag,
+			Status:       doc.DeviceStatus(ups.Status),
+			LocationID:   ups.LocationID,
+			DepartmentID: ups.DepartmentID,
+			Nama:         ups.Nama,
+			NoSeri:       ups.NoSeri,
+			Lokasi:       ups.Lokasi,
+			Departemen:   ups.Departemen,
+			Inserted:     ups.Inserted.At,
+		}, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// FindOne returns the device record of the type, or mongo.ErrNoDocuments
+// when it does not exist.
+func (r *DeviceRepository) FindOne(device string, id bson.ObjectID) (interface{}, error) {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.FindOneByID(id)
+	case _const.Fingerprint:
+		return r.fingerprint.FindOneByID(id)
+	case _const.KomputerPH1:
+		return r.kph1.FindOneByID(id)
+	case _const.KomputerPH2:
+		return r.kph2.FindOneByID(id)
+	case _const.Printer:
+		return r.printer.FindOneByID(id)
+	case _const.Telepon:
+		return r.telepon.FindOneByID(id)
+	case _const.Toa:
+		return r.toa.FindOneByID(id)
+	case _const.Ups:
+		return r.ups.FindOneByID(id)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *DeviceRepository) findAllSummary(device string, cq *util.CommonQuery) ([]DeviceSummary, error) {
+	switch device {
+	case _const.CCTV:
+		cctvs, err := r.cctv.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*cctvs))
+		for _, cctv := range *cctvs {
+			result = append(result, DeviceSummary{
+				Device:     _const.CCTV,
+				ID:         cctv.ID,
+				AssetTag:   cctv.AssetTag,
+				Status:     doc.DeviceStatus(cctv.Status),
+				LocationID: cctv.LocationID,
+				Nama:       cctv.Nama,
+				Kode:       cctv.Kode,
+				Lokasi:     cctv.Lokasi,
+				Inserted:   cctv.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.Fingerprint:
+		fps, err := r.fingerprint.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*fps))
+		for _, fp := range *fps {
+			result = append(result, DeviceSummary{
+				Device:     _const.Fingerprint,
+				ID:         fp.ID,
+				AssetTag:   fp.AssetTag,
+				Status:     doc.DeviceStatus(fp.Status),
+				LocationID: fp.LocationID,
+				Nama:       fp.Nama,
+				Kode:       fp.Kode,
+				Lokasi:     fp.Lokasi,
+				Inserted:   fp.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.KomputerPH1:
+		kph1s, err := r.kph1.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*kph1s))
+		for _, kph1 := range *kph1s {
+			result = append(result, DeviceSummary{
+				Device:     _const.KomputerPH1,
+				ID:         kph1.ID,
+				AssetTag:   kph1.AssetTag,
+				Status:     doc.DeviceStatus(kph1.Status),
+				LocationID: kph1.LocationID,
+				Nama:       kph1.Nama,
+				Lokasi:     kph1.Lokasi,
+				Inserted:   kph1.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.KomputerPH2:
+		kph2s, err := r.kph2.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*kph2s))
+		for _, kph2 := range *kph2s {
+			result = append(result, DeviceSummary{
+				Device:     _const.KomputerPH2,
+				ID:         kph2.ID,
+				AssetTag:   kph2.AssetTag,
+				Status:     doc.DeviceStatus(kph2.Status),
+				LocationID: kph2.LocationID,
+				Nama:       kph2.Nama,
+				Lokasi:     kph2.Lokasi,
+				Inserted:   kph2.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.Printer:
+		printers, err := r.printer.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*printers))
+		for _, printer := range *printers {
+			result = append(result, DeviceSummary{
+				Device:       _const.Printer,
+				ID:           printer.ID,
+				AssetTag:     printer.AssetTag,
+				Status:       doc.DeviceStatus(printer.Status),
+				LocationID:   printer.LocationID,
+				DepartmentID: printer.DepartmentID,
+				Nama:         printer.Nama,
+				NoSeri:       printer.NoSeri,
+				Departemen:   printer.Departemen,
+				Inserted:     printer.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.Telepon:
+		telepons, err := r.telepon.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*telepons))
+		for _, telepon := range *telepons {
+			result = append(result, DeviceSummary{
+				Device:       _const.Telepon,
+				ID:           telepon.ID,
+				AssetTag:     telepon.AssetTag,
+				Status:       doc.DeviceStatus(telepon.Status),
+				LocationID:   telepon.LocationID,
+				DepartmentID: telepon.DepartmentID,
+				Nama:         telepon.User,
+				Kode:         telepon.Ext,
+				Lokasi:       telepon.Lokasi,
+				Departemen:   telepon.Departemen,
+				Inserted:     telepon.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.Toa:
+		toas, err := r.toa.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*toas))
+		for _, toa := range *toas {
+			result = append(result, DeviceSummary{
+				Device:     _const.Toa,
+				ID:         toa.ID,
+				AssetTag:   toa.AssetTag,
+				Status:     doc.DeviceStatus(toa.Status),
+				LocationID: toa.LocationID,
+				Nama:       toa.Nama,
+				Kode:       toa.Kode,
+				Lokasi:     toa.Lokasi,
+				Inserted:   toa.Inserted.At,
+			})
+		}
+		return result, nil
+	case _const.Ups:
+		upss, err := r.ups.FindAll(cq)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]DeviceSummary, 0, len(*upss))
+		for _, ups := range *upss {
+			result = append(result, DeviceSummary{
+				Device:       _const.Ups,
+				ID:           ups.ID,
+				AssetTag:     ups.AssetTag,
+				Status:       doc.DeviceStatus(ups.Status),
+				LocationID:   ups.LocationID,
+				DepartmentID: ups.DepartmentID,
+				Nama:         ups.Nama,
+				NoSeri:       ups.NoSeri,
+				Lokasi:       ups.Lokasi,
+				Departemen:   ups.Departemen,
+				Inserted:     ups.Inserted.At,
+			})
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+// FindTrash returns the soft deleted devices of the type.
+func (r *DeviceRepository) FindTrash(device string) ([]doc.TrashRecord, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindTrash(coll, "nama", "user")
+}
+
+// FindTrashedBefore returns the ids of the devices of the type deleted before
+// the given date.
+func (r *DeviceRepository) FindTrashedBefore(device string, before time.Time) ([]bson.ObjectID, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindTrashedBefore(coll, before)
+}
+
+// InitDeletedAt dates the devices of the type deleted before the deleter was
+// tracked as deleted now.
+func (r *DeviceRepository) InitDeletedAt(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+	return doc.InitDeletedAt(coll)
+}
+
+func (r *DeviceRepository) Restore(device string, id bson.ObjectID) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+	return doc.Restore(coll, id)
+}
+
+func (r *DeviceRepository) Purge(device string, id bson.ObjectID) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+	return doc.Purge(coll, id)
+}
+
+// FindStatus returns the lifecycle status of the device.
+func (r *DeviceRepository) FindStatus(device string, id bson.ObjectID) (string, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return "", mongo.ErrNoDocuments
+	}
+	return doc.FindStatus(coll, id)
+}
+
+// SetStatus applies a lifecycle transition to the device, returning
+// mongo.ErrNoDocuments when it is no longer in change.From.
+func (r *DeviceRepository) SetStatus(device string, id bson.ObjectID, change doc.StatusChange) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+	return doc.SetStatus(coll, id, change)
+}
+
+// InitStatus marks the devices of the type written before the lifecycle
+// status was tracked as active.
+func (r *DeviceRepository) InitStatus(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+	return doc.InitStatus(coll)
+}
+
+// CountByLocation returns the number of devices of every type at one of the
+// given locations.
+func (r *DeviceRepository) CountByLocation(ids []bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"location_id": bson.M{"$in": ids},
+		"is_deleted":  bson.M{"$ne": true},
+	}
+
+	var total int64
+	for _, device := range _const.Devices {
+		count, err := r.coll(device).CountDocuments(context.TODO(), filter)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// SetLokasi renames the lokasi of the devices of every type at the location.
+// Printers have no lokasi.
+func (r *DeviceRepository) SetLokasi(locationID bson.ObjectID, lokasi string) error {
+	filter := bson.M{
+		"location_id": locationID,
+		"is_deleted":  bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"lokasi":      lokasi,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	for _, device := range _const.Devices {
+		if device == _const.Printer {
+			continue
+		}
+		_, err := r.coll(device).UpdateMany(context.TODO(), filter, update)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountUnlocated returns the number of devices of the type without a location
+// per lokasi value.
+func (r *DeviceRepository) CountUnlocated(device string) (map[string]int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return countUnreferenced(coll, "location_id", "lokasi")
+}
+
+// Locate references the location from the devices of the type without a
+// location whose lokasi is the given value, and replaces their lokasi by the
+// location name.
+func (r *DeviceRepository) Locate(device, lokasi string, locationID bson.ObjectID, name string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, mongo.ErrNoDocuments
+	}
+	return reference(coll, "location_id", "lokasi", lokasi, locationID, name)
+}
+
+// CountByDepartment returns the number of printers, telepons and UPS of the
+// department.
+func (r *DeviceRepository) CountByDepartment(id bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"department_id": id,
+		"is_deleted":    bson.M{"$ne": true},
+	}
+
+	var total int64
+	for _, device := range _const.DepartmentDevices {
+		count, err := r.coll(device).CountDocuments(context.TODO(), filter)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// SetDepartemen renames the departemen of the printers, telepons and UPS of
+// the department.
+func (r *DeviceRepository) SetDepartemen(departmentID bson.ObjectID, departemen string) error {
+	filter := bson.M{
+		"department_id": departmentID,
+		"is_deleted":    bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"departemen":  departemen,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	for _, device := range _const.DepartmentDevices {
+		_, err := r.coll(device).UpdateMany(context.TODO(), filter, update)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountUndepartmented returns the number of devices of the type without a
+// department per departemen value.
+func (r *DeviceRepository) CountUndepartmented(device string) (map[string]int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return countUnreferenced(coll, "department_id", "departemen")
+}
+
+// AssignDepartment references the department from the devices of the type
+// without a department whose departemen is the given value, and replaces
+// their departemen by the department name.
+func (r *DeviceRepository) AssignDepartment(device, departemen string, departmentID bson.ObjectID, name string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, mongo.ErrNoDocuments
+	}
+	return reference(coll, "department_id", "departemen", departemen, departmentID, name)
+}
+
+// countUnreferenced returns the number of devices of coll without the ref
+// field per value of the free text field they were written with.
+func countUnreferenced(coll *mongo.Collection, ref, text string) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			ref:          bson.M{"$exists": false},
+			text:         bson.M{"$nin": bson.A{nil, ""}},
+			"is_deleted": bson.M{"$ne": true},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$" + text,
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var groups []struct {
+		Value string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	err = cur.All(context.TODO(), &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		result[g.Value] = g.Count
+	}
+	return result, nil
+}
+
+// reference sets the ref field of the devices of coll without it whose free
+// text field is value, replacing the text by name.
+func reference(coll *mongo.Collection, ref, text, value string, id bson.ObjectID, name string) (int64, error) {
+	filter := bson.M{
+		ref:          bson.M{"$exists": false},
+		text:         value,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			ref:           id,
+			text:          name,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	res, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/util"
+)
+
+// Each calls fn with every device of the type matching the list filters of
+// cq, read one at a time from a cursor. The document passed to fn is only
+// valid until fn returns.
+func (r *DeviceRepository) Each(device string, cq *util.CommonQuery, fn func(bson.Raw) error) error {
+	var filter bson.M
+	switch device {
+	case _const.CCTV:
+		filter = r.cctv.filter(cq)
+	case _const.Fingerprint:
+		filter = r.fingerprint.filter(cq)
+	case _const.KomputerPH1:
+		filter = r.kph1.filter(cq)
+	case _const.KomputerPH2:
+		filter = r.kph2.filter(cq)
+	case _const.Printer:
+		filter = r.printer.filter(cq)
+	case _const.Telepon:
+		filter = r.telepon.filter(cq)
+	case _const.Toa:
+		filter = r.toa.filter(cq)
+	case _const.Ups:
+		filter = r.ups.filter(cq)
+	default:
+		return mongo.ErrNoDocuments
+	}
+
+	cur, err := r.coll(device).Find(context.TODO(), filter, options.Find().SetSort(bson.M{"_id": cq.Sort}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+
+	for cur.Next(context.TODO()) {
+		if err = fn(cur.Current); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
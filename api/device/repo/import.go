@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"strings"
+)
+
+// ImportKey joins the values of the fields identifying a device, the way
+// TakenKeys returns them. It is case-sensitive like the unique indexes, so a
+// row is refused exactly when inserting it would be.
+func ImportKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+// TakenKeys returns the ImportKey of the fields of every device of the type,
+// ignoring devices leaving the last field empty.
+func (r *DeviceRepository) TakenKeys(device string, fields []string) (map[string]bool, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	last := fields[len(fields)-1]
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+		last:         bson.M{"$gt": ""},
+	}
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+
+	cur, err := coll.Find(context.TODO(), filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var devices []bson.M
+	err = cur.All(context.TODO(), &devices)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i], _ = d[field].(string)
+		}
+		taken[ImportKey(values)] = true
+	}
+	return taken, nil
+}
+
+func text(values map[string]interface{}, name string) string {
+	s, _ := values[name].(string)
+	return s
+}
+
+func ref(values map[string]interface{}, name string) *bson.ObjectID {
+	id, _ := values[name].(*bson.ObjectID)
+	return id
+}
+
+// InsertRows inserts active devices of the type from validated import rows,
+// keyed by the field names of the type. The location_id and department_id of
+// a row reference the location and department its lokasi and departemen were
+// resolved to. Either all rows are inserted or none are.
+func (r *DeviceRepository) InsertRows(device string, rows []map[string]interface{}, inserted doc.ByAt) error {
+	switch device {
+	case _const.CCTV:
+		cctvs := make([]CCTV, 0, len(rows))
+		for _, values := range rows {
+			cctvs = append(cctvs, CCTV{
+				ID:         bson.NewObjectID(),
+				Nama:       text(values, "nama"),
+				Lokasi:     text(values, "lokasi"),
+				Kode:       text(values, "kode"),
+				LocationID: ref(values, "location_id"),
+				Status:     _const.DeviceActive,
+				Inserted:   inserted,
+				IsDeleted:  false,
+			})
+		}
+		return r.cctv.InsertMany(cctvs)
+	case _const.Fingerprint:
+		fps := make([]FingerPrint, 0, len(rows))
+		for _, values := range rows {
+			fps = append(fps, FingerPrint{
+				ID:         bson.NewObjectID(),
+				Nama:       text(values, "nama"),
+				Lokasi:     text(values, "lokasi"),
+				Kode:       text(values, "kode"),
+				LocationID: ref(values, "location_id"),
+				Status:     _const.DeviceActive,
+				Inserted:   inserted,
+				IsDeleted:  false,
+			})
+		}
+		return r.fingerprint.InsertMany(fps)
+	case _const.KomputerPH1:
+		kph1s := make([]KomputerPH1, 0, len(rows))
+		for _, values := range rows {
+			kph1s = append(kph1s, KomputerPH1{
+				ID:         bson.NewObjectID(),
+				Nama:       text(values, "nama"),
+				Merk:       text(values, "merk"),
+				PC:         text(values, "pc"),
+				Monitor:    text(values, "monitor"),
+				CPU:        text(values, "cpu"),
+				RAM:        text(values, "ram"),
+				Internal:   text(values, "internal"),
+				Lokasi:     text(values, "lokasi"),
+				LocationID: ref(values, "location_id"),
+				Status:     _const.DeviceActive,
+				Inserted:   inserted,
+				IsDeleted:  false,
+			})
+		}
+		return r.kph1.InsertMany(kph1s)
+	case _const.KomputerPH2:
+		kph2s := make([]KomputerPH2, 0, len(rows))
+		for _, values := range rows {
+			kph2s = append(kph2s, KomputerPH2{
+				ID:         bson.NewObjectID(),
+				Nama:       text(values, "nama"),
+				Merk:       text(values, "merk"),
+				PC:         text(values, "pc"),
+				Monitor:    text(values, "monitor"),
+				CPU:        text(values, "cpu"),
+				RAM:        text(values, "ram"),
+				Internal:   text(values, "internal"),
+				Lokasi:     text(values, "lokasi"),
+				LocationID: ref(values, "location_id"),
+				Status:     _const.DeviceActive,
+				Inserted:   inserted,
+				IsDeleted:  false,
+			})
+		}
+		return r.kph2.InsertMany(kph2s)
+	case _const.Printer:
+		printers := make([]Printer, 0, len(rows))
+		for _, values := range rows {
+			printers = append(printers, Printer{
+				ID:           bson.NewObjectID(),
+				Nama:         text(values, "nama"),
+				Departemen:   text(values, "departemen"),
+				TipePrinter:  text(values, "tipe_printer"),
+				NoSeri:       text(values, "no_seri"),
+				LocationID:   ref(values, "location_id"),
+				DepartmentID: ref(values, "department_id"),
+				Status:       _const.DeviceActive,
+				Inserted:     inserted,
+				IsDeleted:    false,
+			})
+		}
+		return r.printer.InsertMany(printers)
+	case _const.Telepon:
+		telepons := make([]Telepon, 0, len(rows))
+		for _, values := range rows {
+			telepons = append(telepons, Telepon{
+				ID:           bson.NewObjectID(),
+				Lokasi:       text(values, "lokasi"),
+				Departemen:   text(values, "departemen"),
+				User:         text(values, "user"),
+				Ext:          text(values, "ext"),
+				Merk:         text(values, "merk"),
+				Tipe:         text(values, "tipe"),
+				LocationID:   ref(values, "location_id"),
+				DepartmentID: ref(values, "department_id"),
+				Status:       _const.DeviceActive,
+				Inserted:     inserted,
+				IsDeleted:    false,
+			})
+		}
+		return r.telepon.InsertMany(telepons)
+	case _const.Toa:
+		toas := make([]TOA, 0, len(rows))
+		for _, values := range rows {
+			toas = append(toas, TOA{
+				ID:         bson.NewObjectID(),
+				Nama:       text(values, "nama"),
+				Lokasi:     text(values, "lokasi"),
+				Kode:       text(values, "kode"),
+				Posisi:     text(values, "posisi"),
+				LocationID: ref(values, "location_id"),
+				Status:     _const.DeviceActive,
+				Inserted:   inserted,
+				IsDeleted:  false,
+			})
+		}
+		return r.toa.InsertMany(toas)
+	case _const.Ups:
+		ups := make([]UPS, 0, len(rows))
+		for _, values := range rows {
+			ups = append(ups, UPS{
+				ID:           bson.NewObjectID(),
+				Nama:         text(values, "nama"),
+				Departemen:   text(values, "departemen"),
+				Tipe:         text(values, "tipe"),
+				NoSeri:       text(values, "no_seri"),
+				Lokasi:       text(values, "lokasi"),
+				LocationID:   ref(values, "location_id"),
+				DepartmentID: ref(values, "department_id"),
+				Status:       _const.DeviceActive,
+				Inserted:     inserted,
+				IsDeleted:    false,
+			})
+		}
+		return r.ups.InsertMany(ups)
+	}
+	return mongo.ErrNoDocuments
+}
@@ -4,23 +4,33 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type KomputerPH1 struct {
-	ID        bson.ObjectID `json:"_id" bson:"_id"`
-	Nama      string        `json:"nama" bson:"nama"`
-	Merk      string        `json:"merk" bson:"merk"`
-	PC        string        `json:"pc" bson:"pc"`
-	Monitor   string        `json:"monitor" bson:"monitor"`
-	CPU       string        `json:"cpu" bson:"cpu"`
-	RAM       string        `json:"ram" bson:"ram"`
-	Internal  string        `json:"internal" bson:"internal"`
-	Lokasi    string        `json:"lokasi" bson:"lokasi"`
-	Inserted  doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated   *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Merk          string             `json:"merk" bson:"merk"`
+	PC            string             `json:"pc" bson:"pc"`
+	Monitor       string             `json:"monitor" bson:"monitor"`
+	CPU           string             `json:"cpu" bson:"cpu"`
+	RAM           string             `json:"ram" bson:"ram"`
+	Internal      string             `json:"internal" bson:"internal"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type KomputerPH1CollRepository struct {
@@ -33,8 +43,13 @@ func NewKomputerPH1Repository(db *mongo.Database) *KomputerPH1CollRepository {
 	}
 }
 
-func (r *KomputerPH1CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH1, error) {
-	var kph1s []KomputerPH1
+// EnsureIndexes creates the indexes of the komputer_ph1s collection.
+func (r *KomputerPH1CollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *KomputerPH1CollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -44,6 +59,20 @@ func (r *KomputerPH1CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+	return filter
+}
+
+func (r *KomputerPH1CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH1, error) {
+	var kph1s []KomputerPH1
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -80,22 +109,48 @@ func (r *KomputerPH1CollRepository) FindOneByID(id bson.ObjectID) (*KomputerPH1,
 }
 
 func (r *KomputerPH1CollRepository) InsertOne(kph1 *KomputerPH1) error {
-	_, err := r.coll.InsertOne(context.TODO(), kph1)
+	tag, err := nextTag(r.coll.Database(), _const.KomputerPH1)
+	if err != nil {
+		return err
+	}
+
+	kph1.AssetTag = tag
+	kph1.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), kph1)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *KomputerPH1CollRepository) InsertMany(kph1s []KomputerPH1) error {
-	_, err := r.coll.InsertMany(context.TODO(), kph1s)
+	tags, err := nextTags(r.coll.Database(), _const.KomputerPH1, len(kph1s))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range kph1s {
+		kph1s[i].AssetTag = tags[i]
+		kph1s[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), kph1s)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(kph1s))
+		for i := range kph1s {
+			ids[i] = kph1s[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *KomputerPH1CollRepository) UpdateOneByID(id bson.ObjectID, kph1 *KomputerPH1) error {
+	kph1.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -103,6 +158,9 @@ func (r *KomputerPH1CollRepository) UpdateOneByID(id bson.ObjectID, kph1 *Komput
 	update := bson.M{
 		"$set": kph1,
 	}
+	if kph1.LocationID == nil {
+		update["$unset"] = bson.M{"location_id": ""}
+	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
@@ -122,14 +180,25 @@ func (r *KomputerPH1CollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *KomputerPH1CollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *KomputerPH1CollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -139,13 +208,27 @@ func (r *KomputerPH1CollRepository) CountQuery(cq *util.CommonQuery) (int64, err
 	return count, nil
 }
 
-func (r *KomputerPH1CollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *KomputerPH1CollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *KomputerPH1CollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -154,3 +237,27 @@ func (r *KomputerPH1CollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the komputer ph1s written at or after since,
+// or all of them when since is zero.
+func (r *KomputerPH1CollRepository) FindAllModifiedSince(since time.Time) (*[]KomputerPH1, error) {
+	var kph1s []KomputerPH1
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &kph1s)
+	if err != nil {
+		return nil, err
+	}
+	if kph1s == nil {
+		return &[]KomputerPH1{}, nil
+	}
+	return &kph1s, nil
+}
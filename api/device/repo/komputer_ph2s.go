@@ -4,23 +4,33 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type KomputerPH2 struct {
-	ID        bson.ObjectID `json:"_id" bson:"_id"`
-	Nama      string        `json:"nama" bson:"nama"`
-	Merk      string        `json:"merk" bson:"merk"`
-	PC        string        `json:"pc" bson:"pc"`
-	Monitor   string        `json:"monitor" bson:"monitor"`
-	CPU       string        `json:"cpu" bson:"cpu"`
-	RAM       string        `json:"ram" bson:"ram"`
-	Internal  string        `json:"internal" bson:"internal"`
-	Lokasi    string        `json:"lokasi" bson:"lokasi"`
-	Inserted  doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated   *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Merk          string             `json:"merk" bson:"merk"`
+	PC            string             `json:"pc" bson:"pc"`
+	Monitor       string             `json:"monitor" bson:"monitor"`
+	CPU           string             `json:"cpu" bson:"cpu"`
+	RAM           string             `json:"ram" bson:"ram"`
+	Internal      string             `json:"internal" bson:"internal"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type KomputerPH2CollRepository struct {
@@ -33,8 +43,13 @@ func NewKomputerPH2Repository(db *mongo.Database) *KomputerPH2CollRepository {
 	}
 }
 
-func (r *KomputerPH2CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH2, error) {
-	var kph2s []KomputerPH2
+// EnsureIndexes creates the indexes of the komputer_ph2s collection.
+func (r *KomputerPH2CollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *KomputerPH2CollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -44,6 +59,20 @@ func (r *KomputerPH2CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+	return filter
+}
+
+func (r *KomputerPH2CollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH2, error) {
+	var kph2s []KomputerPH2
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -80,22 +109,48 @@ func (r *KomputerPH2CollRepository) FindOneByID(id bson.ObjectID) (*KomputerPH2,
 }
 
 func (r *KomputerPH2CollRepository) InsertOne(kph2 *KomputerPH2) error {
-	_, err := r.coll.InsertOne(context.TODO(), kph2)
+	tag, err := nextTag(r.coll.Database(), _const.KomputerPH2)
+	if err != nil {
+		return err
+	}
+
+	kph2.AssetTag = tag
+	kph2.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), kph2)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *KomputerPH2CollRepository) InsertMany(kph2s []KomputerPH2) error {
-	_, err := r.coll.InsertMany(context.TODO(), kph2s)
+	tags, err := nextTags(r.coll.Database(), _const.KomputerPH2, len(kph2s))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range kph2s {
+		kph2s[i].AssetTag = tags[i]
+		kph2s[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), kph2s)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(kph2s))
+		for i := range kph2s {
+			ids[i] = kph2s[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *KomputerPH2CollRepository) UpdateOneByID(id bson.ObjectID, kph2 *KomputerPH2) error {
+	kph2.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -103,6 +158,9 @@ func (r *KomputerPH2CollRepository) UpdateOneByID(id bson.ObjectID, kph2 *Komput
 	update := bson.M{
 		"$set": kph2,
 	}
+	if kph2.LocationID == nil {
+		update["$unset"] = bson.M{"location_id": ""}
+	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
@@ -122,14 +180,25 @@ func (r *KomputerPH2CollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *KomputerPH2CollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *KomputerPH2CollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -139,13 +208,27 @@ func (r *KomputerPH2CollRepository) CountQuery(cq *util.CommonQuery) (int64, err
 	return count, nil
 }
 
-func (r *KomputerPH2CollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *KomputerPH2CollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *KomputerPH2CollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -154,3 +237,27 @@ func (r *KomputerPH2CollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the komputer ph2s written at or after since,
+// or all of them when since is zero.
+func (r *KomputerPH2CollRepository) FindAllModifiedSince(since time.Time) (*[]KomputerPH2, error) {
+	var kph2s []KomputerPH2
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &kph2s)
+	if err != nil {
+		return nil, err
+	}
+	if kph2s == nil {
+		return &[]KomputerPH2{}, nil
+	}
+	return &kph2s, nil
+}
@@ -4,19 +4,30 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type Printer struct {
-	ID          bson.ObjectID `json:"_id" bson:"_id"`
-	Nama        string        `json:"nama" bson:"nama"`
-	Departemen  string        `json:"departemen" bson:"departemen"`
-	TipePrinter string        `json:"tipe_printer" bson:"tipe_printer"`
-	NoSeri      string        `json:"no_seri" bson:"no_seri"`
-	Inserted    doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated     *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted   bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Departemen    string             `json:"departemen" bson:"departemen"`
+	DepartmentID  *bson.ObjectID     `json:"department_id,omitempty" bson:"department_id,omitempty"`
+	TipePrinter   string             `json:"tipe_printer" bson:"tipe_printer"`
+	NoSeri        string             `json:"no_seri" bson:"no_seri"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type PrinterCollRepository struct {
@@ -29,8 +40,13 @@ func NewPrinterRepository(db *mongo.Database) *PrinterCollRepository {
 	}
 }
 
-func (r *PrinterCollRepository) FindAll(cq *util.CommonQuery) (*[]Printer, error) {
-	var printers []Printer
+// EnsureIndexes creates the indexes of the printers collection.
+func (r *PrinterCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("no_seri"), index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *PrinterCollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -40,6 +56,24 @@ func (r *PrinterCollRepository) FindAll(cq *util.CommonQuery) (*[]Printer, error
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+	return filter
+}
+
+func (r *PrinterCollRepository) FindAll(cq *util.CommonQuery) (*[]Printer, error) {
+	var printers []Printer
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -76,22 +110,48 @@ func (r *PrinterCollRepository) FindOneByID(id bson.ObjectID) (*Printer, error)
 }
 
 func (r *PrinterCollRepository) InsertOne(printer *Printer) error {
-	_, err := r.coll.InsertOne(context.TODO(), printer)
+	tag, err := nextTag(r.coll.Database(), _const.Printer)
+	if err != nil {
+		return err
+	}
+
+	printer.AssetTag = tag
+	printer.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), printer)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *PrinterCollRepository) InsertMany(printers []Printer) error {
-	_, err := r.coll.InsertMany(context.TODO(), printers)
+	tags, err := nextTags(r.coll.Database(), _const.Printer, len(printers))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range printers {
+		printers[i].AssetTag = tags[i]
+		printers[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), printers)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(printers))
+		for i := range printers {
+			ids[i] = printers[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *PrinterCollRepository) UpdateOneByID(id bson.ObjectID, printer *Printer) error {
+	printer.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -100,6 +160,17 @@ func (r *PrinterCollRepository) UpdateOneByID(id bson.ObjectID, printer *Printer
 		"$set": printer,
 	}
 
+	unset := bson.M{}
+	if printer.LocationID == nil {
+		unset["location_id"] = ""
+	}
+	if printer.DepartmentID == nil {
+		unset["department_id"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
@@ -118,16 +189,37 @@ func (r *PrinterCollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *PrinterCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *PrinterCollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
 	}
 
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *PrinterCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return 0, err
@@ -135,13 +227,17 @@ func (r *PrinterCollRepository) CountQuery(cq *util.CommonQuery) (int64, error)
 	return count, nil
 }
 
-func (r *PrinterCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *PrinterCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -150,3 +246,27 @@ func (r *PrinterCollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the printers written at or after since, or all of
+// them when since is zero.
+func (r *PrinterCollRepository) FindAllModifiedSince(since time.Time) (*[]Printer, error) {
+	var printers []Printer
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &printers)
+	if err != nil {
+		return nil, err
+	}
+	if printers == nil {
+		return &[]Printer{}, nil
+	}
+	return &printers, nil
+}
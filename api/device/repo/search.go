@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"regexp"
+	"strings"
+)
+
+// searchFields are the identifying fields matched by Search, with the weight
+// of a match. Telepon has User and Ext where the other types have Nama and
+// Kode.
+var searchFields = []struct {
+	Name   string
+	Weight int
+}{
+	{"asset_tag", 4},
+	{"nama", 4},
+	{"kode", 4},
+	{"no_seri", 4},
+	{"ext", 4},
+	{"user", 3},
+	{"lokasi", 2},
+	{"departemen", 2},
+}
+
+// DeviceSearchResult is a device matched by Search. Score ranks exact field
+// matches above prefix matches above matches inside a field, weighted by the
+// field.
+type DeviceSearchResult struct {
+	Device     string        `json:"device" bson:"device"`
+	ID         bson.ObjectID `json:"_id" bson:"_id"`
+	AssetTag   string        `json:"asset_tag,omitempty" bson:"asset_tag"`
+	Nama       string        `json:"nama,omitempty" bson:"nama"`
+	Kode       string        `json:"kode,omitempty" bson:"kode"`
+	NoSeri     string        `json:"no_seri,omitempty" bson:"no_seri"`
+	Ext        string        `json:"ext,omitempty" bson:"ext"`
+	User       string        `json:"user,omitempty" bson:"user"`
+	Lokasi     string        `json:"lokasi,omitempty" bson:"lokasi"`
+	Departemen string        `json:"departemen,omitempty" bson:"departemen"`
+	Score      int           `json:"score" bson:"score"`
+}
+
+// searchPipeline returns the stages that turn the devices of one type into
+// search results.
+func searchPipeline(device string) mongo.Pipeline {
+	project := bson.M{
+		"device": device,
+	}
+	for _, field := range searchFields {
+		project[field.Name] = bson.M{"$ifNull": bson.A{"$" + field.Name, ""}}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"is_deleted": bson.M{"$ne": true}}}},
+		{{Key: "$project", Value: project}},
+	}
+}
+
+// Search matches q against the identifying fields of the devices of the
+// given types, returning the page of results ordered by relevance and the
+// number of matches.
+func (r *DeviceRepository) Search(q string, types []string, page, limit int) ([]DeviceSearchResult, int64, error) {
+	if len(types) == 0 {
+		return []DeviceSearchResult{}, 0, nil
+	}
+
+	quoted := regexp.QuoteMeta(strings.TrimSpace(q))
+	contains := bson.Regex{Pattern: quoted, Options: "i"}
+
+	var or bson.A
+	var score bson.A
+	for _, field := range searchFields {
+		ref := "$" + field.Name
+		or = append(or, bson.M{field.Name: contains})
+		score = append(score, bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{
+					"case": bson.M{"$regexMatch": bson.M{"input": ref, "regex": "^" + quoted + "$", "options": "i"}},
+					"then": field.Weight * 3,
+				},
+				bson.M{
+					"case": bson.M{"$regexMatch": bson.M{"input": ref, "regex": "^" + quoted, "options": "i"}},
+					"then": field.Weight * 2,
+				},
+				bson.M{
+					"case": bson.M{"$regexMatch": bson.M{"input": ref, "regex": quoted, "options": "i"}},
+					"then": field.Weight,
+				},
+			},
+			"default": 0,
+		}})
+	}
+
+	pipeline := searchPipeline(types[0])
+	for _, device := range types[1:] {
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.M{
+			"coll":     r.coll(device).Name(),
+			"pipeline": searchPipeline(device),
+		}}})
+	}
+
+	skip := (page - 1) * limit
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: bson.M{"$or": or}}},
+		bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$add": score}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "nama", Value: 1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$facet", Value: bson.M{
+			"results": bson.A{bson.M{"$skip": skip}, bson.M{"$limit": limit}},
+			"total":   bson.A{bson.M{"$count": "count"}},
+		}}},
+	)
+
+	cur, err := r.coll(types[0]).Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(context.TODO())
+
+	var facets []struct {
+		Results []DeviceSearchResult `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	err = cur.All(context.TODO(), &facets)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(facets) == 0 || len(facets[0].Total) == 0 {
+		return []DeviceSearchResult{}, 0, nil
+	}
+	return facets[0].Results, facets[0].Total[0].Count, nil
+}
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+)
+
+type counter struct {
+	Seq int `bson:"seq"`
+}
+
+// nextTags reserves n asset tags of the device type, like "PRN-00042". Tags
+// are numbered by a counter per type, so one is never given twice, even
+// after its device is purged.
+func nextTags(db *mongo.Database, device string, n int) ([]string, error) {
+	filter := bson.M{
+		"_id": "asset_tag." + device,
+	}
+	update := bson.M{
+		"$inc": bson.M{"seq": n},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var c counter
+	err := db.Collection("counters").FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("%s-%05d", _const.DeviceTagPrefixes[device], c.Seq-n+i+1)
+	}
+	return tags, nil
+}
+
+func nextTag(db *mongo.Database, device string) (string, error) {
+	tags, err := nextTags(db, device, 1)
+	if err != nil {
+		return "", err
+	}
+	return tags[0], nil
+}
+
+// FindOneByTag returns the device type and id of the device with the asset
+// tag, or mongo.ErrNoDocuments when no device has it.
+func (r *DeviceRepository) FindOneByTag(tag string) (string, bson.ObjectID, error) {
+	device := _const.TagDevice(tag)
+	coll := r.coll(device)
+	if coll == nil {
+		return "", bson.NilObjectID, mongo.ErrNoDocuments
+	}
+
+	filter := bson.M{
+		"asset_tag":  tag,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.FindOne().SetProjection(bson.M{"_id": 1})
+
+	var d struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err := coll.FindOne(context.TODO(), filter, findOptions).Decode(&d)
+	if err != nil {
+		return "", bson.NilObjectID, err
+	}
+	return device, d.ID, nil
+}
+
+// TagUntagged gives an asset tag to the devices of the type inserted before
+// tags existed, oldest first, returning how many were tagged. Deleted devices
+// are tagged too, so restoring one keeps it scannable.
+func (r *DeviceRepository) TagUntagged(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+
+	filter := bson.M{
+		"asset_tag": bson.M{"$exists": false},
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "inserted.at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1})
+
+	cur, err := coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(context.TODO())
+
+	var devices []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err = cur.All(context.TODO(), &devices)
+	if err != nil {
+		return 0, err
+	}
+	if len(devices) == 0 {
+		return 0, nil
+	}
+
+	tags, err := nextTags(coll.Database(), device, len(devices))
+	if err != nil {
+		return 0, err
+	}
+
+	var tagged int64
+	for i, d := range devices {
+		filter := bson.M{
+			"_id":       d.ID,
+			"asset_tag": bson.M{"$exists": false},
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"asset_tag":   tags[i],
+				"modified_at": doc.Stamp(),
+			},
+		}
+
+		res, err := coll.UpdateOne(context.TODO(), filter, update)
+		if err != nil {
+			return tagged, err
+		}
+		tagged += res.ModifiedCount
+	}
+	return tagged, nil
+}
@@ -4,21 +4,32 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type Telepon struct {
-	ID         bson.ObjectID `json:"_id" bson:"_id"`
-	Lokasi     string        `json:"lokasi" bson:"lokasi"`
-	Departemen string        `json:"departemen" bson:"departemen"`
-	User       string        `json:"user" bson:"user"`
-	Ext        string        `json:"ext" bson:"ext"`
-	Merk       string        `json:"merk" bson:"merk"`
-	Tipe       string        `json:"tipe" bson:"tipe"`
-	Inserted   doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated    *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted  bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	Departemen    string             `json:"departemen" bson:"departemen"`
+	DepartmentID  *bson.ObjectID     `json:"department_id,omitempty" bson:"department_id,omitempty"`
+	User          string             `json:"user" bson:"user"`
+	Ext           string             `json:"ext" bson:"ext"`
+	Merk          string             `json:"merk" bson:"merk"`
+	Tipe          string             `json:"tipe" bson:"tipe"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type TeleponCollRepository struct {
@@ -31,8 +42,14 @@ func NewTeleponRepository(db *mongo.Database) *TeleponCollRepository {
 	}
 }
 
-func (r *TeleponCollRepository) FindAll(cq *util.CommonQuery) (*[]Telepon, error) {
-	var telepons []Telepon
+// EnsureIndexes creates the indexes of the telepons collection. Extensions are
+// numbered per site, so they are unique within a lokasi.
+func (r *TeleponCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("ext", "lokasi"), index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *TeleponCollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -42,6 +59,24 @@ func (r *TeleponCollRepository) FindAll(cq *util.CommonQuery) (*[]Telepon, error
 		filter["tipe"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+	return filter
+}
+
+func (r *TeleponCollRepository) FindAll(cq *util.CommonQuery) (*[]Telepon, error) {
+	var telepons []Telepon
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -78,22 +113,48 @@ func (r *TeleponCollRepository) FindOneByID(id bson.ObjectID) (*Telepon, error)
 }
 
 func (r *TeleponCollRepository) InsertOne(telepon *Telepon) error {
-	_, err := r.coll.InsertOne(context.TODO(), telepon)
+	tag, err := nextTag(r.coll.Database(), _const.Telepon)
+	if err != nil {
+		return err
+	}
+
+	telepon.AssetTag = tag
+	telepon.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), telepon)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *TeleponCollRepository) InsertMany(telepons []Telepon) error {
-	_, err := r.coll.InsertMany(context.TODO(), telepons)
+	tags, err := nextTags(r.coll.Database(), _const.Telepon, len(telepons))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range telepons {
+		telepons[i].AssetTag = tags[i]
+		telepons[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), telepons)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(telepons))
+		for i := range telepons {
+			ids[i] = telepons[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *TeleponCollRepository) UpdateOneByID(id bson.ObjectID, telepon *Telepon) error {
+	telepon.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -102,6 +163,17 @@ func (r *TeleponCollRepository) UpdateOneByID(id bson.ObjectID, telepon *Telepon
 		"$set": telepon,
 	}
 
+	unset := bson.M{}
+	if telepon.LocationID == nil {
+		unset["location_id"] = ""
+	}
+	if telepon.DepartmentID == nil {
+		unset["department_id"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
@@ -120,16 +192,37 @@ func (r *TeleponCollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *TeleponCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *TeleponCollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["tipe"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
 	}
 
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *TeleponCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return 0, err
@@ -137,13 +230,17 @@ func (r *TeleponCollRepository) CountQuery(cq *util.CommonQuery) (int64, error)
 	return count, nil
 }
 
-func (r *TeleponCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *TeleponCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -152,3 +249,27 @@ func (r *TeleponCollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the telepons written at or after since, or all of
+// them when since is zero.
+func (r *TeleponCollRepository) FindAllModifiedSince(since time.Time) (*[]Telepon, error) {
+	var telepons []Telepon
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &telepons)
+	if err != nil {
+		return nil, err
+	}
+	if telepons == nil {
+		return &[]Telepon{}, nil
+	}
+	return &telepons, nil
+}
@@ -4,19 +4,29 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type TOA struct {
-	ID        bson.ObjectID `json:"_id" bson:"_id"`
-	Nama      string        `json:"nama" bson:"nama"`
-	Lokasi    string        `json:"lokasi" bson:"lokasi"`
-	Kode      string        `json:"kode" bson:"kode"`
-	Posisi    string        `json:"posisi" bson:"posisi"`
-	Inserted  doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated   *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	Kode          string             `json:"kode" bson:"kode"`
+	Posisi        string             `json:"posisi" bson:"posisi"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type TOACollRepository struct {
@@ -29,8 +39,13 @@ func NewTOARepository(db *mongo.Database) *TOACollRepository {
 	}
 }
 
-func (r *TOACollRepository) FindAll(cq *util.CommonQuery) (*[]TOA, error) {
-	var toas []TOA
+// EnsureIndexes creates the indexes of the toas collection.
+func (r *TOACollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("kode"), index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *TOACollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -40,6 +55,20 @@ func (r *TOACollRepository) FindAll(cq *util.CommonQuery) (*[]TOA, error) {
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+	return filter
+}
+
+func (r *TOACollRepository) FindAll(cq *util.CommonQuery) (*[]TOA, error) {
+	var toas []TOA
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -76,22 +105,48 @@ func (r *TOACollRepository) FindOneByID(id bson.ObjectID) (*TOA, error) {
 }
 
 func (r *TOACollRepository) InsertOne(toa *TOA) error {
-	_, err := r.coll.InsertOne(context.TODO(), toa)
+	tag, err := nextTag(r.coll.Database(), _const.Toa)
+	if err != nil {
+		return err
+	}
+
+	toa.AssetTag = tag
+	toa.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), toa)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *TOACollRepository) InsertMany(toas []TOA) error {
-	_, err := r.coll.InsertMany(context.TODO(), toas)
+	tags, err := nextTags(r.coll.Database(), _const.Toa, len(toas))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range toas {
+		toas[i].AssetTag = tags[i]
+		toas[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), toas)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(toas))
+		for i := range toas {
+			ids[i] = toas[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *TOACollRepository) UpdateOneByID(id bson.ObjectID, toa *TOA) error {
+	toa.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -99,6 +154,9 @@ func (r *TOACollRepository) UpdateOneByID(id bson.ObjectID, toa *TOA) error {
 	update := bson.M{
 		"$set": toa,
 	}
+	if toa.LocationID == nil {
+		update["$unset"] = bson.M{"location_id": ""}
+	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
@@ -118,14 +176,25 @@ func (r *TOACollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *TOACollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *TOACollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -135,13 +204,27 @@ func (r *TOACollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
 	return count, nil
 }
 
-func (r *TOACollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *TOACollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *TOACollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -150,3 +233,27 @@ func (r *TOACollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the toas written at or after since, or all of
+// them when since is zero.
+func (r *TOACollRepository) FindAllModifiedSince(since time.Time) (*[]TOA, error) {
+	var toas []TOA
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &toas)
+	if err != nil {
+		return nil, err
+	}
+	if toas == nil {
+		return &[]TOA{}, nil
+	}
+	return &toas, nil
+}
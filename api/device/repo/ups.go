@@ -4,20 +4,31 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type UPS struct {
-	ID         bson.ObjectID `json:"_id" bson:"_id"`
-	Nama       string        `json:"nama" bson:"nama"`
-	Departemen string        `json:"departemen" bson:"departemen"`
-	Tipe       string        `json:"tipe" bson:"tipe"`
-	NoSeri     string        `json:"no_seri" bson:"no_seri"`
-	Lokasi     string        `json:"lokasi" bson:"lokasi"`
-	Inserted   doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated    *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted  bool          `json:"-" bson:"is_deleted"`
+	ID            bson.ObjectID      `json:"_id" bson:"_id"`
+	AssetTag      string             `json:"asset_tag" bson:"asset_tag,omitempty"`
+	Nama          string             `json:"nama" bson:"nama"`
+	Departemen    string             `json:"departemen" bson:"departemen"`
+	DepartmentID  *bson.ObjectID     `json:"department_id,omitempty" bson:"department_id,omitempty"`
+	Tipe          string             `json:"tipe" bson:"tipe"`
+	NoSeri        string             `json:"no_seri" bson:"no_seri"`
+	Lokasi        string             `json:"lokasi" bson:"lokasi"`
+	LocationID    *bson.ObjectID     `json:"location_id,omitempty" bson:"location_id,omitempty"`
+	Status        string             `json:"status" bson:"status"`
+	StatusHistory []doc.StatusChange `json:"status_history,omitempty" bson:"status_history,omitempty"`
+	Inserted      doc.ByAt           `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt          `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted       *doc.ByAt          `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt    time.Time          `json:"modified_at" bson:"modified_at"`
+	IsDeleted     bool               `json:"-" bson:"is_deleted"`
 }
 
 type UPSCollRepository struct {
@@ -30,8 +41,13 @@ func NewUPSRepository(db *mongo.Database) *UPSCollRepository {
 	}
 }
 
-func (r *UPSCollRepository) FindAll(cq *util.CommonQuery) (*[]UPS, error) {
-	var ups []UPS
+// EnsureIndexes creates the indexes of the ups collection.
+func (r *UPSCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("no_seri"), index.Unique("asset_tag"))
+}
+
+// filter returns the list filter of cq.
+func (r *UPSCollRepository) filter(cq *util.CommonQuery) bson.M {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
 	}
@@ -41,6 +57,24 @@ func (r *UPSCollRepository) FindAll(cq *util.CommonQuery) (*[]UPS, error) {
 		filter["nama"] = bson.M{"$regex": pattern}
 	}
 
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
+	}
+
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+	return filter
+}
+
+func (r *UPSCollRepository) FindAll(cq *util.CommonQuery) (*[]UPS, error) {
+	var ups []UPS
+	filter := r.filter(cq)
+
 	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
 	if err != nil {
 		return nil, err
@@ -77,22 +111,48 @@ func (r *UPSCollRepository) FindOneByID(id bson.ObjectID) (*UPS, error) {
 }
 
 func (r *UPSCollRepository) InsertOne(ups *UPS) error {
-	_, err := r.coll.InsertOne(context.TODO(), ups)
+	tag, err := nextTag(r.coll.Database(), _const.Ups)
+	if err != nil {
+		return err
+	}
+
+	ups.AssetTag = tag
+	ups.ModifiedAt = doc.Stamp()
+	_, err = r.coll.InsertOne(context.TODO(), ups)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertMany inserts all devices or none, removing the inserted ones when the
+// insert fails part way.
 func (r *UPSCollRepository) InsertMany(ups []UPS) error {
-	_, err := r.coll.InsertMany(context.TODO(), ups)
+	tags, err := nextTags(r.coll.Database(), _const.Ups, len(ups))
 	if err != nil {
 		return err
 	}
+
+	stamp := doc.Stamp()
+	for i := range ups {
+		ups[i].AssetTag = tags[i]
+		ups[i].ModifiedAt = stamp
+	}
+
+	_, err = r.coll.InsertMany(context.TODO(), ups)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(ups))
+		for i := range ups {
+			ids[i] = ups[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
 	return nil
 }
 
 func (r *UPSCollRepository) UpdateOneByID(id bson.ObjectID, ups *UPS) error {
+	ups.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
@@ -101,6 +161,17 @@ func (r *UPSCollRepository) UpdateOneByID(id bson.ObjectID, ups *UPS) error {
 		"$set": ups,
 	}
 
+	unset := bson.M{}
+	if ups.LocationID == nil {
+		unset["location_id"] = ""
+	}
+	if ups.DepartmentID == nil {
+		unset["department_id"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
@@ -119,16 +190,37 @@ func (r *UPSCollRepository) Count() (int64, error) {
 	return count, nil
 }
 
-func (r *UPSCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+// CountInventory returns the number of devices matching the status, location
+// and department of the query, or of the devices in service when the query
+// has no status.
+func (r *UPSCollRepository) CountInventory(cq *util.CommonQuery) (int64, error) {
 	filter := bson.M{
 		"is_deleted": bson.M{"$ne": true},
+		"status":     doc.InServiceFilter(),
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	if _const.ValidDeviceStatus(cq.Status) {
+		filter["status"] = doc.StatusFilter(cq.Status)
 	}
 
+	if cq.LocationIDs != nil {
+		filter["location_id"] = bson.M{"$in": cq.LocationIDs}
+	}
+
+	if cq.DepartmentID != nil {
+		filter["department_id"] = *cq.DepartmentID
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *UPSCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(cq)
+
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		return 0, err
@@ -136,13 +228,17 @@ func (r *UPSCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
 	return count, nil
 }
 
-func (r *UPSCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *UPSCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
 		"_id":        id,
 		"is_deleted": bson.M{"$ne": true},
 	}
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
 	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
@@ -151,3 +247,27 @@ func (r *UPSCollRepository) DeleteOneByID(id bson.ObjectID) error {
 	}
 	return nil
 }
+
+// FindAllModifiedSince returns the ups written at or after since, or all of
+// them when since is zero.
+func (r *UPSCollRepository) FindAllModifiedSince(since time.Time) (*[]UPS, error) {
+	var ups []UPS
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &ups)
+	if err != nil {
+		return nil, err
+	}
+	if ups == nil {
+		return &[]UPS{}, nil
+	}
+	return &ups, nil
+}
@@ -2,17 +2,27 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
+	"reflect"
 	"sipamit-be/api/device_cp/repo"
+	repo2 "sipamit-be/api/device_type/repo"
 	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
+	"strings"
 )
 
+// updateCheckpointForm replaces the item names of a template. Items declares
+// the typed items; when it is left out the declarations of the items that
+// are kept stay as they were.
 type updateCheckpointForm struct {
-	Checkpoint []string `json:"checkpoint" form:"checkpoint"`
+	Checkpoint []string     `json:"checkpoint" form:"checkpoint"`
+	Items      []doc.CPItem `json:"items" form:"items"`
 }
 
 func newUpdateCheckpointForm(c echo.Context) (*updateCheckpointForm, error) {
@@ -25,16 +35,54 @@ func newUpdateCheckpointForm(c echo.Context) (*updateCheckpointForm, error) {
 	if len(f.Checkpoint) <= 0 {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Checkpoint is required")
 	}
+
+	if f.Items != nil {
+		if err := doc.ValidateCPItems(f.Checkpoint, f.Items); err != nil {
+			return nil, err
+		}
+	}
 	return f, nil
 }
 
+// items returns the item declarations of the updated template.
+func (f *updateCheckpointForm) items(current []doc.CPItem) []doc.CPItem {
+	if f.Items != nil {
+		return f.Items
+	}
+
+	kept := make(map[string]string, len(f.Checkpoint))
+	for _, name := range f.Checkpoint {
+		kept[strings.ToLower(strings.TrimSpace(name))] = name
+	}
+
+	var items []doc.CPItem
+	for _, item := range current {
+		if name, ok := kept[strings.ToLower(strings.TrimSpace(item.Name))]; ok {
+			item.Name = name
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type checkpointVersionResult struct {
+	repo.CheckpointVersion
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
 type CheckpointHandler struct {
-	cpRepo *repo.CheckpointCollRepository
+	cpRepo        *repo.CheckpointCollRepository
+	cpVersionRepo *repo.CheckpointVersionCollRepository
+	typeRepo      *repo2.DeviceTypeCollRepository
 }
 
 func NewCheckpointAPIHandler(e *echo.Echo, db *mongo.Database) *CheckpointHandler {
 	h := &CheckpointHandler{
-		cpRepo: repo.NewCheckpointRepository(db),
+		cpRepo:        repo.NewCheckpointRepository(db),
+		cpVersionRepo: repo.NewCheckpointVersionRepository(db),
+		typeRepo:      repo2.NewDeviceTypeRepository(db),
 	}
 
 	group := e.Group("/api", context.Handler)
@@ -47,6 +95,8 @@ func NewCheckpointAPIHandler(e *echo.Echo, db *mongo.Database) *CheckpointHandle
 	group.GET("/checkpoint/telepon", h.telepon)
 	group.GET("/checkpoint/toa", h.toa)
 	group.GET("/checkpoint/ups", h.ups)
+	group.GET("/checkpoint/:device", h.findByType)
+	group.GET("/checkpoint/:device/versions", h.versions)
 
 	group.PUT("/checkpoint/cctv", h.updateCCTV)
 	group.PUT("/checkpoint/fingerprint", h.updateFingerprint)
@@ -56,6 +106,7 @@ func NewCheckpointAPIHandler(e *echo.Echo, db *mongo.Database) *CheckpointHandle
 	group.PUT("/checkpoint/telepon", h.updateTelepon)
 	group.PUT("/checkpoint/toa", h.updateToa)
 	group.PUT("/checkpoint/ups", h.updateUps)
+	group.PUT("/checkpoint/:device", h.updateByType)
 
 	return h
 }
@@ -220,6 +271,123 @@ func (h *CheckpointHandler) ups(c echo.Context) error {
 	return c.JSON(http.StatusOK, ups)
 }
 
+// versions
+// @Tags Checkpoint
+// @Summary Get checkpoint template version history
+// @ID get-checkpoint-versions
+// @Security ApiKeyAuth
+// @Param device path string true "Device type or registered device type slug"
+// @Router /api/checkpoint/{device}/versions [GET]
+// @Produce json
+// @Success 200
+func (h *CheckpointHandler) versions(c echo.Context) error {
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		deviceType, err := h.deviceType(c)
+		if err != nil {
+			return err
+		}
+		device = deviceType.Slug
+	}
+
+	versions, err := h.cpVersionRepo.FindAllByDevice(device)
+	if err != nil {
+		log.Errorf("Failed to get %s checkpoint versions: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	var previous repo.CheckpointVersion
+	result := make([]checkpointVersionResult, 0, len(*versions))
+	for _, v := range *versions {
+		added, removed, changed := diffCheckpoint(previous, v)
+		result = append(result, checkpointVersionResult{
+			CheckpointVersion: v,
+			Added:             added,
+			Removed:           removed,
+			Changed:           changed,
+		})
+		previous = v
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// saveVersion stores the template of the device as the version after the one
+// it was read at, then replaces the current template if it is still at that
+// version. The version is stored first so the template never carries one
+// missing from the history, and removed again when the template write fails.
+// It returns mongo.ErrNoDocuments when the template was changed since read.
+func (h *CheckpointHandler) saveVersion(cp *repo.Checkpoint) error {
+	version := &repo.CheckpointVersion{
+		ID:         bson.NewObjectID(),
+		Device:     cp.Device,
+		Version:    cp.Version + 1,
+		Checkpoint: cp.Checkpoint,
+		Items:      cp.Items,
+		Inserted:   *cp.Updated,
+	}
+	err := h.cpVersionRepo.InsertOne(version)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return mongo.ErrNoDocuments
+		}
+		return err
+	}
+
+	err = h.cpRepo.UpdateTemplate(cp, cp.Version)
+	if err != nil {
+		if derr := h.cpVersionRepo.DeleteOneByID(version.ID); derr != nil {
+			return errors.Join(err, fmt.Errorf("remove version %d: %w", version.Version, derr))
+		}
+		return err
+	}
+	cp.Version = version.Version
+	return nil
+}
+
+// diffCheckpoint returns the names added to and removed from the template
+// since the previous version, and the names kept whose item changed its type,
+// unit, thresholds or options.
+func diffCheckpoint(previous, current repo.CheckpointVersion) ([]string, []string, []string) {
+	added := []string{}
+	removed := []string{}
+	changed := []string{}
+
+	prev := make(map[string]bool, len(previous.Checkpoint))
+	for _, name := range previous.Checkpoint {
+		prev[name] = true
+	}
+	curr := make(map[string]bool, len(current.Checkpoint))
+	for _, name := range current.Checkpoint {
+		curr[name] = true
+		if !prev[name] {
+			added = append(added, name)
+		} else if !reflect.DeepEqual(cpItem(previous.Items, name), cpItem(current.Items, name)) {
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range previous.Checkpoint {
+		if !curr[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed, changed
+}
+
+// cpItem returns the item declared for the name, an item without a
+// declaration being a boolean one.
+func cpItem(items []doc.CPItem, name string) doc.CPItem {
+	for _, item := range items {
+		if strings.EqualFold(strings.TrimSpace(item.Name), name) {
+			item.Name = name
+			if item.Type == "" {
+				item.Type = _const.CPBoolean
+			}
+			return item
+		}
+	}
+	return doc.CPItem{Name: name, Type: _const.CPBoolean}
+}
+
 // updateCCTV
 // @Tags Checkpoint
 // @Summary Update cctv checkpoint
@@ -247,11 +415,15 @@ func (h *CheckpointHandler) updateCCTV(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "CCTV checkpoint not found")
 	}
 
+	cctv.Items = f.items(cctv.Items)
 	cctv.Checkpoint = f.Checkpoint
 	cctv.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.CCTV, cctv)
+	err = h.saveVersion(cctv)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "CCTV checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update cctv checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -285,11 +457,15 @@ func (h *CheckpointHandler) updateFingerprint(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Fingerprint checkpoint not found")
 	}
 
+	fingerprint.Items = f.items(fingerprint.Items)
 	fingerprint.Checkpoint = f.Checkpoint
 	fingerprint.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.Fingerprint, fingerprint)
+	err = h.saveVersion(fingerprint)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Fingerprint checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update fingerprint checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -323,11 +499,15 @@ func (h *CheckpointHandler) updateKomputerPh1(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer-ph1 checkpoint not found")
 	}
 
+	komputerPh1.Items = f.items(komputerPh1.Items)
 	komputerPh1.Checkpoint = f.Checkpoint
 	komputerPh1.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.KomputerPH1, komputerPh1)
+	err = h.saveVersion(komputerPh1)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer-ph1 checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update komputer-ph1 checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -361,11 +541,15 @@ func (h *CheckpointHandler) updateKomputerPh2(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer-ph2 checkpoint not found")
 	}
 
+	komputerPh2.Items = f.items(komputerPh2.Items)
 	komputerPh2.Checkpoint = f.Checkpoint
 	komputerPh2.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.KomputerPH2, komputerPh2)
+	err = h.saveVersion(komputerPh2)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer-ph2 checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update komputer-ph2 checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -399,11 +583,15 @@ func (h *CheckpointHandler) updatePrinter(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Printer checkpoint not found")
 	}
 
+	printer.Items = f.items(printer.Items)
 	printer.Checkpoint = f.Checkpoint
 	printer.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.Printer, printer)
+	err = h.saveVersion(printer)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Printer checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update printer checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -437,11 +625,15 @@ func (h *CheckpointHandler) updateTelepon(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Telepon checkpoint not found")
 	}
 
+	telepon.Items = f.items(telepon.Items)
 	telepon.Checkpoint = f.Checkpoint
 	telepon.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.Telepon, telepon)
+	err = h.saveVersion(telepon)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Telepon checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update telepon checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -475,11 +667,15 @@ func (h *CheckpointHandler) updateToa(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Toa checkpoint not found")
 	}
 
+	toa.Items = f.items(toa.Items)
 	toa.Checkpoint = f.Checkpoint
 	toa.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.Toa, toa)
+	err = h.saveVersion(toa)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Toa checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update toa checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
@@ -513,13 +709,106 @@ func (h *CheckpointHandler) updateUps(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Ups checkpoint not found")
 	}
 
+	ups.Items = f.items(ups.Items)
 	ups.Checkpoint = f.Checkpoint
 	ups.Updated = nc.Claims.ByAtPtr()
 
-	err = h.cpRepo.UpdateByDevice(_const.Ups, ups)
+	err = h.saveVersion(ups)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Ups checkpoint was changed, please reload")
+		}
 		log.Errorf("Failed to update ups checkpoint: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Ups checkpoint updated")
 }
+
+// deviceType returns the registered device type of the :device route param.
+func (h *CheckpointHandler) deviceType(c echo.Context) (*repo2.DeviceType, error) {
+	slug := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(c.Param("device"))), "-", "_")
+
+	deviceType, err := h.typeRepo.FindBySlug(slug)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Device type not found")
+		}
+		log.Errorf("Failed to get device type: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return deviceType, nil
+}
+
+// findByType
+// @Tags Checkpoint
+// @Summary Get the checkpoint of a registered device type
+// @ID get-checkpoint-by-type
+// @Security ApiKeyAuth
+// @Param device path string true "Device type slug"
+// @Router /api/checkpoint/{device} [GET]
+// @Produce json
+// @Success 200
+func (h *CheckpointHandler) findByType(c echo.Context) error {
+	deviceType, err := h.deviceType(c)
+	if err != nil {
+		return err
+	}
+
+	cp, err := h.cpRepo.FindByDevice(deviceType.Slug)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Errorf("Failed to get %s checkpoint: %v", deviceType.Slug, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		return echo.NewHTTPError(http.StatusNotFound, deviceType.Name+" checkpoint not found")
+	}
+	return c.JSON(http.StatusOK, cp)
+}
+
+// updateByType
+// @Tags Checkpoint
+// @Summary Update the checkpoint of a registered device type
+// @ID update-checkpoint-by-type
+// @Security ApiKeyAuth
+// @Param device path string true "Device type slug"
+// @Param checkpoint body updateCheckpointForm true "Checkpoint"
+// @Router /api/checkpoint/{device} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *CheckpointHandler) updateByType(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := h.deviceType(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := newUpdateCheckpointForm(c)
+	if err != nil {
+		return err
+	}
+
+	cp, err := h.cpRepo.FindByDevice(deviceType.Slug)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Errorf("Failed to get %s checkpoint: %v", deviceType.Slug, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		return echo.NewHTTPError(http.StatusNotFound, deviceType.Name+" checkpoint not found")
+	}
+
+	cp.Items = f.items(cp.Items)
+	cp.Checkpoint = f.Checkpoint
+	cp.Updated = nc.Claims.ByAtPtr()
+
+	err = h.saveVersion(cp)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, deviceType.Name+" checkpoint was changed, please reload")
+		}
+		log.Errorf("Failed to update %s checkpoint: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceType.Name+" checkpoint updated")
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"reflect"
+	"sipamit-be/api/device_cp/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"testing"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestDiffCheckpoint(t *testing.T) {
+	v1 := repo.CheckpointVersion{
+		Checkpoint: []string{"Kondisi Kamera", "Tegangan", "Lensa"},
+		Items:      []doc.CPItem{{Name: "Tegangan", Type: _const.CPNumber, Unit: "V", Min: float(210), Max: float(230)}},
+	}
+
+	tests := []struct {
+		name     string
+		previous repo.CheckpointVersion
+		current  repo.CheckpointVersion
+		added    []string
+		removed  []string
+		changed  []string
+	}{
+		{
+			name:    "first version",
+			current: v1,
+			added:   []string{"Kondisi Kamera", "Tegangan", "Lensa"},
+		},
+		{
+			name:     "unchanged",
+			previous: v1,
+			current:  v1,
+		},
+		{
+			name:     "added and removed",
+			previous: v1,
+			current: repo.CheckpointVersion{
+				Checkpoint: []string{"Kondisi Kamera", "Tegangan", "Kabel"},
+				Items:      v1.Items,
+			},
+			added:   []string{"Kabel"},
+			removed: []string{"Lensa"},
+		},
+		{
+			name:     "threshold changed",
+			previous: v1,
+			current: repo.CheckpointVersion{
+				Checkpoint: v1.Checkpoint,
+				Items:      []doc.CPItem{{Name: "Tegangan", Type: _const.CPNumber, Unit: "V", Min: float(200), Max: float(230)}},
+			},
+			changed: []string{"Tegangan"},
+		},
+		{
+			name:     "type changed from undeclared boolean",
+			previous: v1,
+			current: repo.CheckpointVersion{
+				Checkpoint: v1.Checkpoint,
+				Items: append([]doc.CPItem{{Name: "kondisi kamera", Type: _const.CPEnum, Options: []string{"Baik", "Rusak"}}},
+					v1.Items...),
+			},
+			changed: []string{"Kondisi Kamera"},
+		},
+		{
+			name:     "boolean declared explicitly",
+			previous: v1,
+			current: repo.CheckpointVersion{
+				Checkpoint: v1.Checkpoint,
+				Items:      append([]doc.CPItem{{Name: "Lensa", Type: _const.CPBoolean}}, v1.Items...),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed, changed := diffCheckpoint(tt.previous, tt.current)
+			for _, diff := range []struct {
+				name      string
+				got, want []string
+			}{
+				{"added", added, tt.added},
+				{"removed", removed, tt.removed},
+				{"changed", changed, tt.changed},
+			} {
+				if diff.want == nil {
+					diff.want = []string{}
+				}
+				if !reflect.DeepEqual(diff.got, diff.want) {
+					t.Errorf("%s = %q, want %q", diff.name, diff.got, diff.want)
+				}
+			}
+		})
+	}
+}
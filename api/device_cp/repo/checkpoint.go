@@ -5,13 +5,17 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"sipamit-be/internal/pkg/doc"
+	"time"
 )
 
 type Checkpoint struct {
 	ID         bson.ObjectID `json:"_id" bson:"_id"`
 	Device     string        `json:"device" bson:"device"`
 	Checkpoint []string      `json:"checkpoint" bson:"checkpoint"`
+	Items      []doc.CPItem  `json:"items" bson:"items,omitempty"`
+	Version    int           `json:"version" bson:"version"`
 	Updated    *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
+	ModifiedAt time.Time     `json:"modified_at" bson:"modified_at"`
 }
 
 type CheckpointCollRepository struct {
@@ -25,6 +29,11 @@ func NewCheckpointRepository(db *mongo.Database) *CheckpointCollRepository {
 }
 
 func (r *CheckpointCollRepository) InsertMany(checkpoints []Checkpoint) error {
+	stamp := doc.Stamp()
+	for i := range checkpoints {
+		checkpoints[i].ModifiedAt = stamp
+	}
+
 	_, err := r.coll.InsertMany(context.TODO(), checkpoints)
 	if err != nil {
 		return err
@@ -32,6 +41,26 @@ func (r *CheckpointCollRepository) InsertMany(checkpoints []Checkpoint) error {
 	return nil
 }
 
+func (r *CheckpointCollRepository) FindAll() (*[]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	filter := bson.M{}
+
+	cur, err := r.coll.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoints == nil {
+		return &[]Checkpoint{}, nil
+	}
+	return &checkpoints, nil
+}
+
 func (r *CheckpointCollRepository) FindByDevice(device string) (*Checkpoint, error) {
 	var checkpoint Checkpoint
 	filter := bson.M{
@@ -46,6 +75,8 @@ func (r *CheckpointCollRepository) FindByDevice(device string) (*Checkpoint, err
 }
 
 func (r *CheckpointCollRepository) UpdateByDevice(device string, checkpoint *Checkpoint) error {
+	checkpoint.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
 		"device": device,
 	}
@@ -60,6 +91,43 @@ func (r *CheckpointCollRepository) UpdateByDevice(device string, checkpoint *Che
 	return nil
 }
 
+// UpdateTemplate replaces the template of the device and sets the version
+// after the given one, if the template is still at that version. It returns
+// mongo.ErrNoDocuments when another edit came first. Templates predating
+// versioning are at version 0.
+func (r *CheckpointCollRepository) UpdateTemplate(checkpoint *Checkpoint, version int) error {
+	filter := bson.M{
+		"device":  checkpoint.Device,
+		"version": version,
+	}
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	set := bson.M{
+		"checkpoint":  checkpoint.Checkpoint,
+		"version":     version + 1,
+		"updated":     checkpoint.Updated,
+		"modified_at": doc.Stamp(),
+	}
+	update := bson.M{
+		"$set": set,
+	}
+	if len(checkpoint.Items) > 0 {
+		set["items"] = checkpoint.Items
+	} else {
+		update["$unset"] = bson.M{"items": ""}
+	}
+
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (r *CheckpointCollRepository) Count() (int64, error) {
 	filter := bson.M{}
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -68,3 +136,31 @@ func (r *CheckpointCollRepository) Count() (int64, error) {
 	}
 	return count, nil
 }
+
+// FindAllModifiedSince returns the checkpoints written at or after since, or
+// all of them when since is zero.
+func (r *CheckpointCollRepository) FindAllModifiedSince(since time.Time) (*[]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	filter := doc.ModifiedSince(since)
+
+	cur, err := r.coll.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoints == nil {
+		return &[]Checkpoint{}, nil
+	}
+	return &checkpoints, nil
+}
+
+// InitModifiedAt stamps the checkpoints written before modified_at was
+// tracked.
+func (r *CheckpointCollRepository) InitModifiedAt() (int64, error) {
+	return doc.InitModifiedAt(r.coll)
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+)
+
+// CheckpointVersion is an immutable snapshot of a checkpoint template, stored
+// every time the template of a device type changes.
+type CheckpointVersion struct {
+	ID         bson.ObjectID `json:"_id" bson:"_id"`
+	Device     string        `json:"device" bson:"device"`
+	Version    int           `json:"version" bson:"version"`
+	Checkpoint []string      `json:"checkpoint" bson:"checkpoint"`
+	Items      []doc.CPItem  `json:"items" bson:"items,omitempty"`
+	Inserted   doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
+}
+
+type CheckpointVersionCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewCheckpointVersionRepository(db *mongo.Database) *CheckpointVersionCollRepository {
+	return &CheckpointVersionCollRepository{
+		coll: db.Collection("checkpoint_versions"),
+	}
+}
+
+// EnsureIndexes creates the indexes of the checkpoint_versions collection.
+func (r *CheckpointVersionCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Key("device", "version"))
+}
+
+func (r *CheckpointVersionCollRepository) FindAllByDevice(device string) (*[]CheckpointVersion, error) {
+	var versions []CheckpointVersion
+	filter := bson.M{
+		"device": device,
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"version": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &versions)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		return &[]CheckpointVersion{}, nil
+	}
+	return &versions, nil
+}
+
+func (r *CheckpointVersionCollRepository) FindByDeviceVersion(device string, version int) (*CheckpointVersion, error) {
+	var cpVersion CheckpointVersion
+	filter := bson.M{
+		"device":  device,
+		"version": version,
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&cpVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &cpVersion, nil
+}
+
+func (r *CheckpointVersionCollRepository) InsertOne(cpVersion *CheckpointVersion) error {
+	_, err := r.coll.InsertOne(context.TODO(), cpVersion)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteOneByID removes a version whose template write failed, so the history
+// holds no version the template never had.
+func (r *CheckpointVersionCollRepository) DeleteOneByID(id bson.ObjectID) error {
+	_, err := r.coll.DeleteOne(context.TODO(), bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	return nil
+}
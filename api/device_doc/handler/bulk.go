@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sipamit-be/internal/pkg/doc"
+)
+
+// bulkItemError returns the message reported for a failed bulk entry.
+func bulkItemError(err error) interface{} {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Message
+	}
+	return err.Error()
+}
+
+func newBulkResult(results []doc.BulkItemResult, docs interface{}) *doc.BulkResult {
+	res := &doc.BulkResult{
+		Results: results,
+		Docs:    docs,
+	}
+	for _, r := range results {
+		if r.Error != nil {
+			res.Failed++
+		} else {
+			res.Created++
+		}
+	}
+	return res
+}
+
+// rejectBulk responds to an atomic bulk request that had invalid entries,
+// none of which were created.
+func rejectBulk(c echo.Context, results []doc.BulkItemResult) error {
+	res := newBulkResult(results, []interface{}{})
+	for i := range res.Results {
+		res.Results[i].ID = nil
+	}
+	res.Created = 0
+	res.Failed = len(res.Results)
+	return c.JSON(http.StatusBadRequest, res)
+}
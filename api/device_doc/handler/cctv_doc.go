@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type CCTVDocHandler struct {
-	cctvRepo    *repo2.CCTVCollRepository
-	cctvDocRepo *repo.CCTVDocCollRepository
+	cctvRepo     *repo2.CCTVCollRepository
+	cctvDocRepo  *repo.CCTVDocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewCCTVDocAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVDocHandler {
-	h := &CCTVDocHandler{
-		cctvRepo:    repo2.NewCCTVRepository(db),
-		cctvDocRepo: repo.NewCCTVDocRepository(db),
+func newCCTVDocHandler(db *mongo.Database) *CCTVDocHandler {
+	return &CCTVDocHandler{
+		cctvRepo:     repo2.NewCCTVRepository(db),
+		cctvDocRepo:  repo.NewCCTVDocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewCCTVDocAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVDocHandler {
+	h := newCCTVDocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/cctvs", h.findAll)
 	group.GET("/doc/cctv/:id", h.findByID)
+	group.GET("/cctv/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/cctv", h.create)
+	group.POST("/doc/cctv/bulk", h.bulkCreate)
 
 	group.PUT("/doc/cctv/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewCCTVDocAPIHandler(e *echo.Echo, db *mongo.Database) *CCTVDocHandler {
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/cctvs [GET]
 // @Produce json
 // @Success 200
 func (h *CCTVDocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	cctvDocs, err := h.cctvDocRepo.FindAll(cq)
+	cctvDocs, err := h.cctvDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get cctvDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *CCTVDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "CCTV Docs not found")
 	}
 
-	totalCctvDocs, err := h.cctvDocRepo.CountQuery(cq)
+	totalCctvDocs, err := h.cctvDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count cctvDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *CCTVDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "CCTV Docs not found")
 	}
 
-	result := util.MakeResult(cctvDocs, totalCctvDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(cctvDocs, totalCctvDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *CCTVDocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, cctvDoc)
 }
 
+// findAllByDevice
+// @Tags Doc CCTV
+// @Summary Get maintenance history of a cctv
+// @ID get-cctv-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "CCTV ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/cctv/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *CCTVDocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid cctv ID")
+	}
+
+	cctv, err := h.cctvRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "CCTV not found")
+		}
+		log.Errorf("Failed to get cctv: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	cctvDocs, err := h.cctvDocRepo.FindAllByDeviceID(cctv.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get cctvDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalCctvDocs, err := h.cctvDocRepo.CountByDeviceID(cctv.ID)
+	if err != nil {
+		log.Errorf("Failed to count cctvDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(cctvDocs, totalCctvDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc CCTV
 // @Summary Create cctv document
@@ -122,31 +198,111 @@ func (h *CCTVDocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	cctvDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.cctvDocRepo.InsertOne(cctvDoc)
+	if err != nil {
+		log.Errorf("Failed to create cctvDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, cctvDoc.Summary(), cctvDoc.Inserted)
+	return c.JSON(http.StatusOK, cctvDoc)
+}
+
+// bulkCreate
+// @Tags Doc CCTV
+// @Summary Create many cctv documents at once
+// @ID bulk-create-cctv-document
+// @Security ApiKeyAuth
+// @Router /api/doc/cctv/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk CCTV Document Form"
+// @Success 200
+func (h *CCTVDocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	cctvDocs := make([]repo.CCTVDoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		cctvDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &cctvDoc.ID
+		cctvDocs = append(cctvDocs, *cctvDoc)
+	}
+
+	if f.Atomic && len(cctvDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(cctvDocs) > 0 {
+		err = h.cctvDocRepo.InsertMany(cctvDocs)
+		if err != nil {
+			log.Errorf("Failed to create cctvDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range cctvDocs {
+		syncTickets(h.ticketRepo, cctvDocs[i].Summary(), cctvDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, cctvDocs))
+}
+
+// newDoc builds a cctv doc from the form, snapshotting the device.
+func (h *CCTVDocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.CCTVDoc, error) {
 	cctv, err := h.cctvRepo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "CCTV not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "CCTV not found")
 		}
 		log.Errorf("Failed to get cctv: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.CCTV, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	cctvDoc := &repo.CCTVDoc{
-		ID:         bson.NewObjectID(),
-		Nama:       cctv.Nama,
-		Lokasi:     cctv.Lokasi,
-		Kode:       cctv.Kode,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          cctv.ID,
+		Device:            _const.CCTV,
+		Nama:              cctv.Nama,
+		Lokasi:            cctv.Lokasi,
+		Kode:              cctv.Kode,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.cctvDocRepo.InsertOne(cctvDoc)
-	if err != nil {
-		log.Errorf("Failed to create cctvDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		cctvDoc.Status = _const.DocSubmitted
+		cctvDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, cctvDoc)
+	return cctvDoc, nil
 }
 
 // update
@@ -174,6 +330,11 @@ func (h *CCTVDocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.CCTV, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	cctvDoc, err := h.cctvDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -183,13 +344,33 @@ func (h *CCTVDocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if cctvDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved CCTV Doc is read-only")
+	}
+	if cctvDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted CCTV Doc is under review, reject it to edit")
+	}
+
+	previous := cctvDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	cctvDoc.Checkpoint = f.Checkpoint
-	cctvDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.cctvDocRepo.UpdateOneByID(oId, cctvDoc)
+	cctvDoc.CheckpointVersion = cp.Version
+	cctvDoc.Updated = updated
+	err = h.cctvDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, cctvDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "CCTV Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update cctvDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save cctvDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, cctvDoc.Summary(), *cctvDoc.Updated)
 	return c.JSON(http.StatusOK, cctvDoc)
 }
 
@@ -203,6 +384,8 @@ func (h *CCTVDocHandler) update(c echo.Context) error {
 // @Param id path string true "CCTV Document ID"
 // @Success 200
 func (h *CCTVDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -211,13 +394,86 @@ func (h *CCTVDocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.cctvDocRepo.DeleteOneByID(oId)
+	cctvDoc, err := h.cctvDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "CCTV Doc not found")
 		}
+		log.Errorf("Failed to get cctvDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if cctvDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved CCTV Doc is read-only")
+	}
+	if cctvDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted CCTV Doc is under review, reject it to delete")
+	}
+
+	err = h.cctvDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "CCTV Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete cctvDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "CCTV Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *CCTVDocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	cctvDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	cctvDoc.ID = id
+	err = h.cctvDocRepo.InsertOne(cctvDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, cctvDoc.Summary(), cctvDoc.Inserted)
+	return cctvDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *CCTVDocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.CCTV, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	cctvDoc, err := h.cctvDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cctvDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved CCTV Doc is read-only")
+	}
+	if cctvDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted CCTV Doc is under review, reject it to edit")
+	}
+
+	previous := cctvDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	cctvDoc.Checkpoint = checkpoint
+	cctvDoc.CheckpointVersion = cp.Version
+	cctvDoc.Updated = updated
+	err = h.cctvDocRepo.UpdateOneIfUnmodified(id, base, cctvDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save cctvDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, cctvDoc.Summary(), *updated)
+	return cctvDoc.Summary(), nil
+}
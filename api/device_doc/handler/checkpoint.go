@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo3 "sipamit-be/api/device_cp/repo"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+)
+
+// validateCheckpoint loads the checkpoint template of the device type and
+// validates the submitted checkpoint results against it. The template is
+// returned so the doc can record the version it was filled against.
+func validateCheckpoint(cpRepo *repo3.CheckpointCollRepository, device string, checkpoint []doc.CPDetail) (*repo3.Checkpoint, error) {
+	cp, err := cpRepo.FindByDevice(device)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Checkpoint not found")
+		}
+		log.Errorf("Failed to get %s checkpoint: %v", device, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	err = doc.ValidateCheckpoint(cp.Checkpoint, cp.Items, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
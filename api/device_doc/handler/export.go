@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/sheet"
+	"strings"
+	"time"
+)
+
+type DocExportHandler struct {
+	docRepo *repo.DocRepository
+}
+
+func NewDocExportAPIHandler(e *echo.Echo, db *mongo.Database) *DocExportHandler {
+	h := &DocExportHandler{
+		docRepo: repo.NewDocRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	for _, device := range _const.Devices {
+		group.GET("/doc/"+_const.DeviceParam(device)+"/export", func(c echo.Context) error {
+			return h.export(c, device)
+		})
+	}
+
+	return h
+}
+
+func byAtTime(u *doc.ByAt) string {
+	if u == nil {
+		return ""
+	}
+	return sheet.Time(u.At)
+}
+
+// export
+// @Tags Export
+// @Summary Export the documents of a device type matching the list filters as CSV or XLSX
+// @ID export-documents
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param format query string false "File format" enums(csv, xlsx) default(csv)
+// @Param q query string false "Search by nama"
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
+// @Router /api/doc/{type}/export [GET]
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200
+func (h *DocExportHandler) export(c echo.Context, device string) error {
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
+
+	header := append([]string{"ID"}, repo.FieldLabels(device)...)
+	header = append(header,
+		"Status", "Checkpoint OK", "Failed Checkpoints",
+		"Created At", "Created By", "Submitted At", "Submitted By",
+		"Reviewed At", "Reviewed By", "Reject Reason", "Updated At", "Updated By",
+	)
+
+	names := doc.UserNames{}
+	filename := fmt.Sprintf("%s_docs_%s", device, time.Now().Format("2006-01-02"))
+	return sheet.Export(c, filename, header, func(write func([]string) error) error {
+		return h.docRepo.Each(device, dq, func(d *repo.DeviceDoc) error {
+			row := []string{d.ID.Hex()}
+			for _, f := range d.Fields {
+				row = append(row, f.Value)
+			}
+
+			var ok int
+			var failed []string
+			for _, cp := range d.Checkpoint {
+				if cp.OK {
+					ok++
+				} else {
+					failed = append(failed, cp.Name)
+				}
+			}
+
+			row = append(row,
+				d.Status,
+				fmt.Sprintf("%d/%d", ok, len(d.Checkpoint)),
+				strings.Join(failed, "; "),
+				sheet.Time(d.Inserted.At),
+				names.FullName(&d.Inserted),
+				byAtTime(d.Submitted),
+				names.FullName(d.Submitted),
+				byAtTime(d.Reviewed),
+				names.FullName(d.Reviewed),
+				d.RejectReason,
+				byAtTime(d.Updated),
+				names.FullName(d.Updated),
+			)
+			return write(row)
+		})
+	})
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type FingerprintDocHandler struct {
-	fpRepo    *repo2.FingerPrintCollRepository
-	fpDocRepo *repo.FingerprintDocCollRepository
+	fpRepo       *repo2.FingerPrintCollRepository
+	fpDocRepo    *repo.FingerprintDocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewFingerprintDocAPIHandler(e *echo.Echo, db *mongo.Database) *FingerprintDocHandler {
-	h := &FingerprintDocHandler{
-		fpRepo:    repo2.NewFingerPrintRepository(db),
-		fpDocRepo: repo.NewFingerprintDocRepository(db),
+func newFingerprintDocHandler(db *mongo.Database) *FingerprintDocHandler {
+	return &FingerprintDocHandler{
+		fpRepo:       repo2.NewFingerPrintRepository(db),
+		fpDocRepo:    repo.NewFingerprintDocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewFingerprintDocAPIHandler(e *echo.Echo, db *mongo.Database) *FingerprintDocHandler {
+	h := newFingerprintDocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/fingerprints", h.findAll)
 	group.GET("/doc/fingerprint/:id", h.findByID)
+	group.GET("/fingerprint/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/fingerprint", h.create)
+	group.POST("/doc/fingerprint/bulk", h.bulkCreate)
 
 	group.PUT("/doc/fingerprint/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewFingerprintDocAPIHandler(e *echo.Echo, db *mongo.Database) *FingerprintD
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/fingerprints [GET]
 // @Produce json
 // @Success 200
 func (h *FingerprintDocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	fpDocs, err := h.fpDocRepo.FindAll(cq)
+	fpDocs, err := h.fpDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get fingerprintDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *FingerprintDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Fingerprint Docs not found")
 	}
 
-	totalFpDocs, err := h.fpDocRepo.CountQuery(cq)
+	totalFpDocs, err := h.fpDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count fingerprintDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *FingerprintDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Fingerprint Docs not found")
 	}
 
-	result := util.MakeResult(fpDocs, totalFpDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(fpDocs, totalFpDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *FingerprintDocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, fpDoc)
 }
 
+// findAllByDevice
+// @Tags Doc Fingerprint
+// @Summary Get maintenance history of a fingerprint
+// @ID get-fingerprint-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "Fingerprint ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/fingerprint/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *FingerprintDocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid fingerprint ID")
+	}
+
+	fp, err := h.fpRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Fingerprint not found")
+		}
+		log.Errorf("Failed to get fp: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	fpDocs, err := h.fpDocRepo.FindAllByDeviceID(fp.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get fpDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalFpDocs, err := h.fpDocRepo.CountByDeviceID(fp.ID)
+	if err != nil {
+		log.Errorf("Failed to count fpDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(fpDocs, totalFpDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc Fingerprint
 // @Summary Create fingerprint document
@@ -122,31 +198,111 @@ func (h *FingerprintDocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	fpDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.fpDocRepo.InsertOne(fpDoc)
+	if err != nil {
+		log.Errorf("Failed to create fingerprintDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, fpDoc.Summary(), fpDoc.Inserted)
+	return c.JSON(http.StatusOK, "Fingerprint Doc created")
+}
+
+// bulkCreate
+// @Tags Doc Fingerprint
+// @Summary Create many fingerprint documents at once
+// @ID bulk-create-fingerprint-document
+// @Security ApiKeyAuth
+// @Router /api/doc/fingerprint/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk Fingerprint Document Form"
+// @Success 200
+func (h *FingerprintDocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	fpDocs := make([]repo.FingerprintDoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		fpDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &fpDoc.ID
+		fpDocs = append(fpDocs, *fpDoc)
+	}
+
+	if f.Atomic && len(fpDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(fpDocs) > 0 {
+		err = h.fpDocRepo.InsertMany(fpDocs)
+		if err != nil {
+			log.Errorf("Failed to create fpDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range fpDocs {
+		syncTickets(h.ticketRepo, fpDocs[i].Summary(), fpDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, fpDocs))
+}
+
+// newDoc builds a fingerprint doc from the form, snapshotting the device.
+func (h *FingerprintDocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.FingerprintDoc, error) {
 	fp, err := h.fpRepo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "Fingerprint not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Fingerprint not found")
 		}
 		log.Errorf("Failed to get fingerprint: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.Fingerprint, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	fpDoc := &repo.FingerprintDoc{
-		ID:         bson.NewObjectID(),
-		Nama:       fp.Nama,
-		Lokasi:     fp.Lokasi,
-		Kode:       fp.Kode,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          fp.ID,
+		Device:            _const.Fingerprint,
+		Nama:              fp.Nama,
+		Lokasi:            fp.Lokasi,
+		Kode:              fp.Kode,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.fpDocRepo.InsertOne(fpDoc)
-	if err != nil {
-		log.Errorf("Failed to create fingerprintDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		fpDoc.Status = _const.DocSubmitted
+		fpDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, "Fingerprint Doc created")
+	return fpDoc, nil
 }
 
 // update
@@ -174,6 +330,11 @@ func (h *FingerprintDocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.Fingerprint, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	fpDoc, err := h.fpDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -183,13 +344,33 @@ func (h *FingerprintDocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if fpDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Fingerprint Doc is read-only")
+	}
+	if fpDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Fingerprint Doc is under review, reject it to edit")
+	}
+
+	previous := fpDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	fpDoc.Checkpoint = f.Checkpoint
-	fpDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.fpDocRepo.UpdateOneByID(oId, fpDoc)
+	fpDoc.CheckpointVersion = cp.Version
+	fpDoc.Updated = updated
+	err = h.fpDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, fpDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Fingerprint Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update fpDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save fpDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, fpDoc.Summary(), *fpDoc.Updated)
 	return c.JSON(http.StatusOK, fpDoc)
 }
 
@@ -203,6 +384,8 @@ func (h *FingerprintDocHandler) update(c echo.Context) error {
 // @Param id path string true "Fingerprint Document ID"
 // @Success 200
 func (h *FingerprintDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -211,13 +394,86 @@ func (h *FingerprintDocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.fpDocRepo.DeleteOneByID(oId)
+	fpDoc, err := h.fpDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "Fingerprint Doc not found")
 		}
+		log.Errorf("Failed to get fpDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if fpDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Fingerprint Doc is read-only")
+	}
+	if fpDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Fingerprint Doc is under review, reject it to delete")
+	}
+
+	err = h.fpDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Fingerprint Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete fingerprintDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Fingerprint Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *FingerprintDocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	fpDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	fpDoc.ID = id
+	err = h.fpDocRepo.InsertOne(fpDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, fpDoc.Summary(), fpDoc.Inserted)
+	return fpDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *FingerprintDocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.Fingerprint, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	fpDoc, err := h.fpDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if fpDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved Fingerprint Doc is read-only")
+	}
+	if fpDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted Fingerprint Doc is under review, reject it to edit")
+	}
+
+	previous := fpDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	fpDoc.Checkpoint = checkpoint
+	fpDoc.CheckpointVersion = cp.Version
+	fpDoc.Updated = updated
+	err = h.fpDocRepo.UpdateOneIfUnmodified(id, base, fpDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save fpDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, fpDoc.Summary(), *updated)
+	return fpDoc.Summary(), nil
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type KomputerPH1DocHandler struct {
-	kph1Repo    *repo2.KomputerPH1CollRepository
-	kph1DocRepo *repo.KomputerPH1DocCollRepository
+	kph1Repo     *repo2.KomputerPH1CollRepository
+	kph1DocRepo  *repo.KomputerPH1DocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewKomputerPH1DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1DocHandler {
-	h := &KomputerPH1DocHandler{
-		kph1Repo:    repo2.NewKomputerPH1Repository(db),
-		kph1DocRepo: repo.NewKomputerPH1DocRepository(db),
+func newKomputerPH1DocHandler(db *mongo.Database) *KomputerPH1DocHandler {
+	return &KomputerPH1DocHandler{
+		kph1Repo:     repo2.NewKomputerPH1Repository(db),
+		kph1DocRepo:  repo.NewKomputerPH1DocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewKomputerPH1DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1DocHandler {
+	h := newKomputerPH1DocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/komputer-ph1s", h.findAll)
 	group.GET("/doc/komputer-ph1/:id", h.findByID)
+	group.GET("/komputer-ph1/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/komputer-ph1", h.create)
+	group.POST("/doc/komputer-ph1/bulk", h.bulkCreate)
 
 	group.PUT("/doc/komputer-ph1/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewKomputerPH1DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH1D
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/komputer-ph1s [GET]
 // @Produce json
 // @Success 200
 func (h *KomputerPH1DocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	kph1Doc, err := h.kph1DocRepo.FindAll(cq)
+	kph1Doc, err := h.kph1DocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get kph1Doc: %v", err)
@@ -63,7 +91,7 @@ func (h *KomputerPH1DocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 Docs not found")
 	}
 
-	totalKph1Docs, err := h.kph1DocRepo.CountQuery(cq)
+	totalKph1Docs, err := h.kph1DocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count kph1Doc: %v", err)
@@ -72,7 +100,7 @@ func (h *KomputerPH1DocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 Docs not found")
 	}
 
-	result := util.MakeResult(kph1Doc, totalKph1Docs, cq.Page, cq.Limit)
+	result := util.MakeResult(kph1Doc, totalKph1Docs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *KomputerPH1DocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, kph1Doc)
 }
 
+// findAllByDevice
+// @Tags Doc Komputer PH1
+// @Summary Get maintenance history of a komputer ph1
+// @ID get-komputer-ph1-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "Komputer PH1 ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/komputer-ph1/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *KomputerPH1DocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid komputer ph1 ID")
+	}
+
+	kph1, err := h.kph1Repo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 not found")
+		}
+		log.Errorf("Failed to get kph1: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	kph1Docs, err := h.kph1DocRepo.FindAllByDeviceID(kph1.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get kph1Docs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalKph1Docs, err := h.kph1DocRepo.CountByDeviceID(kph1.ID)
+	if err != nil {
+		log.Errorf("Failed to count kph1Docs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(kph1Docs, totalKph1Docs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc Komputer PH1
 // @Summary Create new komputer ph1 document
@@ -122,36 +198,116 @@ func (h *KomputerPH1DocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	kph1Doc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.kph1DocRepo.InsertOne(kph1Doc)
+	if err != nil {
+		log.Errorf("Failed to create kph1Doc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, kph1Doc.Summary(), kph1Doc.Inserted)
+	return c.JSON(http.StatusOK, kph1Doc)
+}
+
+// bulkCreate
+// @Tags Doc Komputer PH1
+// @Summary Create many komputer ph1 documents at once
+// @ID bulk-create-komputer-ph1-document
+// @Security ApiKeyAuth
+// @Router /api/doc/komputer-ph1/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk Komputer PH1 Document Form"
+// @Success 200
+func (h *KomputerPH1DocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	kph1Docs := make([]repo.KomputerPH1Doc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		kph1Doc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &kph1Doc.ID
+		kph1Docs = append(kph1Docs, *kph1Doc)
+	}
+
+	if f.Atomic && len(kph1Docs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(kph1Docs) > 0 {
+		err = h.kph1DocRepo.InsertMany(kph1Docs)
+		if err != nil {
+			log.Errorf("Failed to create kph1Docs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range kph1Docs {
+		syncTickets(h.ticketRepo, kph1Docs[i].Summary(), kph1Docs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, kph1Docs))
+}
+
+// newDoc builds a komputer ph1 doc from the form, snapshotting the device.
+func (h *KomputerPH1DocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.KomputerPH1Doc, error) {
 	kph1, err := h.kph1Repo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 not found")
 		}
 		log.Errorf("Failed to get kph1: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH1, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	kph1Doc := &repo.KomputerPH1Doc{
-		ID:         bson.NewObjectID(),
-		Nama:       kph1.Nama,
-		Merk:       kph1.Merk,
-		PC:         kph1.PC,
-		Monitor:    kph1.Monitor,
-		CPU:        kph1.CPU,
-		RAM:        kph1.RAM,
-		Internal:   kph1.Internal,
-		Lokasi:     kph1.Lokasi,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          kph1.ID,
+		Device:            _const.KomputerPH1,
+		Nama:              kph1.Nama,
+		Merk:              kph1.Merk,
+		PC:                kph1.PC,
+		Monitor:           kph1.Monitor,
+		CPU:               kph1.CPU,
+		RAM:               kph1.RAM,
+		Internal:          kph1.Internal,
+		Lokasi:            kph1.Lokasi,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.kph1DocRepo.InsertOne(kph1Doc)
-	if err != nil {
-		log.Errorf("Failed to create kph1Doc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		kph1Doc.Status = _const.DocSubmitted
+		kph1Doc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, kph1Doc)
+	return kph1Doc, nil
 }
 
 // update
@@ -179,6 +335,11 @@ func (h *KomputerPH1DocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH1, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	kph1Doc, err := h.kph1DocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -188,13 +349,33 @@ func (h *KomputerPH1DocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if kph1Doc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH1 Doc is read-only")
+	}
+	if kph1Doc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH1 Doc is under review, reject it to edit")
+	}
+
+	previous := kph1Doc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	kph1Doc.Checkpoint = f.Checkpoint
-	kph1Doc.Updated = nc.Claims.ByAtPtr()
-	err = h.kph1DocRepo.UpdateOneByID(oId, kph1Doc)
+	kph1Doc.CheckpointVersion = cp.Version
+	kph1Doc.Updated = updated
+	err = h.kph1DocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, kph1Doc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer PH1 Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update kph1Doc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save kph1Doc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, kph1Doc.Summary(), *kph1Doc.Updated)
 	return c.JSON(http.StatusOK, kph1Doc)
 }
 
@@ -208,6 +389,8 @@ func (h *KomputerPH1DocHandler) update(c echo.Context) error {
 // @Param id path string true "Komputer PH1 Document ID"
 // @Success 200
 func (h *KomputerPH1DocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -216,13 +399,86 @@ func (h *KomputerPH1DocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.kph1DocRepo.DeleteOneByID(oId)
+	kph1Doc, err := h.kph1DocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH1 Doc not found")
 		}
+		log.Errorf("Failed to get kph1Doc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if kph1Doc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH1 Doc is read-only")
+	}
+	if kph1Doc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH1 Doc is under review, reject it to delete")
+	}
+
+	err = h.kph1DocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer PH1 Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete kph1Doc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Komputer PH1 Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *KomputerPH1DocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	kph1Doc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	kph1Doc.ID = id
+	err = h.kph1DocRepo.InsertOne(kph1Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, kph1Doc.Summary(), kph1Doc.Inserted)
+	return kph1Doc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *KomputerPH1DocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH1, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	kph1Doc, err := h.kph1DocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if kph1Doc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH1 Doc is read-only")
+	}
+	if kph1Doc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH1 Doc is under review, reject it to edit")
+	}
+
+	previous := kph1Doc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	kph1Doc.Checkpoint = checkpoint
+	kph1Doc.CheckpointVersion = cp.Version
+	kph1Doc.Updated = updated
+	err = h.kph1DocRepo.UpdateOneIfUnmodified(id, base, kph1Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save kph1Doc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, kph1Doc.Summary(), *updated)
+	return kph1Doc.Summary(), nil
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type KomputerPH2DocHandler struct {
-	kph2Repo    *repo2.KomputerPH2CollRepository
-	kph2DocRepo *repo.KomputerPH2DocCollRepository
+	kph2Repo     *repo2.KomputerPH2CollRepository
+	kph2DocRepo  *repo.KomputerPH2DocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewKomputerPH2DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH2DocHandler {
-	h := &KomputerPH2DocHandler{
-		kph2Repo:    repo2.NewKomputerPH2Repository(db),
-		kph2DocRepo: repo.NewKomputerPH2DocRepository(db),
+func newKomputerPH2DocHandler(db *mongo.Database) *KomputerPH2DocHandler {
+	return &KomputerPH2DocHandler{
+		kph2Repo:     repo2.NewKomputerPH2Repository(db),
+		kph2DocRepo:  repo.NewKomputerPH2DocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewKomputerPH2DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH2DocHandler {
+	h := newKomputerPH2DocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/komputer-ph2s", h.findAll)
 	group.GET("/doc/komputer-ph2/:id", h.findByID)
+	group.GET("/komputer-ph2/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/komputer-ph2", h.create)
+	group.POST("/doc/komputer-ph2/bulk", h.bulkCreate)
 
 	group.PUT("/doc/komputer-ph2/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewKomputerPH2DocAPIHandler(e *echo.Echo, db *mongo.Database) *KomputerPH2D
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/komputer-ph2s [GET]
 // @Produce json
 // @Success 200
 func (h *KomputerPH2DocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	kph2Doc, err := h.kph2DocRepo.FindAll(cq)
+	kph2Doc, err := h.kph2DocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get kph2Doc: %v", err)
@@ -63,7 +91,7 @@ func (h *KomputerPH2DocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 Docs not found")
 	}
 
-	totalKph2Docs, err := h.kph2DocRepo.CountQuery(cq)
+	totalKph2Docs, err := h.kph2DocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count kph2Doc: %v", err)
@@ -72,7 +100,7 @@ func (h *KomputerPH2DocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 Docs not found")
 	}
 
-	result := util.MakeResult(kph2Doc, totalKph2Docs, cq.Page, cq.Limit)
+	result := util.MakeResult(kph2Doc, totalKph2Docs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *KomputerPH2DocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, kph2Doc)
 }
 
+// findAllByDevice
+// @Tags Doc Komputer PH2
+// @Summary Get maintenance history of a komputer ph2
+// @ID get-komputer-ph2-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "Komputer PH2 ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/komputer-ph2/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *KomputerPH2DocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid komputer ph2 ID")
+	}
+
+	kph2, err := h.kph2Repo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 not found")
+		}
+		log.Errorf("Failed to get kph2: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	kph2Docs, err := h.kph2DocRepo.FindAllByDeviceID(kph2.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get kph2Docs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalKph2Docs, err := h.kph2DocRepo.CountByDeviceID(kph2.ID)
+	if err != nil {
+		log.Errorf("Failed to count kph2Docs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(kph2Docs, totalKph2Docs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc Komputer PH2
 // @Summary Create new komputer ph2 document
@@ -122,36 +198,116 @@ func (h *KomputerPH2DocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	kph2Doc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.kph2DocRepo.InsertOne(kph2Doc)
+	if err != nil {
+		log.Errorf("Failed to create kph2Doc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, kph2Doc.Summary(), kph2Doc.Inserted)
+	return c.JSON(http.StatusOK, kph2Doc)
+}
+
+// bulkCreate
+// @Tags Doc Komputer PH2
+// @Summary Create many komputer ph2 documents at once
+// @ID bulk-create-komputer-ph2-document
+// @Security ApiKeyAuth
+// @Router /api/doc/komputer-ph2/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk Komputer PH2 Document Form"
+// @Success 200
+func (h *KomputerPH2DocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	kph2Docs := make([]repo.KomputerPH2Doc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		kph2Doc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &kph2Doc.ID
+		kph2Docs = append(kph2Docs, *kph2Doc)
+	}
+
+	if f.Atomic && len(kph2Docs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(kph2Docs) > 0 {
+		err = h.kph2DocRepo.InsertMany(kph2Docs)
+		if err != nil {
+			log.Errorf("Failed to create kph2Docs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range kph2Docs {
+		syncTickets(h.ticketRepo, kph2Docs[i].Summary(), kph2Docs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, kph2Docs))
+}
+
+// newDoc builds a komputer ph2 doc from the form, snapshotting the device.
+func (h *KomputerPH2DocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.KomputerPH2Doc, error) {
 	kph2, err := h.kph2Repo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 not found")
 		}
 		log.Errorf("Failed to get kph2: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH2, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	kph2Doc := &repo.KomputerPH2Doc{
-		ID:         bson.NewObjectID(),
-		Nama:       kph2.Nama,
-		Merk:       kph2.Merk,
-		PC:         kph2.PC,
-		Monitor:    kph2.Monitor,
-		CPU:        kph2.CPU,
-		RAM:        kph2.RAM,
-		Internal:   kph2.Internal,
-		Lokasi:     kph2.Lokasi,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          kph2.ID,
+		Device:            _const.KomputerPH2,
+		Nama:              kph2.Nama,
+		Merk:              kph2.Merk,
+		PC:                kph2.PC,
+		Monitor:           kph2.Monitor,
+		CPU:               kph2.CPU,
+		RAM:               kph2.RAM,
+		Internal:          kph2.Internal,
+		Lokasi:            kph2.Lokasi,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.kph2DocRepo.InsertOne(kph2Doc)
-	if err != nil {
-		log.Errorf("Failed to create kph2Doc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		kph2Doc.Status = _const.DocSubmitted
+		kph2Doc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, kph2Doc)
+	return kph2Doc, nil
 }
 
 // update
@@ -179,6 +335,11 @@ func (h *KomputerPH2DocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH2, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	kph2Doc, err := h.kph2DocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -188,13 +349,33 @@ func (h *KomputerPH2DocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if kph2Doc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH2 Doc is read-only")
+	}
+	if kph2Doc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH2 Doc is under review, reject it to edit")
+	}
+
+	previous := kph2Doc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	kph2Doc.Checkpoint = f.Checkpoint
-	kph2Doc.Updated = nc.Claims.ByAtPtr()
-	err = h.kph2DocRepo.UpdateOneByID(oId, kph2Doc)
+	kph2Doc.CheckpointVersion = cp.Version
+	kph2Doc.Updated = updated
+	err = h.kph2DocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, kph2Doc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer PH2 Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update kph2Doc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save kph2Doc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, kph2Doc.Summary(), *kph2Doc.Updated)
 	return c.JSON(http.StatusOK, kph2Doc)
 }
 
@@ -208,6 +389,8 @@ func (h *KomputerPH2DocHandler) update(c echo.Context) error {
 // @Param id path string true "Komputer PH2 Document ID"
 // @Success 200
 func (h *KomputerPH2DocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -216,13 +399,86 @@ func (h *KomputerPH2DocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.kph2DocRepo.DeleteOneByID(oId)
+	kph2Doc, err := h.kph2DocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "Komputer PH2 Doc not found")
 		}
+		log.Errorf("Failed to get kph2Doc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if kph2Doc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH2 Doc is read-only")
+	}
+	if kph2Doc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH2 Doc is under review, reject it to delete")
+	}
+
+	err = h.kph2DocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Komputer PH2 Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete kph2Doc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Komputer PH2 Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *KomputerPH2DocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	kph2Doc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	kph2Doc.ID = id
+	err = h.kph2DocRepo.InsertOne(kph2Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, kph2Doc.Summary(), kph2Doc.Inserted)
+	return kph2Doc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *KomputerPH2DocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.KomputerPH2, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	kph2Doc, err := h.kph2DocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if kph2Doc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved Komputer PH2 Doc is read-only")
+	}
+	if kph2Doc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted Komputer PH2 Doc is under review, reject it to edit")
+	}
+
+	previous := kph2Doc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	kph2Doc.Checkpoint = checkpoint
+	kph2Doc.CheckpointVersion = cp.Version
+	kph2Doc.Updated = updated
+	err = h.kph2DocRepo.UpdateOneIfUnmodified(id, base, kph2Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save kph2Doc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, kph2Doc.Summary(), *updated)
+	return kph2Doc.Summary(), nil
+}
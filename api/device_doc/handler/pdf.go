@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"sipamit-be/api/app/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/config"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/pdf"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pdfMargin    = 50.0
+	pdfFontSize  = 10.0
+	pdfLineSize  = 14.0
+	pdfCellInset = 4.0
+)
+
+var pdfMonths = []string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+func pdfDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), pdfMonths[t.Month()-1], t.Year())
+}
+
+type DocPDFHandler struct {
+	docRepo  *repo3.DocRepository
+	userRepo *repo.UserCollRepository
+}
+
+func NewDocPDFAPIHandler(e *echo.Echo, db *mongo.Database) *DocPDFHandler {
+	h := &DocPDFHandler{
+		docRepo:  repo3.NewDocRepository(db),
+		userRepo: repo.NewUserRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	for _, device := range _const.Devices {
+		group.GET("/doc/"+_const.DeviceParam(device)+"/pdf", func(c echo.Context) error {
+			return h.monthPDF(c, device)
+		})
+		group.GET("/doc/"+_const.DeviceParam(device)+"/:id/pdf", func(c echo.Context) error {
+			return h.docPDF(c, device)
+		})
+	}
+
+	return h
+}
+
+// docPDF
+// @Tags Doc PDF
+// @Summary Get berita acara PDF of a document
+// @ID get-document-pdf
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id}/pdf [GET]
+// @Produce application/pdf
+// @Success 200
+func (h *DocPDFHandler) docPDF(c echo.Context, device string) error {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid document ID")
+	}
+
+	d, err := h.docRepo.FindOneByID(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Document not found")
+		}
+		log.Errorf("Failed to get %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	doc := pdf.New()
+	h.renderDoc(doc, d, map[bson.ObjectID]string{})
+
+	filename := fmt.Sprintf("berita_acara_%s_%s.pdf", device, d.ID.Hex())
+	return writePDF(c, doc, filename)
+}
+
+// monthPDF
+// @Tags Doc PDF
+// @Summary Get berita acara PDF of all documents of a device type in a month
+// @Description Only submitted, approved and legacy documents are printed, drafts and rejected documents are left out.
+// @ID get-month-document-pdf
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param month query string false "Month (YYYY-MM), default this month"
+// @Router /api/doc/{type}/pdf [GET]
+// @Produce application/pdf
+// @Success 200
+func (h *DocPDFHandler) monthPDF(c echo.Context, device string) error {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if s := strings.TrimSpace(c.QueryParam("month")); s != "" {
+		t, err := time.ParseInLocation("2006-01", s, now.Location())
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid month")
+		}
+		from = t
+	}
+	to := from.AddDate(0, 1, 0)
+
+	docs, err := h.docRepo.FindAllByPeriod(device, from, to)
+	if err != nil {
+		log.Errorf("Failed to get %s docs: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if len(docs) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Documents not found")
+	}
+
+	doc := pdf.New()
+	names := make(map[bson.ObjectID]string)
+	for i := range docs {
+		h.renderDoc(doc, &docs[i], names)
+	}
+
+	filename := fmt.Sprintf("berita_acara_%s_%s.pdf", device, from.Format("2006-01"))
+	return writePDF(c, doc, filename)
+}
+
+func writePDF(c echo.Context, doc *pdf.Document, filename string) error {
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		log.Errorf("Failed to write pdf: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Blob(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// technician returns the full name of the user who created the doc, cached in
+// names so a batch only looks up each technician once.
+func (h *DocPDFHandler) technician(d *repo3.DeviceDoc, names map[bson.ObjectID]string) string {
+	if d.Inserted.ID == nil {
+		return ""
+	}
+	if name, ok := names[*d.Inserted.ID]; ok {
+		return name
+	}
+
+	var name string
+	user, err := h.userRepo.FindByID(*d.Inserted.ID)
+	if err == nil {
+		name = user.FullName
+	}
+	names[*d.Inserted.ID] = name
+	return name
+}
+
+// renderDoc writes the berita acara of d starting on a new page.
+func (h *DocPDFHandler) renderDoc(doc *pdf.Document, d *repo3.DeviceDoc, names map[bson.ObjectID]string) {
+	width := pdf.PageWidth - 2*pdfMargin
+	doc.AddPage()
+
+	y := pdfMargin
+	doc.Text(pdfMargin, y, 14, true, config.Company.Name)
+	if config.Company.Address != "" {
+		y += 14
+		doc.Text(pdfMargin, y, 9, false, config.Company.Address)
+	}
+	y += 8
+	doc.Line(pdfMargin, y, pdfMargin+width, y)
+
+	y += 26
+	doc.TextCenter(pdfMargin, y, width, 13, true, "BERITA ACARA PEMELIHARAAN "+strings.ToUpper(_const.DeviceLabels[d.Device]))
+	y += 14
+	doc.TextCenter(pdfMargin, y, width, 9, false, "No. "+d.ID.Hex())
+
+	y += 26
+	fields := append(append([]repo3.DocField{}, d.Fields...), repo3.DocField{Label: "Tanggal", Value: pdfDate(d.Inserted.At)})
+	for _, f := range fields {
+		doc.Text(pdfMargin, y, pdfFontSize, false, f.Label)
+		doc.Text(pdfMargin+90, y, pdfFontSize, false, ": "+f.Value)
+		y += pdfLineSize
+	}
+
+	// checkpoint table: No | Checkpoint | Hasil | OK | Keterangan
+	cols := []float64{30, 170, 80, 40, width - 320}
+	header := []string{"No", "Checkpoint", "Hasil", "OK", "Keterangan"}
+
+	y += 10
+	y = tableRow(doc, y, cols, header, true)
+	for i, cp := range d.Checkpoint {
+		ok := "Tidak"
+		if cp.OK {
+			ok = "Ya"
+		}
+		row := []string{strconv.Itoa(i + 1), cp.Name, cp.ValueText(), ok, cp.Keterangan}
+
+		if y+rowHeight(cols, row) > pdf.PageHeight-pdfMargin {
+			doc.AddPage()
+			y = tableRow(doc, pdfMargin, cols, header, true)
+		}
+		y = tableRow(doc, y, cols, row, false)
+	}
+
+	// signature boxes
+	boxWidth, boxHeight := 180.0, 90.0
+	if y+30+boxHeight > pdf.PageHeight-pdfMargin {
+		doc.AddPage()
+		y = pdfMargin
+	}
+	y += 30
+	signers := []struct {
+		title string
+		name  string
+		x     float64
+	}{
+		{"Teknisi", h.technician(d, names), pdfMargin},
+		{"Mengetahui", "", pdfMargin + width - boxWidth},
+	}
+	for _, s := range signers {
+		doc.Rect(s.x, y, boxWidth, boxHeight)
+		doc.TextCenter(s.x, y+pdfLineSize, boxWidth, pdfFontSize, true, s.title)
+		doc.Line(s.x+20, y+boxHeight-20, s.x+boxWidth-20, y+boxHeight-20)
+		doc.TextCenter(s.x, y+boxHeight-8, boxWidth, pdfFontSize, false, s.name)
+	}
+}
+
+func rowHeight(cols []float64, cells []string) float64 {
+	lines := 1
+	for i, cell := range cells {
+		n := len(pdf.Wrap(cell, cols[i]-2*pdfCellInset, pdfFontSize, false))
+		if n > lines {
+			lines = n
+		}
+	}
+	return float64(lines)*pdfLineSize + pdfCellInset
+}
+
+// tableRow draws a bordered row at y and returns the y of the next row.
+func tableRow(doc *pdf.Document, y float64, cols []float64, cells []string, bold bool) float64 {
+	height := rowHeight(cols, cells)
+
+	x := pdfMargin
+	for i, cell := range cells {
+		doc.Rect(x, y, cols[i], height)
+		for j, line := range pdf.Wrap(cell, cols[i]-2*pdfCellInset, pdfFontSize, bold) {
+			doc.Text(x+pdfCellInset, y+float64(j+1)*pdfLineSize-2, pdfFontSize, bold, line)
+		}
+		x += cols[i]
+	}
+	return y + height
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type PrinterDocHandler struct {
 	printerRepo    *repo2.PrinterCollRepository
 	printerDocRepo *repo.PrinterDocCollRepository
+	cpRepo         *repo3.CheckpointCollRepository
+	ticketRepo     *repo4.TicketCollRepository
+	revisionRepo   *repo.DocRevisionCollRepository
 }
 
-func NewPrinterDocAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterDocHandler {
-	h := &PrinterDocHandler{
+func newPrinterDocHandler(db *mongo.Database) *PrinterDocHandler {
+	return &PrinterDocHandler{
 		printerRepo:    repo2.NewPrinterRepository(db),
 		printerDocRepo: repo.NewPrinterDocRepository(db),
+		cpRepo:         repo3.NewCheckpointRepository(db),
+		ticketRepo:     repo4.NewTicketRepository(db),
+		revisionRepo:   repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewPrinterDocAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterDocHandler {
+	h := newPrinterDocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/printers", h.findAll)
 	group.GET("/doc/printer/:id", h.findByID)
+	group.GET("/printer/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/printer", h.create)
+	group.POST("/doc/printer/bulk", h.bulkCreate)
 
 	group.PUT("/doc/printer/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewPrinterDocAPIHandler(e *echo.Echo, db *mongo.Database) *PrinterDocHandle
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/printers [GET]
 // @Produce json
 // @Success 200
 func (h *PrinterDocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	printerDocs, err := h.printerDocRepo.FindAll(cq)
+	printerDocs, err := h.printerDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get printerDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *PrinterDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Printer Docs not found")
 	}
 
-	totalPrinterDocs, err := h.printerDocRepo.CountQuery(cq)
+	totalPrinterDocs, err := h.printerDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count printerDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *PrinterDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Printer Docs not found")
 	}
 
-	result := util.MakeResult(printerDocs, totalPrinterDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(printerDocs, totalPrinterDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *PrinterDocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, printerDoc)
 }
 
+// findAllByDevice
+// @Tags Doc Printer
+// @Summary Get maintenance history of a printer
+// @ID get-printer-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "Printer ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/printer/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *PrinterDocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid printer ID")
+	}
+
+	printer, err := h.printerRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Printer not found")
+		}
+		log.Errorf("Failed to get printer: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	printerDocs, err := h.printerDocRepo.FindAllByDeviceID(printer.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get printerDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalPrinterDocs, err := h.printerDocRepo.CountByDeviceID(printer.ID)
+	if err != nil {
+		log.Errorf("Failed to count printerDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(printerDocs, totalPrinterDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc Printer
 // @Summary Create printer document
@@ -122,32 +198,112 @@ func (h *PrinterDocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	printerDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.printerDocRepo.InsertOne(printerDoc)
+	if err != nil {
+		log.Errorf("Failed to create printerDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, printerDoc.Summary(), printerDoc.Inserted)
+	return c.JSON(http.StatusOK, printerDoc)
+}
+
+// bulkCreate
+// @Tags Doc Printer
+// @Summary Create many printer documents at once
+// @ID bulk-create-printer-document
+// @Security ApiKeyAuth
+// @Router /api/doc/printer/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk Printer Document Form"
+// @Success 200
+func (h *PrinterDocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	printerDocs := make([]repo.PrinterDoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		printerDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &printerDoc.ID
+		printerDocs = append(printerDocs, *printerDoc)
+	}
+
+	if f.Atomic && len(printerDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(printerDocs) > 0 {
+		err = h.printerDocRepo.InsertMany(printerDocs)
+		if err != nil {
+			log.Errorf("Failed to create printerDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range printerDocs {
+		syncTickets(h.ticketRepo, printerDocs[i].Summary(), printerDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, printerDocs))
+}
+
+// newDoc builds a printer doc from the form, snapshotting the device.
+func (h *PrinterDocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.PrinterDoc, error) {
 	printer, err := h.printerRepo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "Printer not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Printer not found")
 		}
 		log.Errorf("Failed to get printer: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.Printer, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	printerDoc := &repo.PrinterDoc{
-		ID:          bson.NewObjectID(),
-		Nama:        printer.Nama,
-		Departemen:  printer.Departemen,
-		TipePrinter: printer.TipePrinter,
-		NoSeri:      printer.NoSeri,
-		Checkpoint:  f.Checkpoint,
-		Inserted:    nc.Claims.ByAt(),
-		IsDeleted:   false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          printer.ID,
+		Device:            _const.Printer,
+		Nama:              printer.Nama,
+		Departemen:        printer.Departemen,
+		TipePrinter:       printer.TipePrinter,
+		NoSeri:            printer.NoSeri,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.printerDocRepo.InsertOne(printerDoc)
-	if err != nil {
-		log.Errorf("Failed to create printerDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		printerDoc.Status = _const.DocSubmitted
+		printerDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, printerDoc)
+	return printerDoc, nil
 }
 
 // update
@@ -175,6 +331,11 @@ func (h *PrinterDocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.Printer, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	printerDoc, err := h.printerDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -184,13 +345,33 @@ func (h *PrinterDocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if printerDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Printer Doc is read-only")
+	}
+	if printerDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Printer Doc is under review, reject it to edit")
+	}
+
+	previous := printerDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	printerDoc.Checkpoint = f.Checkpoint
-	printerDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.printerDocRepo.UpdateOneByID(oId, printerDoc)
+	printerDoc.CheckpointVersion = cp.Version
+	printerDoc.Updated = updated
+	err = h.printerDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, printerDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Printer Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update printerDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save printerDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, printerDoc.Summary(), *printerDoc.Updated)
 	return c.JSON(http.StatusOK, printerDoc)
 }
 
@@ -203,6 +384,8 @@ func (h *PrinterDocHandler) update(c echo.Context) error {
 // @Param id path string true "Printer Document ID"
 // @Success 200
 func (h *PrinterDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -211,13 +394,86 @@ func (h *PrinterDocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.printerDocRepo.DeleteOneByID(oId)
+	printerDoc, err := h.printerDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "Printer Doc not found")
 		}
+		log.Errorf("Failed to get printerDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if printerDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Printer Doc is read-only")
+	}
+	if printerDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Printer Doc is under review, reject it to delete")
+	}
+
+	err = h.printerDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Printer Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete printerDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Printer Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *PrinterDocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	printerDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	printerDoc.ID = id
+	err = h.printerDocRepo.InsertOne(printerDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, printerDoc.Summary(), printerDoc.Inserted)
+	return printerDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *PrinterDocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.Printer, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	printerDoc, err := h.printerDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if printerDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved Printer Doc is read-only")
+	}
+	if printerDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted Printer Doc is under review, reject it to edit")
+	}
+
+	previous := printerDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	printerDoc.Checkpoint = checkpoint
+	printerDoc.CheckpointVersion = cp.Version
+	printerDoc.Updated = updated
+	err = h.printerDocRepo.UpdateOneIfUnmodified(id, base, printerDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save printerDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, printerDoc.Summary(), *updated)
+	return printerDoc.Summary(), nil
+}
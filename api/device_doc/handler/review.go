@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo3 "sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+)
+
+type DocReviewHandler struct {
+	docRepo    *repo3.DocRepository
+	ticketRepo *repo4.TicketCollRepository
+}
+
+func NewDocReviewAPIHandler(e *echo.Echo, db *mongo.Database) *DocReviewHandler {
+	h := &DocReviewHandler{
+		docRepo:    repo3.NewDocRepository(db),
+		ticketRepo: repo4.NewTicketRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	for _, device := range _const.Devices {
+		path := "/doc/" + _const.DeviceParam(device) + "/:id"
+
+		group.PUT(path+"/submit", func(c echo.Context) error {
+			return h.submit(c, device)
+		})
+		group.PUT(path+"/approve", func(c echo.Context) error {
+			return h.approve(c, device)
+		}, context.AdminOrSuperAdminOnly)
+		group.PUT(path+"/reject", func(c echo.Context) error {
+			return h.reject(c, device)
+		}, context.AdminOrSuperAdminOnly)
+	}
+
+	return h
+}
+
+// submit
+// @Tags Doc Review
+// @Summary Submit a draft or rejected document for review
+// @ID submit-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id}/submit [PUT]
+// @Produce json
+// @Success 200
+func (h *DocReviewHandler) submit(c echo.Context, device string) error {
+	nc := c.(*context.Context)
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":    _const.DocSubmitted,
+			"submitted": nc.Claims.ByAtPtr(),
+		},
+		"$unset": bson.M{
+			"reviewed":      "",
+			"reject_reason": "",
+		},
+	}
+	return h.transition(c, device, _const.DocSubmitted, update)
+}
+
+// approve
+// @Tags Doc Review
+// @Summary Approve a submitted document
+// @ID approve-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id}/approve [PUT]
+// @Produce json
+// @Success 200
+func (h *DocReviewHandler) approve(c echo.Context, device string) error {
+	nc := c.(*context.Context)
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":   _const.DocApproved,
+			"reviewed": nc.Claims.ByAtPtr(),
+		},
+		"$unset": bson.M{
+			"reject_reason": "",
+		},
+	}
+	return h.transition(c, device, _const.DocApproved, update)
+}
+
+// reject
+// @Tags Doc Review
+// @Summary Reject a submitted document with a reason
+// @Description The tickets the document opened are resolved, and opened again if it is resubmitted still failing.
+// @ID reject-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Param body body doc.RejectDocForm true "Reject Form"
+// @Router /api/doc/{type}/{id}/reject [PUT]
+// @Produce json
+// @Success 200
+func (h *DocReviewHandler) reject(c echo.Context, device string) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewRejectDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":        _const.DocRejected,
+			"reviewed":      nc.Claims.ByAtPtr(),
+			"reject_reason": f.Reason,
+		},
+	}
+	return h.transition(c, device, _const.DocRejected, update)
+}
+
+// transition moves the doc to status after checking the workflow allows it
+// from the doc's current status.
+func (h *DocReviewHandler) transition(c echo.Context, device, status string, update bson.M) error {
+	nc := c.(*context.Context)
+
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid document ID")
+	}
+
+	d, err := h.docRepo.FindOneByID(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Document not found")
+		}
+		log.Errorf("Failed to get %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if !doc.CanTransition(d.Status, status) {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Cannot change %s document to %s", d.Status, status))
+	}
+
+	err = h.docRepo.UpdateStatus(device, oId, d.Status, update)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Document was changed, please reload")
+		}
+		log.Errorf("Failed to update %s doc status: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	d, err = h.docRepo.FindOneByID(device, oId)
+	if err != nil {
+		log.Errorf("Failed to get %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	switch status {
+	case _const.DocSubmitted:
+		syncTickets(h.ticketRepo, d, nc.Claims.ByAt())
+	case _const.DocRejected:
+		_, err = h.ticketRepo.ResolveRejected(oId, nc.Claims.ByAt())
+		if err != nil {
+			log.Errorf("Failed to resolve %s tickets of rejected doc: %v", device, err)
+		}
+	}
+	return c.JSON(http.StatusOK, d)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+)
+
+// docVersion is one version of a doc's checkpoint results with the changes
+// from the version before it.
+type docVersion struct {
+	Revision          int            `json:"revision"`
+	Current           bool           `json:"current"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version"`
+	Author            doc.ByAt       `json:"author"`
+	Replaced          *doc.ByAt      `json:"replaced,omitempty"`
+	Changes           []doc.CPChange `json:"changes"`
+}
+
+type DocRevisionHandler struct {
+	docRepo      *repo.DocRepository
+	revisionRepo *repo.DocRevisionCollRepository
+}
+
+func NewDocRevisionAPIHandler(e *echo.Echo, db *mongo.Database) *DocRevisionHandler {
+	h := &DocRevisionHandler{
+		docRepo:      repo.NewDocRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	for _, device := range _const.Devices {
+		group.GET("/doc/"+_const.DeviceParam(device)+"/:id/revisions", func(c echo.Context) error {
+			return h.revisions(c, device)
+		})
+	}
+
+	return h
+}
+
+// revisions
+// @Tags Doc Revision
+// @Summary Get the revision history of a document with changes per checkpoint item
+// @ID get-document-revisions
+// @Security ApiKeyAuth
+// @Param type path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id}/revisions [GET]
+// @Produce json
+// @Success 200
+func (h *DocRevisionHandler) revisions(c echo.Context, device string) error {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid document ID")
+	}
+
+	d, err := h.docRepo.FindOneByID(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Document not found")
+		}
+		log.Errorf("Failed to get %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	revisions, err := h.revisionRepo.FindAllByDoc(device, oId)
+	if err != nil {
+		log.Errorf("Failed to get %s doc revisions: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	versions := make([]docVersion, 0, len(*revisions)+1)
+	var previous []doc.CPDetail
+	for _, r := range *revisions {
+		replaced := r.Inserted
+		versions = append(versions, docVersion{
+			Revision:          r.Revision,
+			Checkpoint:        r.Checkpoint,
+			CheckpointVersion: r.CheckpointVersion,
+			Author:            r.Author,
+			Replaced:          &replaced,
+			Changes:           doc.DiffCheckpoint(previous, r.Checkpoint),
+		})
+		previous = r.Checkpoint
+	}
+
+	author := d.Inserted
+	if d.Updated != nil {
+		author = *d.Updated
+	}
+	versions = append(versions, docVersion{
+		Revision:          len(*revisions) + 1,
+		Current:           true,
+		Checkpoint:        d.Checkpoint,
+		CheckpointVersion: d.CheckpointVersion,
+		Author:            author,
+		Changes:           doc.DiffCheckpoint(previous, d.Checkpoint),
+	})
+	return c.JSON(http.StatusOK, versions)
+}
+
+// saveRevisionAttempts bounds the retries of saveRevision when another update
+// of the doc takes the revision number first.
+const saveRevisionAttempts = 3
+
+// saveRevision stores the checkpoint results d had as a revision, once the
+// doc was updated by replaced.
+func saveRevision(revisionRepo *repo.DocRevisionCollRepository, d *repo.DeviceDoc, replaced doc.ByAt) error {
+	author := d.Inserted
+	if d.Updated != nil {
+		author = *d.Updated
+	}
+
+	var err error
+	for range saveRevisionAttempts {
+		var count int64
+		count, err = revisionRepo.CountByDoc(d.Device, d.ID)
+		if err != nil {
+			return err
+		}
+
+		err = revisionRepo.InsertOne(&repo.DocRevision{
+			ID:                bson.NewObjectID(),
+			Device:            d.Device,
+			DocID:             d.ID,
+			Revision:          int(count) + 1,
+			Checkpoint:        d.Checkpoint,
+			CheckpointVersion: d.CheckpointVersion,
+			Author:            author,
+			Inserted:          replaced,
+		})
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return err
+}
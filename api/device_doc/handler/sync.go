@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
+	"sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+	"strings"
+	"time"
+)
+
+const (
+	syncCreated   = "created"
+	syncUpdated   = "updated"
+	syncUnchanged = "unchanged"
+	syncConflict  = "conflict"
+	syncFailed    = "failed"
+)
+
+// syncDocDays is how far back the first pull of a client returns docs.
+const syncDocDays = 31
+
+// docSyncer writes the docs of one device type pushed by offline clients.
+type docSyncer interface {
+	syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error)
+	syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error)
+}
+
+type syncDeleted struct {
+	Devices map[string][]bson.ObjectID `json:"devices"`
+	Docs    map[string][]bson.ObjectID `json:"docs"`
+}
+
+// syncPullResult holds the records written since the pull cursor, keyed by
+// device type. Full is set on the first pull, which has no deletes.
+type syncPullResult struct {
+	Cursor      string                 `json:"cursor"`
+	Full        bool                   `json:"full"`
+	Devices     map[string]interface{} `json:"devices"`
+	Checkpoints *[]repo3.Checkpoint    `json:"checkpoints"`
+	Docs        map[string]interface{} `json:"docs"`
+	Deleted     syncDeleted            `json:"deleted"`
+}
+
+// syncPushItem reports the outcome of one pushed doc. On a conflict Server
+// holds the server version the client has to merge with.
+type syncPushItem struct {
+	Index      int             `json:"index"`
+	Device     string          `json:"device"`
+	ID         string          `json:"_id"`
+	Result     string          `json:"result"`
+	ModifiedAt *time.Time      `json:"modified_at,omitempty"`
+	Error      interface{}     `json:"error,omitempty"`
+	Server     *repo.DeviceDoc `json:"server,omitempty"`
+}
+
+type syncPushResult struct {
+	Conflicts int            `json:"conflicts"`
+	Failed    int            `json:"failed"`
+	Results   []syncPushItem `json:"results"`
+}
+
+type DocSyncHandler struct {
+	deviceRepo *repo2.DeviceRepository
+	cpRepo     *repo3.CheckpointCollRepository
+	docRepo    *repo.DocRepository
+	syncers    map[string]docSyncer
+}
+
+func NewDocSyncAPIHandler(e *echo.Echo, db *mongo.Database) *DocSyncHandler {
+	h := &DocSyncHandler{
+		deviceRepo: repo2.NewDeviceRepository(db),
+		cpRepo:     repo3.NewCheckpointRepository(db),
+		docRepo:    repo.NewDocRepository(db),
+		syncers: map[string]docSyncer{
+			_const.CCTV:        newCCTVDocHandler(db),
+			_const.Fingerprint: newFingerprintDocHandler(db),
+			_const.KomputerPH1: newKomputerPH1DocHandler(db),
+			_const.KomputerPH2: newKomputerPH2DocHandler(db),
+			_const.Printer:     newPrinterDocHandler(db),
+			_const.Telepon:     newTeleponDocHandler(db),
+			_const.Toa:         newTOADocHandler(db),
+			_const.Ups:         newUPSDocHandler(db),
+		},
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/sync/pull", h.pull)
+	group.POST("/sync/push", h.push)
+
+	return h
+}
+
+// pull
+// @Tags Sync
+// @Summary Get devices, checkpoints and docs changed since the cursor, with deleted ids
+// @ID sync-pull
+// @Security ApiKeyAuth
+// @Param cursor query string false "Cursor returned by the previous pull, empty for the first pull"
+// @Router /api/sync/pull [GET]
+// @Produce json
+// @Success 200
+func (h *DocSyncHandler) pull(c echo.Context) error {
+	since, err := doc.ParseSyncCursor(strings.TrimSpace(c.QueryParam("cursor")))
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	result := &syncPullResult{
+		Full:    since.IsZero(),
+		Devices: make(map[string]interface{}, len(_const.Devices)),
+		Docs:    make(map[string]interface{}, len(_const.Devices)),
+		Deleted: syncDeleted{
+			Devices: make(map[string][]bson.ObjectID, len(_const.Devices)),
+			Docs:    make(map[string][]bson.ObjectID, len(_const.Devices)),
+		},
+	}
+
+	// the first pull only brings recent docs, older ones are not edited offline
+	docsSince := since
+	if result.Full {
+		docsSince = start.AddDate(0, 0, -syncDocDays)
+	}
+
+	for _, device := range _const.Devices {
+		result.Devices[device], err = h.deviceRepo.FindAllModifiedSince(device, since)
+		if err != nil {
+			log.Errorf("Failed to get changed %s devices: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		result.Docs[device], err = h.docRepo.FindAllModifiedSince(device, docsSince)
+		if err != nil {
+			log.Errorf("Failed to get changed %s docs: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		if result.Full {
+			continue
+		}
+
+		result.Deleted.Devices[device], err = h.deviceRepo.FindDeletedSince(device, since)
+		if err != nil {
+			log.Errorf("Failed to get deleted %s devices: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		result.Deleted.Docs[device], err = h.docRepo.FindDeletedSince(device, since)
+		if err != nil {
+			log.Errorf("Failed to get deleted %s docs: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	result.Checkpoints, err = h.cpRepo.FindAllModifiedSince(since)
+	if err != nil {
+		log.Errorf("Failed to get changed checkpoints: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result.Cursor = doc.NewSyncCursor(start)
+	return c.JSON(http.StatusOK, result)
+}
+
+// push
+// @Tags Sync
+// @Summary Push docs created or edited offline, reporting conflicts per doc
+// @ID sync-push
+// @Security ApiKeyAuth
+// @Param body body doc.SyncPushForm true "Sync Push Form"
+// @Router /api/sync/push [POST]
+// @Produce json
+// @Success 200
+func (h *DocSyncHandler) push(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewSyncPushForm(c)
+	if err != nil {
+		return err
+	}
+
+	result := &syncPushResult{
+		Results: make([]syncPushItem, len(f.Docs)),
+	}
+	for i := range f.Docs {
+		item := h.pushDoc(nc, f, i)
+		switch item.Result {
+		case syncConflict:
+			result.Conflicts++
+		case syncFailed:
+			result.Failed++
+		}
+		result.Results[i] = item
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// pushDoc applies the entry at index i. A doc that is not on the server yet
+// is created under the client id, so a retried push finds it and reports it
+// unchanged. An edit is only applied when it started from the server version.
+func (h *DocSyncHandler) pushDoc(nc *context.Context, f *doc.SyncPushForm, i int) syncPushItem {
+	sd := &f.Docs[i]
+	item := syncPushItem{
+		Index:  i,
+		Device: sd.Device,
+		ID:     sd.ID,
+	}
+
+	if err := f.Validate(i); err != nil {
+		return item.fail(err)
+	}
+	syncer := h.syncers[sd.Device]
+
+	state, err := h.docRepo.FindSyncState(sd.Device, sd.OID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		var d *repo.DeviceDoc
+		d, err = syncer.syncCreate(nc, sd.OID, &sd.DeviceDocForm)
+		if err == nil {
+			return item.done(syncCreated, d)
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return item.fail(err)
+		}
+
+		// a concurrent retry of the same push created it first
+		state, err = h.docRepo.FindSyncState(sd.Device, sd.OID)
+	}
+	if err != nil {
+		return item.fail(err)
+	}
+
+	if state.IsDeleted {
+		item.Result = syncConflict
+		item.Error = "Document was deleted"
+		return item
+	}
+
+	if len(doc.DiffCheckpoint(state.Checkpoint, sd.Checkpoint)) == 0 {
+		item.Result = syncUnchanged
+		item.ModifiedAt = &state.ModifiedAt
+		return item
+	}
+
+	if sd.Base != nil && sd.Base.Equal(state.ModifiedAt) {
+		d, err := syncer.syncUpdate(nc, sd.OID, *sd.Base, sd.Checkpoint)
+		if err == nil {
+			return item.done(syncUpdated, d)
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return item.fail(err)
+		}
+	}
+	return h.conflict(item, sd.OID)
+}
+
+// conflict reports the entry as conflicting with the current server version.
+func (h *DocSyncHandler) conflict(item syncPushItem, id bson.ObjectID) syncPushItem {
+	item.Result = syncConflict
+
+	d, err := h.docRepo.FindOneByID(item.Device, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			item.Error = "Document was deleted"
+			return item
+		}
+		return item.fail(err)
+	}
+
+	item.Error = "Document was changed on the server"
+	item.ModifiedAt = &d.ModifiedAt
+	item.Server = d
+	return item
+}
+
+func (item syncPushItem) done(result string, d *repo.DeviceDoc) syncPushItem {
+	item.Result = result
+	item.ModifiedAt = &d.ModifiedAt
+	return item
+}
+
+func (item syncPushItem) fail(err error) syncPushItem {
+	item.Result = syncFailed
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		item.Error = he.Message
+		return item
+	}
+
+	log.Errorf("Failed to sync %s doc %s: %v", item.Device, item.ID, err)
+	item.Error = "Internal server error"
+	return item
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type TeleponDocHandler struct {
 	teleponDoc     *repo2.TeleponCollRepository
 	teleponDocRepo *repo.TeleponDocCollRepository
+	cpRepo         *repo3.CheckpointCollRepository
+	ticketRepo     *repo4.TicketCollRepository
+	revisionRepo   *repo.DocRevisionCollRepository
 }
 
-func NewTeleponDocAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponDocHandler {
-	h := &TeleponDocHandler{
+func newTeleponDocHandler(db *mongo.Database) *TeleponDocHandler {
+	return &TeleponDocHandler{
 		teleponDoc:     repo2.NewTeleponRepository(db),
 		teleponDocRepo: repo.NewTeleponDocRepository(db),
+		cpRepo:         repo3.NewCheckpointRepository(db),
+		ticketRepo:     repo4.NewTicketRepository(db),
+		revisionRepo:   repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewTeleponDocAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponDocHandler {
+	h := newTeleponDocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/telepons", h.findAll)
 	group.GET("/doc/telepon/:id", h.findByID)
+	group.GET("/telepon/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/telepon", h.create)
+	group.POST("/doc/telepon/bulk", h.bulkCreate)
 
 	group.PUT("/doc/telepon/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewTeleponDocAPIHandler(e *echo.Echo, db *mongo.Database) *TeleponDocHandle
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/telepons [GET]
 // @Produce json
 // @Success 200
 func (h *TeleponDocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	teleponDocs, err := h.teleponDocRepo.FindAll(cq)
+	teleponDocs, err := h.teleponDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get teleponDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *TeleponDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Telepon Docs not found")
 	}
 
-	totalTeleponDocs, err := h.teleponDocRepo.CountQuery(cq)
+	totalTeleponDocs, err := h.teleponDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count teleponDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *TeleponDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "Telepon Docs not found")
 	}
 
-	result := util.MakeResult(teleponDocs, totalTeleponDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(teleponDocs, totalTeleponDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *TeleponDocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, teleponDoc)
 }
 
+// findAllByDevice
+// @Tags Doc Telepon
+// @Summary Get maintenance history of a telepon
+// @ID get-telepon-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "Telepon ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/telepon/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *TeleponDocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid telepon ID")
+	}
+
+	telepon, err := h.teleponDoc.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Telepon not found")
+		}
+		log.Errorf("Failed to get telepon: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	teleponDocs, err := h.teleponDocRepo.FindAllByDeviceID(telepon.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get teleponDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalTeleponDocs, err := h.teleponDocRepo.CountByDeviceID(telepon.ID)
+	if err != nil {
+		log.Errorf("Failed to count teleponDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(teleponDocs, totalTeleponDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc Telepon
 // @Summary Create new telepon document
@@ -123,34 +199,114 @@ func (h *TeleponDocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	teleponDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.teleponDocRepo.InsertOne(teleponDoc)
+	if err != nil {
+		log.Errorf("Failed to create teleponDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, teleponDoc.Summary(), teleponDoc.Inserted)
+	return c.JSON(http.StatusOK, teleponDoc)
+}
+
+// bulkCreate
+// @Tags Doc Telepon
+// @Summary Create many telepon documents at once
+// @ID bulk-create-telepon-document
+// @Security ApiKeyAuth
+// @Router /api/doc/telepon/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk Telepon Document Form"
+// @Success 200
+func (h *TeleponDocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	teleponDocs := make([]repo.TeleponDoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		teleponDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &teleponDoc.ID
+		teleponDocs = append(teleponDocs, *teleponDoc)
+	}
+
+	if f.Atomic && len(teleponDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(teleponDocs) > 0 {
+		err = h.teleponDocRepo.InsertMany(teleponDocs)
+		if err != nil {
+			log.Errorf("Failed to create teleponDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range teleponDocs {
+		syncTickets(h.ticketRepo, teleponDocs[i].Summary(), teleponDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, teleponDocs))
+}
+
+// newDoc builds a telepon doc from the form, snapshotting the device.
+func (h *TeleponDocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.TeleponDoc, error) {
 	telepon, err := h.teleponDoc.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "Telepon not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Telepon not found")
 		}
 		log.Errorf("Failed to get telepon: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.Telepon, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	teleponDoc := &repo.TeleponDoc{
-		ID:         bson.NewObjectID(),
-		Lokasi:     telepon.Lokasi,
-		Departemen: telepon.Departemen,
-		User:       telepon.User,
-		Ext:        telepon.Ext,
-		Merk:       telepon.Merk,
-		Tipe:       telepon.Tipe,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          telepon.ID,
+		Device:            _const.Telepon,
+		Lokasi:            telepon.Lokasi,
+		Departemen:        telepon.Departemen,
+		User:              telepon.User,
+		Ext:               telepon.Ext,
+		Merk:              telepon.Merk,
+		Tipe:              telepon.Tipe,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.teleponDocRepo.InsertOne(teleponDoc)
-	if err != nil {
-		log.Errorf("Failed to create teleponDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		teleponDoc.Status = _const.DocSubmitted
+		teleponDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, teleponDoc)
+	return teleponDoc, nil
 }
 
 // update
@@ -178,6 +334,11 @@ func (h *TeleponDocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.Telepon, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	teleponDoc, err := h.teleponDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -187,13 +348,33 @@ func (h *TeleponDocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if teleponDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Telepon Doc is read-only")
+	}
+	if teleponDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Telepon Doc is under review, reject it to edit")
+	}
+
+	previous := teleponDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	teleponDoc.Checkpoint = f.Checkpoint
-	teleponDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.teleponDocRepo.UpdateOneByID(oId, teleponDoc)
+	teleponDoc.CheckpointVersion = cp.Version
+	teleponDoc.Updated = updated
+	err = h.teleponDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, teleponDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Telepon Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update teleponDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save teleponDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, teleponDoc.Summary(), *teleponDoc.Updated)
 	return c.JSON(http.StatusOK, teleponDoc)
 }
 
@@ -206,6 +387,8 @@ func (h *TeleponDocHandler) update(c echo.Context) error {
 // @Param id path string true "Telepon Document ID"
 // @Success 200
 func (h *TeleponDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -214,13 +397,86 @@ func (h *TeleponDocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.teleponDocRepo.DeleteOneByID(oId)
+	teleponDoc, err := h.teleponDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "Telepon Doc not found")
 		}
+		log.Errorf("Failed to get teleponDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if teleponDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved Telepon Doc is read-only")
+	}
+	if teleponDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted Telepon Doc is under review, reject it to delete")
+	}
+
+	err = h.teleponDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "Telepon Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete teleponDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "Telepon Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *TeleponDocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	teleponDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	teleponDoc.ID = id
+	err = h.teleponDocRepo.InsertOne(teleponDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, teleponDoc.Summary(), teleponDoc.Inserted)
+	return teleponDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *TeleponDocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.Telepon, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	teleponDoc, err := h.teleponDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if teleponDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved Telepon Doc is read-only")
+	}
+	if teleponDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted Telepon Doc is under review, reject it to edit")
+	}
+
+	previous := teleponDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	teleponDoc.Checkpoint = checkpoint
+	teleponDoc.CheckpointVersion = cp.Version
+	teleponDoc.Updated = updated
+	err = h.teleponDocRepo.UpdateOneIfUnmodified(id, base, teleponDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save teleponDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, teleponDoc.Summary(), *updated)
+	return teleponDoc.Summary(), nil
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+)
+
+// syncTickets opens a corrective ticket for each failed checkpoint of d and
+// resolves the tickets of the checkpoints d reports OK. Only submitted,
+// approved and legacy docs without a status count, a draft syncs once
+// submitted. Failures are logged without failing the doc request.
+func syncTickets(ticketRepo *repo4.TicketCollRepository, d *repo.DeviceDoc, by doc.ByAt) {
+	if d.DeviceID.IsZero() {
+		return
+	}
+	switch d.Status {
+	case "", _const.DocSubmitted, _const.DocApproved:
+	default:
+		return
+	}
+
+	for _, cp := range d.Checkpoint {
+		if !cp.OK {
+			err := ticketRepo.Open(d.Device, d.DeviceID, d.ID, d.Inserted.At, cp, by)
+			if err != nil {
+				log.Errorf("Failed to open %s ticket for %s: %v", d.Device, cp.Name, err)
+			}
+			continue
+		}
+
+		_, err := ticketRepo.ResolveByDoc(d.DeviceID, d.ID, d.Inserted.At, cp.Name, by)
+		if err != nil {
+			log.Errorf("Failed to resolve %s tickets for %s: %v", d.Device, cp.Name, err)
+		}
+	}
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type TOADocHandler struct {
-	toaRepo    *repo2.TOACollRepository
-	toaDocRepo *repo.TOADocCollRepository
+	toaRepo      *repo2.TOACollRepository
+	toaDocRepo   *repo.TOADocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewTOADocAPIHandler(e *echo.Echo, db *mongo.Database) *TOADocHandler {
-	h := &TOADocHandler{
-		toaRepo:    repo2.NewTOARepository(db),
-		toaDocRepo: repo.NewTOADocRepository(db),
+func newTOADocHandler(db *mongo.Database) *TOADocHandler {
+	return &TOADocHandler{
+		toaRepo:      repo2.NewTOARepository(db),
+		toaDocRepo:   repo.NewTOADocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewTOADocAPIHandler(e *echo.Echo, db *mongo.Database) *TOADocHandler {
+	h := newTOADocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/toas", h.findAll)
 	group.GET("/doc/toa/:id", h.findByID)
+	group.GET("/toa/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/toa", h.create)
+	group.POST("/doc/toa/bulk", h.bulkCreate)
 
 	group.PUT("/doc/toa/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewTOADocAPIHandler(e *echo.Echo, db *mongo.Database) *TOADocHandler {
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/toas [GET]
 // @Produce json
 // @Success 200
 func (h *TOADocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	toaDocs, err := h.toaDocRepo.FindAll(cq)
+	toaDocs, err := h.toaDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get toaDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *TOADocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "TOA Docs not found")
 	}
 
-	totalToaDocs, err := h.toaDocRepo.CountQuery(cq)
+	totalToaDocs, err := h.toaDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count toaDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *TOADocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "TOA Docs not found")
 	}
 
-	result := util.MakeResult(toaDocs, totalToaDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(toaDocs, totalToaDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *TOADocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, toaDoc)
 }
 
+// findAllByDevice
+// @Tags Doc TOA
+// @Summary Get maintenance history of a toa
+// @ID get-toa-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "TOA ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/toa/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *TOADocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid toa ID")
+	}
+
+	toa, err := h.toaRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "TOA not found")
+		}
+		log.Errorf("Failed to get toa: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	toaDocs, err := h.toaDocRepo.FindAllByDeviceID(toa.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get toaDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalToaDocs, err := h.toaDocRepo.CountByDeviceID(toa.ID)
+	if err != nil {
+		log.Errorf("Failed to count toaDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(toaDocs, totalToaDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc TOA
 // @Summary Create toa document
@@ -122,32 +198,112 @@ func (h *TOADocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	toaDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.toaDocRepo.InsertOne(toaDoc)
+	if err != nil {
+		log.Errorf("Failed to create toaDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, toaDoc.Summary(), toaDoc.Inserted)
+	return c.JSON(http.StatusOK, toaDoc)
+}
+
+// bulkCreate
+// @Tags Doc TOA
+// @Summary Create many toa documents at once
+// @ID bulk-create-toa-document
+// @Security ApiKeyAuth
+// @Router /api/doc/toa/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk TOA Document Form"
+// @Success 200
+func (h *TOADocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	toaDocs := make([]repo.TOADoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		toaDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &toaDoc.ID
+		toaDocs = append(toaDocs, *toaDoc)
+	}
+
+	if f.Atomic && len(toaDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(toaDocs) > 0 {
+		err = h.toaDocRepo.InsertMany(toaDocs)
+		if err != nil {
+			log.Errorf("Failed to create toaDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range toaDocs {
+		syncTickets(h.ticketRepo, toaDocs[i].Summary(), toaDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, toaDocs))
+}
+
+// newDoc builds a toa doc from the form, snapshotting the device.
+func (h *TOADocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.TOADoc, error) {
 	toa, err := h.toaRepo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "TOA not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "TOA not found")
 		}
 		log.Errorf("Failed to get toa: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.Toa, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	toaDoc := &repo.TOADoc{
-		ID:         bson.NewObjectID(),
-		Nama:       toa.Nama,
-		Lokasi:     toa.Lokasi,
-		Kode:       toa.Kode,
-		Posisi:     toa.Posisi,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          toa.ID,
+		Device:            _const.Toa,
+		Nama:              toa.Nama,
+		Lokasi:            toa.Lokasi,
+		Kode:              toa.Kode,
+		Posisi:            toa.Posisi,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.toaDocRepo.InsertOne(toaDoc)
-	if err != nil {
-		log.Errorf("Failed to create toaDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		toaDoc.Status = _const.DocSubmitted
+		toaDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, toaDoc)
+	return toaDoc, nil
 }
 
 // update
@@ -175,6 +331,11 @@ func (h *TOADocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.Toa, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	toaDoc, err := h.toaDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -184,13 +345,33 @@ func (h *TOADocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if toaDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved TOA Doc is read-only")
+	}
+	if toaDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted TOA Doc is under review, reject it to edit")
+	}
+
+	previous := toaDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	toaDoc.Checkpoint = f.Checkpoint
-	toaDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.toaDocRepo.UpdateOneByID(oId, toaDoc)
+	toaDoc.CheckpointVersion = cp.Version
+	toaDoc.Updated = updated
+	err = h.toaDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, toaDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "TOA Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update toaDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save toaDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, toaDoc.Summary(), *toaDoc.Updated)
 	return c.JSON(http.StatusOK, toaDoc)
 }
 
@@ -203,6 +384,8 @@ func (h *TOADocHandler) update(c echo.Context) error {
 // @Param id path string true "TOA Document ID"
 // @Success 200
 func (h *TOADocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -211,13 +394,86 @@ func (h *TOADocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.toaDocRepo.DeleteOneByID(oId)
+	toaDoc, err := h.toaDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "TOA Doc not found")
 		}
+		log.Errorf("Failed to get toaDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if toaDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved TOA Doc is read-only")
+	}
+	if toaDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted TOA Doc is under review, reject it to delete")
+	}
+
+	err = h.toaDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "TOA Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete toaDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "TOA Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *TOADocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	toaDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	toaDoc.ID = id
+	err = h.toaDocRepo.InsertOne(toaDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, toaDoc.Summary(), toaDoc.Inserted)
+	return toaDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *TOADocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.Toa, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	toaDoc, err := h.toaDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if toaDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved TOA Doc is read-only")
+	}
+	if toaDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted TOA Doc is under review, reject it to edit")
+	}
+
+	previous := toaDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	toaDoc.Checkpoint = checkpoint
+	toaDoc.CheckpointVersion = cp.Version
+	toaDoc.Updated = updated
+	err = h.toaDocRepo.UpdateOneIfUnmodified(id, base, toaDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save toaDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, toaDoc.Summary(), *updated)
+	return toaDoc.Summary(), nil
+}
@@ -7,30 +7,46 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"net/http"
 	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_cp/repo"
 	"sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/context"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/log"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type UPSDocHandler struct {
-	upsRepo    *repo2.UPSCollRepository
-	upsDocRepo *repo.UPSDocCollRepository
+	upsRepo      *repo2.UPSCollRepository
+	upsDocRepo   *repo.UPSDocCollRepository
+	cpRepo       *repo3.CheckpointCollRepository
+	ticketRepo   *repo4.TicketCollRepository
+	revisionRepo *repo.DocRevisionCollRepository
 }
 
-func NewUPSDocAPIHandler(e *echo.Echo, db *mongo.Database) *UPSDocHandler {
-	h := &UPSDocHandler{
-		upsRepo:    repo2.NewUPSRepository(db),
-		upsDocRepo: repo.NewUPSDocRepository(db),
+func newUPSDocHandler(db *mongo.Database) *UPSDocHandler {
+	return &UPSDocHandler{
+		upsRepo:      repo2.NewUPSRepository(db),
+		upsDocRepo:   repo.NewUPSDocRepository(db),
+		cpRepo:       repo3.NewCheckpointRepository(db),
+		ticketRepo:   repo4.NewTicketRepository(db),
+		revisionRepo: repo.NewDocRevisionRepository(db),
 	}
+}
+
+func NewUPSDocAPIHandler(e *echo.Echo, db *mongo.Database) *UPSDocHandler {
+	h := newUPSDocHandler(db)
 
 	group := e.Group("/api", context.Handler)
 
 	group.GET("/doc/ups", h.findAll)
 	group.GET("/doc/ups/:id", h.findByID)
+	group.GET("/ups/:id/docs", h.findAllByDevice)
 
 	group.POST("/doc/ups", h.create)
+	group.POST("/doc/ups/bulk", h.bulkCreate)
 
 	group.PUT("/doc/ups/:id", h.update)
 
@@ -48,13 +64,25 @@ func NewUPSDocAPIHandler(e *echo.Echo, db *mongo.Database) *UPSDocHandler {
 // @Param page query int false "Page number pagination" default(1)
 // @Param limit query int false "Limit pagination" default(10)
 // @Param sort query string false "Sort" enums(asc,desc)
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param from query string false "Created from date (YYYY-MM-DD)"
+// @Param to query string false "Created until date inclusive (YYYY-MM-DD)"
+// @Param technician_id query string false "Filter by technician user ID"
+// @Param lokasi query string false "Filter by lokasi"
+// @Param departemen query string false "Filter by departemen"
+// @Param failed query bool false "Only docs with failed checkpoints"
+// @Param failed_checkpoint query string false "Only docs where this checkpoint failed"
+// @Param sort_by query string false "Sort by date or a doc field, default _id"
 // @Router /api/doc/ups [GET]
 // @Produce json
 // @Success 200
 func (h *UPSDocHandler) findAll(c echo.Context) error {
-	cq := util.NewCommonQuery(c)
+	dq, err := doc.NewDocQuery(c)
+	if err != nil {
+		return err
+	}
 
-	upsDocs, err := h.upsDocRepo.FindAll(cq)
+	upsDocs, err := h.upsDocRepo.FindAll(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to get upsDocs: %v", err)
@@ -63,7 +91,7 @@ func (h *UPSDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "UPS Docs not found")
 	}
 
-	totalUpsDocs, err := h.upsDocRepo.CountQuery(cq)
+	totalUpsDocs, err := h.upsDocRepo.CountQuery(dq)
 	if err != nil {
 		if !errors.Is(err, mongo.ErrNoDocuments) {
 			log.Errorf("Failed to count upsDocs: %v", err)
@@ -72,7 +100,7 @@ func (h *UPSDocHandler) findAll(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusNotFound, "UPS Docs not found")
 	}
 
-	result := util.MakeResult(upsDocs, totalUpsDocs, cq.Page, cq.Limit)
+	result := util.MakeResult(upsDocs, totalUpsDocs, dq.Page, dq.Limit)
 	return c.JSON(http.StatusOK, result)
 }
 
@@ -105,6 +133,54 @@ func (h *UPSDocHandler) findByID(c echo.Context) error {
 	return c.JSON(http.StatusOK, upsDoc)
 }
 
+// findAllByDevice
+// @Tags Doc UPS
+// @Summary Get maintenance history of a ups
+// @ID get-ups-documents-by-device
+// @Security ApiKeyAuth
+// @Param id path string true "UPS ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/ups/{id}/docs [GET]
+// @Produce json
+// @Success 200
+func (h *UPSDocHandler) findAllByDevice(c echo.Context) error {
+	id := c.Param("id")
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ups ID")
+	}
+
+	ups, err := h.upsRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "UPS not found")
+		}
+		log.Errorf("Failed to get ups: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	upsDocs, err := h.upsDocRepo.FindAllByDeviceID(ups.ID, cq)
+	if err != nil {
+		log.Errorf("Failed to get upsDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalUpsDocs, err := h.upsDocRepo.CountByDeviceID(ups.ID)
+	if err != nil {
+		log.Errorf("Failed to count upsDocs: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(upsDocs, totalUpsDocs, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
 // create
 // @Tags Doc UPS
 // @Summary Create new ups document
@@ -122,33 +198,113 @@ func (h *UPSDocHandler) create(c echo.Context) error {
 		return err
 	}
 
+	upsDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return err
+	}
+
+	err = h.upsDocRepo.InsertOne(upsDoc)
+	if err != nil {
+		log.Errorf("Failed to create upsDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	syncTickets(h.ticketRepo, upsDoc.Summary(), upsDoc.Inserted)
+	return c.JSON(http.StatusOK, upsDoc)
+}
+
+// bulkCreate
+// @Tags Doc UPS
+// @Summary Create many ups documents at once
+// @ID bulk-create-ups-document
+// @Security ApiKeyAuth
+// @Router /api/doc/ups/bulk [POST]
+// @Produce json
+// @Param body body doc.BulkDeviceDocForm true "Bulk UPS Document Form"
+// @Success 200
+func (h *UPSDocHandler) bulkCreate(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := doc.NewBulkDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	results := make([]doc.BulkItemResult, len(f.Docs))
+	upsDocs := make([]repo.UPSDoc, 0, len(f.Docs))
+	for i := range f.Docs {
+		results[i].Index = i
+
+		if err := f.Validate(i); err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		upsDoc, err := h.newDoc(nc, &f.Docs[i])
+		if err != nil {
+			results[i].Error = bulkItemError(err)
+			continue
+		}
+
+		results[i].ID = &upsDoc.ID
+		upsDocs = append(upsDocs, *upsDoc)
+	}
+
+	if f.Atomic && len(upsDocs) < len(f.Docs) {
+		return rejectBulk(c, results)
+	}
+
+	if len(upsDocs) > 0 {
+		err = h.upsDocRepo.InsertMany(upsDocs)
+		if err != nil {
+			log.Errorf("Failed to create upsDocs: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	for i := range upsDocs {
+		syncTickets(h.ticketRepo, upsDocs[i].Summary(), upsDocs[i].Inserted)
+	}
+	return c.JSON(http.StatusOK, newBulkResult(results, upsDocs))
+}
+
+// newDoc builds a ups doc from the form, snapshotting the device.
+func (h *UPSDocHandler) newDoc(nc *context.Context, f *doc.DeviceDocForm) (*repo.UPSDoc, error) {
 	ups, err := h.upsRepo.FindOneByID(f.DeviceOID)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return echo.NewHTTPError(http.StatusNotFound, "UPS not found")
+			return nil, echo.NewHTTPError(http.StatusNotFound, "UPS not found")
 		}
 		log.Errorf("Failed to get ups: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := validateCheckpoint(h.cpRepo, _const.Ups, f.Checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
 	upsDoc := &repo.UPSDoc{
-		ID:         bson.NewObjectID(),
-		Nama:       ups.Nama,
-		Departemen: ups.Departemen,
-		Tipe:       ups.Tipe,
-		NoSeri:     ups.NoSeri,
-		Lokasi:     ups.Lokasi,
-		Checkpoint: f.Checkpoint,
-		Inserted:   nc.Claims.ByAt(),
-		IsDeleted:  false,
+		ID:                bson.NewObjectID(),
+		DeviceID:          ups.ID,
+		Device:            _const.Ups,
+		Nama:              ups.Nama,
+		Departemen:        ups.Departemen,
+		Tipe:              ups.Tipe,
+		NoSeri:            ups.NoSeri,
+		Lokasi:            ups.Lokasi,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
 	}
 
-	err = h.upsDocRepo.InsertOne(upsDoc)
-	if err != nil {
-		log.Errorf("Failed to create upsDoc: %v", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	if f.Submit {
+		upsDoc.Status = _const.DocSubmitted
+		upsDoc.Submitted = nc.Claims.ByAtPtr()
 	}
-	return c.JSON(http.StatusOK, upsDoc)
+	return upsDoc, nil
 }
 
 // update
@@ -176,6 +332,11 @@ func (h *UPSDocHandler) update(c echo.Context) error {
 		return err
 	}
 
+	cp, err := validateCheckpoint(h.cpRepo, _const.Ups, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
 	upsDoc, err := h.upsDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -185,13 +346,33 @@ func (h *UPSDocHandler) update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 
+	if upsDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved UPS Doc is read-only")
+	}
+	if upsDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted UPS Doc is under review, reject it to edit")
+	}
+
+	previous := upsDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
 	upsDoc.Checkpoint = f.Checkpoint
-	upsDoc.Updated = nc.Claims.ByAtPtr()
-	err = h.upsDocRepo.UpdateOneByID(oId, upsDoc)
+	upsDoc.CheckpointVersion = cp.Version
+	upsDoc.Updated = updated
+	err = h.upsDocRepo.UpdateOneIfUnmodified(oId, previous.ModifiedAt, upsDoc)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "UPS Doc was changed, please reload")
+		}
 		log.Errorf("Failed to update upsDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save upsDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, upsDoc.Summary(), *upsDoc.Updated)
 	return c.JSON(http.StatusOK, upsDoc)
 }
 
@@ -204,6 +385,8 @@ func (h *UPSDocHandler) update(c echo.Context) error {
 // @Param id path string true "UPS Document ID"
 // @Success 200
 func (h *UPSDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
 	id := c.Param("id")
 
 	oId, err := bson.ObjectIDFromHex(id)
@@ -212,13 +395,86 @@ func (h *UPSDocHandler) delete(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
 	}
 
-	err = h.upsDocRepo.DeleteOneByID(oId)
+	upsDoc, err := h.upsDocRepo.FindOneByID(oId)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return echo.NewHTTPError(http.StatusNotFound, "UPS Doc not found")
 		}
+		log.Errorf("Failed to get upsDoc: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if upsDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved UPS Doc is read-only")
+	}
+	if upsDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted UPS Doc is under review, reject it to delete")
+	}
+
+	err = h.upsDocRepo.DeleteOneByID(oId, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, "UPS Doc was changed, please reload")
+		}
 		log.Errorf("Failed to delete upsDoc: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
 	}
 	return c.JSON(http.StatusOK, "UPS Doc deleted")
 }
+
+// syncCreate creates a doc pushed by an offline client under the id the
+// client generated.
+func (h *UPSDocHandler) syncCreate(nc *context.Context, id bson.ObjectID, f *doc.DeviceDocForm) (*repo.DeviceDoc, error) {
+	upsDoc, err := h.newDoc(nc, f)
+	if err != nil {
+		return nil, err
+	}
+
+	upsDoc.ID = id
+	err = h.upsDocRepo.InsertOne(upsDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	syncTickets(h.ticketRepo, upsDoc.Summary(), upsDoc.Inserted)
+	return upsDoc.Summary(), nil
+}
+
+// syncUpdate applies checkpoint results pushed by an offline client, failing
+// with mongo.ErrNoDocuments when the doc was written after base.
+func (h *UPSDocHandler) syncUpdate(nc *context.Context, id bson.ObjectID, base time.Time, checkpoint []doc.CPDetail) (*repo.DeviceDoc, error) {
+	cp, err := validateCheckpoint(h.cpRepo, _const.Ups, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	upsDoc, err := h.upsDocRepo.FindOneByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upsDoc.Status == _const.DocApproved {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Approved UPS Doc is read-only")
+	}
+	if upsDoc.Status == _const.DocSubmitted {
+		return nil, echo.NewHTTPError(http.StatusConflict, "Submitted UPS Doc is under review, reject it to edit")
+	}
+
+	previous := upsDoc.Summary()
+	updated := nc.Claims.ByAtPtr()
+	upsDoc.Checkpoint = checkpoint
+	upsDoc.CheckpointVersion = cp.Version
+	upsDoc.Updated = updated
+	err = h.upsDocRepo.UpdateOneIfUnmodified(id, base, upsDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveRevision(h.revisionRepo, previous, *updated)
+	if err != nil {
+		log.Errorf("Failed to save upsDoc revision: %v", err)
+	}
+
+	syncTickets(h.ticketRepo, upsDoc.Summary(), *updated)
+	return upsDoc.Summary(), nil
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+)
+
+// DocRevision is a previous version of a doc's checkpoint results, stored
+// every time the doc is updated. Author is who wrote that version and
+// Inserted who replaced it.
+type DocRevision struct {
+	ID                bson.ObjectID  `json:"_id" bson:"_id"`
+	Device            string         `json:"device" bson:"device"`
+	DocID             bson.ObjectID  `json:"doc_id" bson:"doc_id"`
+	Revision          int            `json:"revision" bson:"revision"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version" bson:"checkpoint_version"`
+	Author            doc.ByAt       `json:"author" bson:"author"`
+	Inserted          doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+}
+
+type DocRevisionCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewDocRevisionRepository(db *mongo.Database) *DocRevisionCollRepository {
+	return &DocRevisionCollRepository{
+		coll: db.Collection("doc_revisions"),
+	}
+}
+
+// EnsureIndexes numbers the revisions of a doc uniquely, so two updates saved
+// at the same time can't both take the next number.
+func (r *DocRevisionCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Key("device", "doc_id", "revision"))
+}
+
+func (r *DocRevisionCollRepository) FindAllByDoc(device string, docID bson.ObjectID) (*[]DocRevision, error) {
+	var revisions []DocRevision
+	filter := bson.M{
+		"device": device,
+		"doc_id": docID,
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"revision": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &revisions)
+	if err != nil {
+		return nil, err
+	}
+	if revisions == nil {
+		return &[]DocRevision{}, nil
+	}
+	return &revisions, nil
+}
+
+func (r *DocRevisionCollRepository) CountByDoc(device string, docID bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"device": device,
+		"doc_id": docID,
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *DocRevisionCollRepository) InsertOne(revision *DocRevision) error {
+	_, err := r.coll.InsertOne(context.TODO(), revision)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DocRevisionCollRepository) DeleteAllByDoc(device string, docID bson.ObjectID) error {
+	filter := bson.M{
+		"device": device,
+		"doc_id": docID,
+	}
+
+	_, err := r.coll.DeleteMany(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"time"
+)
+
+type DocField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// DeviceDoc is the type-independent view of a maintenance doc, with the
+// snapshotted device fields in display order.
+type DeviceDoc struct {
+	ID                bson.ObjectID  `json:"_id"`
+	Device            string         `json:"device"`
+	DeviceID          bson.ObjectID  `json:"device_id"`
+	Fields            []DocField     `json:"fields"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version"`
+	Status            string         `json:"status"`
+	Submitted         *doc.ByAt      `json:"submitted,omitempty"`
+	Reviewed          *doc.ByAt      `json:"reviewed,omitempty"`
+	RejectReason      string         `json:"reject_reason,omitempty"`
+	Inserted          doc.ByAt       `json:"inserted"`
+	Updated           *doc.ByAt      `json:"updated,omitempty"`
+	ModifiedAt        time.Time      `json:"modified_at"`
+}
+
+// DocSyncState is what a sync push compares against, including deleted docs.
+type DocSyncState struct {
+	ID         bson.ObjectID  `bson:"_id"`
+	Checkpoint []doc.CPDetail `bson:"checkpoint"`
+	ModifiedAt time.Time      `bson:"modified_at"`
+	IsDeleted  bool           `bson:"is_deleted"`
+}
+
+// DocRepository queries the eight doc collections together.
+type DocRepository struct {
+	cctv        *CCTVDocCollRepository
+	fingerprint *FingerprintDocCollRepository
+	kph1        *KomputerPH1DocCollRepository
+	kph2        *KomputerPH2DocCollRepository
+	printer     *PrinterDocCollRepository
+	telepon     *TeleponDocCollRepository
+	toa         *TOADocCollRepository
+	ups         *UPSDocCollRepository
+}
+
+func NewDocRepository(db *mongo.Database) *DocRepository {
+	return &DocRepository{
+		cctv:        NewCCTVDocRepository(db),
+		fingerprint: NewFingerprintDocRepository(db),
+		kph1:        NewKomputerPH1DocRepository(db),
+		kph2:        NewKomputerPH2DocRepository(db),
+		printer:     NewPrinterDocRepository(db),
+		telepon:     NewTeleponDocRepository(db),
+		toa:         NewTOADocRepository(db),
+		ups:         NewUPSDocRepository(db),
+	}
+}
+
+func (r *DocRepository) coll(device string) *mongo.Collection {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.coll
+	case _const.Fingerprint:
+		return r.fingerprint.coll
+	case _const.KomputerPH1:
+		return r.kph1.coll
+	case _const.KomputerPH2:
+		return r.kph2.coll
+	case _const.Printer:
+		return r.printer.coll
+	case _const.Telepon:
+		return r.telepon.coll
+	case _const.Toa:
+		return r.toa.coll
+	case _const.Ups:
+		return r.ups.coll
+	}
+	return nil
+}
+
+// LastDocDates returns the date of the latest doc of every device of the type.
+func (r *DocRepository) LastDocDates(device string) (map[bson.ObjectID]time.Time, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return map[bson.ObjectID]time.Time{}, nil
+	}
+	return lastDocDates(coll)
+}
+
+// DocStats returns the doc and checkpoint counts of every device of the type
+// maintained within [from, to).
+func (r *DocRepository) DocStats(device string, from, to time.Time) (map[bson.ObjectID]DocStat, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return map[bson.ObjectID]DocStat{}, nil
+	}
+	return docStats(coll, from, to)
+}
+
+// CheckpointMonthStats returns the monthly result counts of every checkpoint
+// of the type recorded within [from, to), or only of the named checkpoint.
+func (r *DocRepository) CheckpointMonthStats(device string, from, to time.Time, checkpoint string) ([]CheckpointMonthStat, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return []CheckpointMonthStat{}, nil
+	}
+	return checkpointMonthStats(coll, from, to, checkpoint)
+}
+
+// DeviceFailureStats returns the limit devices of the type with the most
+// failed checkpoint results recorded within [from, to).
+func (r *DocRepository) DeviceFailureStats(device string, from, to time.Time, limit int64) ([]DeviceFailureStat, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return []DeviceFailureStat{}, nil
+	}
+	return deviceFailureStats(coll, from, to, limit)
+}
+
+// FindOneByID returns mongo.ErrNoDocuments when the doc does not exist.
+func (r *DocRepository) FindOneByID(device string, id bson.ObjectID) (*DeviceDoc, error) {
+	switch device {
+	case _const.CCTV:
+		d, err := r.cctv.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.Fingerprint:
+		d, err := r.fingerprint.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.KomputerPH1:
+		d, err := r.kph1.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.KomputerPH2:
+		d, err := r.kph2.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.Printer:
+		d, err := r.printer.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.Telepon:
+		d, err := r.telepon.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.Toa:
+		d, err := r.toa.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	case _const.Ups:
+		d, err := r.ups.FindOneByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return d.Summary(), nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// FindLastByDevice returns the latest doc of the device, or
+// mongo.ErrNoDocuments when it was never maintained.
+func (r *DocRepository) FindLastByDevice(device string, deviceID bson.ObjectID) (*DeviceDoc, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	filter := bson.M{
+		"device_id":  deviceID,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.FindOne().
+		SetSort(bson.D{{Key: "inserted.at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"_id": 1})
+
+	var last struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err := coll.FindOne(context.TODO(), filter, findOptions).Decode(&last)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindOneByID(device, last.ID)
+}
+
+// FindAllByPeriod returns the docs of the type created within [from, to) that
+// the stats count, leaving out drafts and rejected docs.
+func (r *DocRepository) FindAllByPeriod(device string, from, to time.Time) ([]DeviceDoc, error) {
+	var result []DeviceDoc
+	switch device {
+	case _const.CCTV:
+		docs, err := r.cctv.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.Fingerprint:
+		docs, err := r.fingerprint.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.KomputerPH1:
+		docs, err := r.kph1.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.KomputerPH2:
+		docs, err := r.kph2.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.Printer:
+		docs, err := r.printer.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.Telepon:
+		docs, err := r.telepon.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.Toa:
+		docs, err := r.toa.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	case _const.Ups:
+		docs, err := r.ups.FindAllByPeriod(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range *docs {
+			result = append(result, *d.Summary())
+		}
+	}
+	return result, nil
+}
+
+// Each calls fn with the summary of every doc of the type matching dq, read
+// one at a time from a cursor.
+func (r *DocRepository) Each(device string, dq *doc.DocQuery, fn func(*DeviceDoc) error) error {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.Each(dq, fn)
+	case _const.Fingerprint:
+		return r.fingerprint.Each(dq, fn)
+	case _const.KomputerPH1:
+		return r.kph1.Each(dq, fn)
+	case _const.KomputerPH2:
+		return r.kph2.Each(dq, fn)
+	case _const.Printer:
+		return r.printer.Each(dq, fn)
+	case _const.Telepon:
+		return r.telepon.Each(dq, fn)
+	case _const.Toa:
+		return r.toa.Each(dq, fn)
+	case _const.Ups:
+		return r.ups.Each(dq, fn)
+	}
+	return mongo.ErrNoDocuments
+}
+
+// FieldLabels returns the labels of the snapshotted device fields of the
+// docs of the type, in the order of DeviceDoc.Fields.
+func FieldLabels(device string) []string {
+	var fields []DocField
+	switch device {
+	case _const.CCTV:
+		fields = (&CCTVDoc{}).Summary().Fields
+	case _const.Fingerprint:
+		fields = (&FingerprintDoc{}).Summary().Fields
+	case _const.KomputerPH1:
+		fields = (&KomputerPH1Doc{}).Summary().Fields
+	case _const.KomputerPH2:
+		fields = (&KomputerPH2Doc{}).Summary().Fields
+	case _const.Printer:
+		fields = (&PrinterDoc{}).Summary().Fields
+	case _const.Telepon:
+		fields = (&TeleponDoc{}).Summary().Fields
+	case _const.Toa:
+		fields = (&TOADoc{}).Summary().Fields
+	case _const.Ups:
+		fields = (&UPSDoc{}).Summary().Fields
+	}
+
+	labels := make([]string, len(fields))
+	for i, f := range fields {
+		labels[i] = f.Label
+	}
+	return labels
+}
+
+// UpdateStatus applies update to the doc only while it is still in status
+// from, returning mongo.ErrNoDocuments when it was changed concurrently.
+func (r *DocRepository) UpdateStatus(device string, id bson.ObjectID, from string, update bson.M) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+
+	filter := bson.M{
+		"_id":        id,
+		"status":     from,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["modified_at"] = doc.Stamp()
+
+	res, err := coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// InitStatus sets status on docs created before the approval workflow,
+// using the insert date as the submit date.
+func (r *DocRepository) InitStatus(device, status string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+
+	filter := bson.M{
+		"status": bson.M{"$exists": false},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status":      status,
+			"submitted":   "$inserted",
+			"modified_at": doc.Stamp(),
+		}}},
+	}
+
+	res, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// FindAllModifiedSince returns the docs of the type written at or after
+// since, or all of them when since is zero.
+func (r *DocRepository) FindAllModifiedSince(device string, since time.Time) (interface{}, error) {
+	switch device {
+	case _const.CCTV:
+		return r.cctv.FindAllModifiedSince(since)
+	case _const.Fingerprint:
+		return r.fingerprint.FindAllModifiedSince(since)
+	case _const.KomputerPH1:
+		return r.kph1.FindAllModifiedSince(since)
+	case _const.KomputerPH2:
+		return r.kph2.FindAllModifiedSince(since)
+	case _const.Printer:
+		return r.printer.FindAllModifiedSince(since)
+	case _const.Telepon:
+		return r.telepon.FindAllModifiedSince(since)
+	case _const.Toa:
+		return r.toa.FindAllModifiedSince(since)
+	case _const.Ups:
+		return r.ups.FindAllModifiedSince(since)
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// FindDeletedSince returns the ids of the docs of the type deleted at or
+// after since.
+func (r *DocRepository) FindDeletedSince(device string, since time.Time) ([]bson.ObjectID, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindDeletedSince(coll, since)
+}
+
+// FindSyncState returns mongo.ErrNoDocuments when the doc was never created.
+func (r *DocRepository) FindSyncState(device string, id bson.ObjectID) (*DocSyncState, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	var state DocSyncState
+	filter := bson.M{
+		"_id": id,
+	}
+
+	err := coll.FindOne(context.TODO(), filter).Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// InitModifiedAt stamps the docs of the type written before modified_at was
+// tracked.
+func (r *DocRepository) InitModifiedAt(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+	return doc.InitModifiedAt(coll)
+}
+
+// FindTrash returns the soft deleted docs of the type.
+func (r *DocRepository) FindTrash(device string) ([]doc.TrashRecord, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindTrash(coll, "nama", "user")
+}
+
+// FindTrashedBefore returns the ids of the docs of the type deleted before the
+// given date.
+func (r *DocRepository) FindTrashedBefore(device string, before time.Time) ([]bson.ObjectID, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return doc.FindTrashedBefore(coll, before)
+}
+
+// InitDeletedAt dates the docs of the type deleted before the deleter was
+// tracked as deleted now.
+func (r *DocRepository) InitDeletedAt(device string) (int64, error) {
+	coll := r.coll(device)
+	if coll == nil {
+		return 0, nil
+	}
+	return doc.InitDeletedAt(coll)
+}
+
+func (r *DocRepository) Restore(device string, id bson.ObjectID) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+	return doc.Restore(coll, id)
+}
+
+func (r *DocRepository) Purge(device string, id bson.ObjectID) error {
+	coll := r.coll(device)
+	if coll == nil {
+		return mongo.ErrNoDocuments
+	}
+	return doc.Purge(coll, id)
+}
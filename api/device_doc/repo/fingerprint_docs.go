@@ -4,19 +4,54 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type FingerprintDoc struct {
-	ID         bson.ObjectID  `json:"_id" bson:"_id"`
-	Nama       string         `json:"nama" bson:"nama"`
-	Lokasi     string         `json:"lokasi" bson:"lokasi"`
-	Kode       string         `json:"kode" bson:"kode"`
-	Checkpoint []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
-	Inserted   doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated    *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted  bool           `json:"-" bson:"is_deleted"`
+	ID                bson.ObjectID  `json:"_id" bson:"_id"`
+	DeviceID          bson.ObjectID  `json:"device_id" bson:"device_id"`
+	Device            string         `json:"device" bson:"device"`
+	Nama              string         `json:"nama" bson:"nama"`
+	Lokasi            string         `json:"lokasi" bson:"lokasi"`
+	Kode              string         `json:"kode" bson:"kode"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version" bson:"checkpoint_version"`
+	Status            string         `json:"status" bson:"status"`
+	Submitted         *doc.ByAt      `json:"submitted,omitempty" bson:"submitted,omitempty"`
+	Reviewed          *doc.ByAt      `json:"reviewed,omitempty" bson:"reviewed,omitempty"`
+	RejectReason      string         `json:"reject_reason,omitempty" bson:"reject_reason,omitempty"`
+	Inserted          doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated           *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted           *doc.ByAt      `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt        time.Time      `json:"modified_at" bson:"modified_at"`
+	IsDeleted         bool           `json:"-" bson:"is_deleted"`
+}
+
+// Summary returns the type-independent view of the doc.
+func (d *FingerprintDoc) Summary() *DeviceDoc {
+	return &DeviceDoc{
+		ID:       d.ID,
+		Device:   _const.Fingerprint,
+		DeviceID: d.DeviceID,
+		Fields: []DocField{
+			{Label: "Nama", Value: d.Nama},
+			{Label: "Lokasi", Value: d.Lokasi},
+			{Label: "Kode", Value: d.Kode},
+		},
+		Checkpoint:        d.Checkpoint,
+		CheckpointVersion: d.CheckpointVersion,
+		Status:            d.Status,
+		Submitted:         d.Submitted,
+		Reviewed:          d.Reviewed,
+		RejectReason:      d.RejectReason,
+		Inserted:          d.Inserted,
+		Updated:           d.Updated,
+		ModifiedAt:        d.ModifiedAt,
+	}
 }
 
 type FingerprintDocCollRepository struct {
@@ -29,18 +64,11 @@ func NewFingerprintDocRepository(db *mongo.Database) *FingerprintDocCollReposito
 	}
 }
 
-func (r *FingerprintDocCollRepository) FindAll(cq *util.CommonQuery) (*[]FingerprintDoc, error) {
+func (r *FingerprintDocCollRepository) FindAll(dq *doc.DocQuery) (*[]FingerprintDoc, error) {
 	var fpDocs []FingerprintDoc
-	filter := bson.M{
-		"is_deleted": bson.M{"$ne": true},
-	}
-
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
-	}
+	filter := dq.Filter("nama")
 
-	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
+	findOptions, err := dq.FindOptions()
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +104,7 @@ func (r *FingerprintDocCollRepository) FindOneByID(id bson.ObjectID) (*Fingerpri
 }
 
 func (r *FingerprintDocCollRepository) InsertOne(fpDoc *FingerprintDoc) error {
+	fpDoc.ModifiedAt = doc.Stamp()
 	_, err := r.coll.InsertOne(context.TODO(), fpDoc)
 	if err != nil {
 		return err
@@ -83,31 +112,153 @@ func (r *FingerprintDocCollRepository) InsertOne(fpDoc *FingerprintDoc) error {
 	return nil
 }
 
-func (r *FingerprintDocCollRepository) UpdateOneByID(id bson.ObjectID, fpDoc *FingerprintDoc) error {
+// InsertMany inserts all docs or none, removing the inserted ones when the
+// insert fails part way.
+func (r *FingerprintDocCollRepository) InsertMany(fpDocs []FingerprintDoc) error {
+	stamp := doc.Stamp()
+	for i := range fpDocs {
+		fpDocs[i].ModifiedAt = stamp
+	}
+
+	_, err := r.coll.InsertMany(context.TODO(), fpDocs)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(fpDocs))
+		for i := range fpDocs {
+			ids[i] = fpDocs[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
+	return nil
+}
+
+func (r *FingerprintDocCollRepository) CountQuery(dq *doc.DocQuery) (int64, error) {
+	filter := dq.Filter("nama")
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Each calls fn with the summary of every doc matching dq, read one at a time
+// from a cursor.
+func (r *FingerprintDocCollRepository) Each(dq *doc.DocQuery, fn func(*DeviceDoc) error) error {
+	filter := dq.Filter("nama")
+
+	cur, err := r.coll.Find(context.TODO(), filter, dq.ExportOptions())
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+
+	for cur.Next(context.TODO()) {
+		var fpDoc FingerprintDoc
+		if err = cur.Decode(&fpDoc); err != nil {
+			return err
+		}
+		if err = fn(fpDoc.Summary()); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// DeleteOneByID soft deletes the doc unless it is submitted or approved,
+// returning mongo.ErrNoDocuments when it is, or is gone.
+func (r *FingerprintDocCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
-		"_id":        id,
-		"is_deleted": bson.M{"$ne": true},
+		"_id":    id,
+		"status": bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": fpDoc,
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
-func (r *FingerprintDocCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+func (r *FingerprintDocCollRepository) FindAllByDeviceID(deviceID bson.ObjectID, cq *util.CommonQuery) (*[]FingerprintDoc, error) {
+	var fpDocs []FingerprintDoc
 	filter := bson.M{
+		"device_id":  deviceID,
 		"is_deleted": bson.M{"$ne": true},
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"inserted.at": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &fpDocs)
+	if err != nil {
+		return nil, err
+	}
+	if fpDocs == nil {
+		return &[]FingerprintDoc{}, nil
+	}
+	return &fpDocs, nil
+}
+
+func (r *FingerprintDocCollRepository) CountByDeviceID(deviceID bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"device_id":  deviceID,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LinkDevice sets the device reference on docs created before it was stored,
+// matching them by the snapshotted identifying fields in match.
+func (r *FingerprintDocCollRepository) LinkDevice(deviceID bson.ObjectID, match bson.M) (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
+	}
+	for k, v := range match {
+		filter[k] = v
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"device_id":   deviceID,
+			"device":      _const.Fingerprint,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *FingerprintDocCollRepository) CountUnlinked() (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -117,18 +268,78 @@ func (r *FingerprintDocCollRepository) CountQuery(cq *util.CommonQuery) (int64,
 	return count, nil
 }
 
-func (r *FingerprintDocCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *FingerprintDocCollRepository) FindAllByPeriod(from, to time.Time) (*[]FingerprintDoc, error) {
+	var fpDocs []FingerprintDoc
+	filter := bson.M{
+		"inserted.at": bson.M{"$gte": from, "$lt": to},
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      countedStatus,
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"inserted.at": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &fpDocs)
+	if err != nil {
+		return nil, err
+	}
+	if fpDocs == nil {
+		return &[]FingerprintDoc{}, nil
+	}
+	return &fpDocs, nil
+}
+
+// FindAllModifiedSince returns the docs written at or after since, or all of
+// them when since is zero.
+func (r *FingerprintDocCollRepository) FindAllModifiedSince(since time.Time) (*[]FingerprintDoc, error) {
+	var fpDocs []FingerprintDoc
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &fpDocs)
+	if err != nil {
+		return nil, err
+	}
+	if fpDocs == nil {
+		return &[]FingerprintDoc{}, nil
+	}
+	return &fpDocs, nil
+}
+
+// UpdateOneIfUnmodified updates the doc only while its modified_at is still
+// base, returning mongo.ErrNoDocuments when it was written since.
+func (r *FingerprintDocCollRepository) UpdateOneIfUnmodified(id bson.ObjectID, base time.Time, fpDoc *FingerprintDoc) error {
+	fpDoc.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
-		"_id": id,
+		"_id":         id,
+		"modified_at": base,
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": fpDoc,
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
@@ -4,24 +4,64 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type KomputerPH2Doc struct {
-	ID         bson.ObjectID  `json:"_id" bson:"_id"`
-	Nama       string         `json:"nama" bson:"nama"`
-	Merk       string         `json:"merk" bson:"merk"`
-	PC         string         `json:"pc" bson:"pc"`
-	Monitor    string         `json:"monitor" bson:"monitor"`
-	CPU        string         `json:"cpu" bson:"cpu"`
-	RAM        string         `json:"ram" bson:"ram"`
-	Internal   string         `json:"internal" bson:"internal"`
-	Lokasi     string         `json:"lokasi" bson:"lokasi"`
-	Checkpoint []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
-	Inserted   doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated    *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted  bool           `json:"-" bson:"is_deleted"`
+	ID                bson.ObjectID  `json:"_id" bson:"_id"`
+	DeviceID          bson.ObjectID  `json:"device_id" bson:"device_id"`
+	Device            string         `json:"device" bson:"device"`
+	Nama              string         `json:"nama" bson:"nama"`
+	Merk              string         `json:"merk" bson:"merk"`
+	PC                string         `json:"pc" bson:"pc"`
+	Monitor           string         `json:"monitor" bson:"monitor"`
+	CPU               string         `json:"cpu" bson:"cpu"`
+	RAM               string         `json:"ram" bson:"ram"`
+	Internal          string         `json:"internal" bson:"internal"`
+	Lokasi            string         `json:"lokasi" bson:"lokasi"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version" bson:"checkpoint_version"`
+	Status            string         `json:"status" bson:"status"`
+	Submitted         *doc.ByAt      `json:"submitted,omitempty" bson:"submitted,omitempty"`
+	Reviewed          *doc.ByAt      `json:"reviewed,omitempty" bson:"reviewed,omitempty"`
+	RejectReason      string         `json:"reject_reason,omitempty" bson:"reject_reason,omitempty"`
+	Inserted          doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated           *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted           *doc.ByAt      `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt        time.Time      `json:"modified_at" bson:"modified_at"`
+	IsDeleted         bool           `json:"-" bson:"is_deleted"`
+}
+
+// Summary returns the type-independent view of the doc.
+func (d *KomputerPH2Doc) Summary() *DeviceDoc {
+	return &DeviceDoc{
+		ID:       d.ID,
+		Device:   _const.KomputerPH2,
+		DeviceID: d.DeviceID,
+		Fields: []DocField{
+			{Label: "Nama", Value: d.Nama},
+			{Label: "Merk", Value: d.Merk},
+			{Label: "PC", Value: d.PC},
+			{Label: "Monitor", Value: d.Monitor},
+			{Label: "CPU", Value: d.CPU},
+			{Label: "RAM", Value: d.RAM},
+			{Label: "Internal", Value: d.Internal},
+			{Label: "Lokasi", Value: d.Lokasi},
+		},
+		Checkpoint:        d.Checkpoint,
+		CheckpointVersion: d.CheckpointVersion,
+		Status:            d.Status,
+		Submitted:         d.Submitted,
+		Reviewed:          d.Reviewed,
+		RejectReason:      d.RejectReason,
+		Inserted:          d.Inserted,
+		Updated:           d.Updated,
+		ModifiedAt:        d.ModifiedAt,
+	}
 }
 
 type KomputerPH2DocCollRepository struct {
@@ -34,18 +74,11 @@ func NewKomputerPH2DocRepository(db *mongo.Database) *KomputerPH2DocCollReposito
 	}
 }
 
-func (r *KomputerPH2DocCollRepository) FindAll(cq *util.CommonQuery) (*[]KomputerPH2Doc, error) {
+func (r *KomputerPH2DocCollRepository) FindAll(dq *doc.DocQuery) (*[]KomputerPH2Doc, error) {
 	var kph2Doc []KomputerPH2Doc
-	filter := bson.M{
-		"is_deleted": bson.M{"$ne": true},
-	}
-
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
-	}
+	filter := dq.Filter("nama")
 
-	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
+	findOptions, err := dq.FindOptions()
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +114,7 @@ func (r *KomputerPH2DocCollRepository) FindOneByID(id bson.ObjectID) (*KomputerP
 }
 
 func (r *KomputerPH2DocCollRepository) InsertOne(kph2Doc *KomputerPH2Doc) error {
+	kph2Doc.ModifiedAt = doc.Stamp()
 	_, err := r.coll.InsertOne(context.TODO(), kph2Doc)
 	if err != nil {
 		return err
@@ -88,31 +122,153 @@ func (r *KomputerPH2DocCollRepository) InsertOne(kph2Doc *KomputerPH2Doc) error
 	return nil
 }
 
-func (r *KomputerPH2DocCollRepository) UpdateOneByID(id bson.ObjectID, kph2Doc *KomputerPH2Doc) error {
+// InsertMany inserts all docs or none, removing the inserted ones when the
+// insert fails part way.
+func (r *KomputerPH2DocCollRepository) InsertMany(kph2Docs []KomputerPH2Doc) error {
+	stamp := doc.Stamp()
+	for i := range kph2Docs {
+		kph2Docs[i].ModifiedAt = stamp
+	}
+
+	_, err := r.coll.InsertMany(context.TODO(), kph2Docs)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(kph2Docs))
+		for i := range kph2Docs {
+			ids[i] = kph2Docs[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
+	return nil
+}
+
+func (r *KomputerPH2DocCollRepository) CountQuery(dq *doc.DocQuery) (int64, error) {
+	filter := dq.Filter("nama")
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Each calls fn with the summary of every doc matching dq, read one at a time
+// from a cursor.
+func (r *KomputerPH2DocCollRepository) Each(dq *doc.DocQuery, fn func(*DeviceDoc) error) error {
+	filter := dq.Filter("nama")
+
+	cur, err := r.coll.Find(context.TODO(), filter, dq.ExportOptions())
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+
+	for cur.Next(context.TODO()) {
+		var kph2Doc KomputerPH2Doc
+		if err = cur.Decode(&kph2Doc); err != nil {
+			return err
+		}
+		if err = fn(kph2Doc.Summary()); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// DeleteOneByID soft deletes the doc unless it is submitted or approved,
+// returning mongo.ErrNoDocuments when it is, or is gone.
+func (r *KomputerPH2DocCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
-		"_id":        id,
-		"is_deleted": bson.M{"$ne": true},
+		"_id":    id,
+		"status": bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": kph2Doc,
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
-func (r *KomputerPH2DocCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+func (r *KomputerPH2DocCollRepository) FindAllByDeviceID(deviceID bson.ObjectID, cq *util.CommonQuery) (*[]KomputerPH2Doc, error) {
+	var kph2Docs []KomputerPH2Doc
+	filter := bson.M{
+		"device_id":  deviceID,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"inserted.at": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &kph2Docs)
+	if err != nil {
+		return nil, err
+	}
+	if kph2Docs == nil {
+		return &[]KomputerPH2Doc{}, nil
+	}
+	return &kph2Docs, nil
+}
+
+func (r *KomputerPH2DocCollRepository) CountByDeviceID(deviceID bson.ObjectID) (int64, error) {
 	filter := bson.M{
+		"device_id":  deviceID,
 		"is_deleted": bson.M{"$ne": true},
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["nama"] = bson.M{"$regex": pattern}
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LinkDevice sets the device reference on docs created before it was stored,
+// matching them by the snapshotted identifying fields in match.
+func (r *KomputerPH2DocCollRepository) LinkDevice(deviceID bson.ObjectID, match bson.M) (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
+	}
+	for k, v := range match {
+		filter[k] = v
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"device_id":   deviceID,
+			"device":      _const.KomputerPH2,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *KomputerPH2DocCollRepository) CountUnlinked() (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -122,18 +278,78 @@ func (r *KomputerPH2DocCollRepository) CountQuery(cq *util.CommonQuery) (int64,
 	return count, nil
 }
 
-func (r *KomputerPH2DocCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *KomputerPH2DocCollRepository) FindAllByPeriod(from, to time.Time) (*[]KomputerPH2Doc, error) {
+	var kph2Docs []KomputerPH2Doc
+	filter := bson.M{
+		"inserted.at": bson.M{"$gte": from, "$lt": to},
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      countedStatus,
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"inserted.at": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &kph2Docs)
+	if err != nil {
+		return nil, err
+	}
+	if kph2Docs == nil {
+		return &[]KomputerPH2Doc{}, nil
+	}
+	return &kph2Docs, nil
+}
+
+// FindAllModifiedSince returns the docs written at or after since, or all of
+// them when since is zero.
+func (r *KomputerPH2DocCollRepository) FindAllModifiedSince(since time.Time) (*[]KomputerPH2Doc, error) {
+	var kph2Docs []KomputerPH2Doc
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &kph2Docs)
+	if err != nil {
+		return nil, err
+	}
+	if kph2Docs == nil {
+		return &[]KomputerPH2Doc{}, nil
+	}
+	return &kph2Docs, nil
+}
+
+// UpdateOneIfUnmodified updates the doc only while its modified_at is still
+// base, returning mongo.ErrNoDocuments when it was written since.
+func (r *KomputerPH2DocCollRepository) UpdateOneIfUnmodified(id bson.ObjectID, base time.Time, kph2Doc *KomputerPH2Doc) error {
+	kph2Doc.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
-		"_id": id,
+		"_id":         id,
+		"modified_at": base,
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": kph2Doc,
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
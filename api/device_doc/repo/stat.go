@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"regexp"
+	"sipamit-be/internal/pkg/const"
+	"time"
+)
+
+// countedStatus matches the docs the stats count: submitted and approved
+// ones, and legacy docs recorded before docs had a status, which are taken as
+// approved. Drafts and rejected docs are left out.
+var countedStatus = bson.M{"$in": bson.A{nil, "", _const.DocSubmitted, _const.DocApproved}}
+
+type lastDoc struct {
+	DeviceID bson.ObjectID `bson:"_id"`
+	At       time.Time     `bson:"at"`
+}
+
+// lastDocDates returns the date of the latest counted doc of every device in coll.
+func lastDocDates(coll *mongo.Collection) (map[bson.ObjectID]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"device_id":  bson.M{"$exists": true},
+			"is_deleted": bson.M{"$ne": true},
+			"status":     countedStatus,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$device_id",
+			"at":  bson.M{"$max": "$inserted.at"},
+		}}},
+	}
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var docs []lastDoc
+	err = cur.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make(map[bson.ObjectID]time.Time, len(docs))
+	for _, d := range docs {
+		dates[d.DeviceID] = d.At
+	}
+	return dates, nil
+}
+
+// DocStat counts the docs of a device and the checkpoints recorded in them.
+type DocStat struct {
+	DeviceID    bson.ObjectID `json:"-" bson:"_id"`
+	Docs        int64         `json:"docs" bson:"docs"`
+	Checkpoints int64         `json:"checkpoints" bson:"checkpoints"`
+	Failed      int64         `json:"failed" bson:"failed"`
+}
+
+// docStats returns the DocStat of every device in coll maintained within
+// [from, to).
+func docStats(coll *mongo.Collection, from, to time.Time) (map[bson.ObjectID]DocStat, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"device_id":   bson.M{"$exists": true},
+			"is_deleted":  bson.M{"$ne": true},
+			"status":      countedStatus,
+			"inserted.at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$device_id",
+			"docs":        bson.M{"$sum": 1},
+			"checkpoints": bson.M{"$sum": bson.M{"$size": "$checkpoint"}},
+			"failed": bson.M{"$sum": bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": "$checkpoint",
+				"cond":  bson.M{"$eq": bson.A{"$$this.ok", false}},
+			}}}},
+		}}},
+	}
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var stats []DocStat
+	err = cur.All(context.TODO(), &stats)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[bson.ObjectID]DocStat, len(stats))
+	for _, s := range stats {
+		result[s.DeviceID] = s
+	}
+	return result, nil
+}
+
+// CheckpointMonthStat counts the results of a checkpoint recorded in one
+// month, Month being formatted as YYYY-MM.
+type CheckpointMonthStat struct {
+	Checkpoint string `json:"checkpoint" bson:"checkpoint"`
+	Month      string `json:"month" bson:"month"`
+	Total      int64  `json:"total" bson:"total"`
+	Failed     int64  `json:"failed" bson:"failed"`
+}
+
+// checkpointMonthStats returns the CheckpointMonthStat of every checkpoint in
+// coll recorded within [from, to), or only of the named checkpoint. Months
+// are taken in the time zone of from.
+func checkpointMonthStats(coll *mongo.Collection, from, to time.Time, checkpoint string) ([]CheckpointMonthStat, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"is_deleted":  bson.M{"$ne": true},
+			"status":      countedStatus,
+			"inserted.at": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$unwind", Value: "$checkpoint"}},
+	}
+	if checkpoint != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{
+			"checkpoint.name": bson.Regex{Pattern: "^" + regexp.QuoteMeta(checkpoint) + "$", Options: "i"},
+		}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"checkpoint": "$checkpoint.name",
+				"month": bson.M{"$dateToString": bson.M{
+					"format":   "%Y-%m",
+					"date":     "$inserted.at",
+					"timezone": from.Format("-07:00"),
+				}},
+			},
+			"total": bson.M{"$sum": 1},
+			"failed": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$checkpoint.ok", false}}, 1, 0,
+			}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"checkpoint": "$_id.checkpoint",
+			"month":      "$_id.month",
+			"total":      1,
+			"failed":     1,
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "checkpoint", Value: 1}, {Key: "month", Value: 1}}}},
+	)
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var stats []CheckpointMonthStat
+	err = cur.All(context.TODO(), &stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// DeviceFailureStat counts the failed checkpoint results of a device.
+type DeviceFailureStat struct {
+	DeviceID    bson.ObjectID `json:"device_id" bson:"_id"`
+	Docs        int64         `json:"docs" bson:"docs"`
+	Failed      int64         `json:"failed" bson:"failed"`
+	Checkpoints []string      `json:"checkpoints" bson:"checkpoints"`
+	LastFailed  time.Time     `json:"last_failed" bson:"last_failed"`
+}
+
+// deviceFailureStats returns the limit devices in coll with the most failed
+// checkpoint results recorded within [from, to), most failures first.
+func deviceFailureStats(coll *mongo.Collection, from, to time.Time, limit int64) ([]DeviceFailureStat, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"device_id":     bson.M{"$exists": true},
+			"is_deleted":    bson.M{"$ne": true},
+			"status":        countedStatus,
+			"inserted.at":   bson.M{"$gte": from, "$lt": to},
+			"checkpoint.ok": false,
+		}}},
+		{{Key: "$unwind", Value: "$checkpoint"}},
+		{{Key: "$match", Value: bson.M{"checkpoint.ok": false}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$device_id",
+			"docs":        bson.M{"$addToSet": "$_id"},
+			"failed":      bson.M{"$sum": 1},
+			"checkpoints": bson.M{"$addToSet": "$checkpoint.name"},
+			"last_failed": bson.M{"$max": "$inserted.at"},
+		}}},
+		{{Key: "$set", Value: bson.M{"docs": bson.M{"$size": "$docs"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "failed", Value: -1}, {Key: "last_failed", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var stats []DeviceFailureStat
+	err = cur.All(context.TODO(), &stats)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
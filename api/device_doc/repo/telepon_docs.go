@@ -4,22 +4,60 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/doc"
 	"sipamit-be/internal/pkg/util"
+	"time"
 )
 
 type TeleponDoc struct {
-	ID         bson.ObjectID  `json:"_id" bson:"_id"`
-	Lokasi     string         `json:"lokasi" bson:"lokasi"`
-	Departemen string         `json:"departemen" bson:"departemen"`
-	User       string         `json:"user" bson:"user"`
-	Ext        string         `json:"ext" bson:"ext"`
-	Merk       string         `json:"merk" bson:"merk"`
-	Tipe       string         `json:"tipe" bson:"tipe"`
-	Checkpoint []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
-	Inserted   doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
-	Updated    *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
-	IsDeleted  bool           `json:"-" bson:"is_deleted"`
+	ID                bson.ObjectID  `json:"_id" bson:"_id"`
+	DeviceID          bson.ObjectID  `json:"device_id" bson:"device_id"`
+	Device            string         `json:"device" bson:"device"`
+	Lokasi            string         `json:"lokasi" bson:"lokasi"`
+	Departemen        string         `json:"departemen" bson:"departemen"`
+	User              string         `json:"user" bson:"user"`
+	Ext               string         `json:"ext" bson:"ext"`
+	Merk              string         `json:"merk" bson:"merk"`
+	Tipe              string         `json:"tipe" bson:"tipe"`
+	Checkpoint        []doc.CPDetail `json:"checkpoint" bson:"checkpoint"`
+	CheckpointVersion int            `json:"checkpoint_version" bson:"checkpoint_version"`
+	Status            string         `json:"status" bson:"status"`
+	Submitted         *doc.ByAt      `json:"submitted,omitempty" bson:"submitted,omitempty"`
+	Reviewed          *doc.ByAt      `json:"reviewed,omitempty" bson:"reviewed,omitempty"`
+	RejectReason      string         `json:"reject_reason,omitempty" bson:"reject_reason,omitempty"`
+	Inserted          doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated           *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted           *doc.ByAt      `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt        time.Time      `json:"modified_at" bson:"modified_at"`
+	IsDeleted         bool           `json:"-" bson:"is_deleted"`
+}
+
+// Summary returns the type-independent view of the doc.
+func (d *TeleponDoc) Summary() *DeviceDoc {
+	return &DeviceDoc{
+		ID:       d.ID,
+		Device:   _const.Telepon,
+		DeviceID: d.DeviceID,
+		Fields: []DocField{
+			{Label: "Lokasi", Value: d.Lokasi},
+			{Label: "Departemen", Value: d.Departemen},
+			{Label: "User", Value: d.User},
+			{Label: "Ext", Value: d.Ext},
+			{Label: "Merk", Value: d.Merk},
+			{Label: "Tipe", Value: d.Tipe},
+		},
+		Checkpoint:        d.Checkpoint,
+		CheckpointVersion: d.CheckpointVersion,
+		Status:            d.Status,
+		Submitted:         d.Submitted,
+		Reviewed:          d.Reviewed,
+		RejectReason:      d.RejectReason,
+		Inserted:          d.Inserted,
+		Updated:           d.Updated,
+		ModifiedAt:        d.ModifiedAt,
+	}
 }
 
 type TeleponDocCollRepository struct {
@@ -32,18 +70,11 @@ func NewTeleponDocRepository(db *mongo.Database) *TeleponDocCollRepository {
 	}
 }
 
-func (r *TeleponDocCollRepository) FindAll(cq *util.CommonQuery) (*[]TeleponDoc, error) {
+func (r *TeleponDocCollRepository) FindAll(dq *doc.DocQuery) (*[]TeleponDoc, error) {
 	var teleponDocs []TeleponDoc
-	filter := bson.M{
-		"is_deleted": bson.M{"$ne": true},
-	}
-
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["tipe"] = bson.M{"$regex": pattern}
-	}
+	filter := dq.Filter("tipe")
 
-	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
+	findOptions, err := dq.FindOptions()
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +110,7 @@ func (r *TeleponDocCollRepository) FindOneByID(id bson.ObjectID) (*TeleponDoc, e
 }
 
 func (r *TeleponDocCollRepository) InsertOne(teleponDoc *TeleponDoc) error {
+	teleponDoc.ModifiedAt = doc.Stamp()
 	_, err := r.coll.InsertOne(context.TODO(), teleponDoc)
 	if err != nil {
 		return err
@@ -86,31 +118,153 @@ func (r *TeleponDocCollRepository) InsertOne(teleponDoc *TeleponDoc) error {
 	return nil
 }
 
-func (r *TeleponDocCollRepository) UpdateOneByID(id bson.ObjectID, teleponDoc *TeleponDoc) error {
+// InsertMany inserts all docs or none, removing the inserted ones when the
+// insert fails part way.
+func (r *TeleponDocCollRepository) InsertMany(teleponDocs []TeleponDoc) error {
+	stamp := doc.Stamp()
+	for i := range teleponDocs {
+		teleponDocs[i].ModifiedAt = stamp
+	}
+
+	_, err := r.coll.InsertMany(context.TODO(), teleponDocs)
+	if err != nil {
+		ids := make([]bson.ObjectID, len(teleponDocs))
+		for i := range teleponDocs {
+			ids[i] = teleponDocs[i].ID
+		}
+		return doc.UndoInsertMany(r.coll, ids, err)
+	}
+	return nil
+}
+
+func (r *TeleponDocCollRepository) CountQuery(dq *doc.DocQuery) (int64, error) {
+	filter := dq.Filter("tipe")
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Each calls fn with the summary of every doc matching dq, read one at a time
+// from a cursor.
+func (r *TeleponDocCollRepository) Each(dq *doc.DocQuery, fn func(*DeviceDoc) error) error {
+	filter := dq.Filter("tipe")
+
+	cur, err := r.coll.Find(context.TODO(), filter, dq.ExportOptions())
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.TODO())
+
+	for cur.Next(context.TODO()) {
+		var teleponDoc TeleponDoc
+		if err = cur.Decode(&teleponDoc); err != nil {
+			return err
+		}
+		if err = fn(teleponDoc.Summary()); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// DeleteOneByID soft deletes the doc unless it is submitted or approved,
+// returning mongo.ErrNoDocuments when it is, or is gone.
+func (r *TeleponDocCollRepository) DeleteOneByID(id bson.ObjectID, deleted doc.ByAt) error {
 	filter := bson.M{
-		"_id":        id,
-		"is_deleted": bson.M{"$ne": true},
+		"_id":    id,
+		"status": bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": teleponDoc,
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
-func (r *TeleponDocCollRepository) CountQuery(cq *util.CommonQuery) (int64, error) {
+func (r *TeleponDocCollRepository) FindAllByDeviceID(deviceID bson.ObjectID, cq *util.CommonQuery) (*[]TeleponDoc, error) {
+	var teleponDocs []TeleponDoc
 	filter := bson.M{
+		"device_id":  deviceID,
 		"is_deleted": bson.M{"$ne": true},
 	}
 
-	if len(cq.Q) > 0 {
-		var pattern = bson.Regex{Pattern: cq.Q, Options: "i"}
-		filter["tipe"] = bson.M{"$regex": pattern}
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"inserted.at": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &teleponDocs)
+	if err != nil {
+		return nil, err
+	}
+	if teleponDocs == nil {
+		return &[]TeleponDoc{}, nil
+	}
+	return &teleponDocs, nil
+}
+
+func (r *TeleponDocCollRepository) CountByDeviceID(deviceID bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"device_id":  deviceID,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// LinkDevice sets the device reference on docs created before it was stored,
+// matching them by the snapshotted identifying fields in match.
+func (r *TeleponDocCollRepository) LinkDevice(deviceID bson.ObjectID, match bson.M) (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
+	}
+	for k, v := range match {
+		filter[k] = v
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"device_id":   deviceID,
+			"device":      _const.Telepon,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *TeleponDocCollRepository) CountUnlinked() (int64, error) {
+	filter := bson.M{
+		"device_id": bson.M{"$exists": false},
 	}
 
 	count, err := r.coll.CountDocuments(context.TODO(), filter)
@@ -120,18 +274,78 @@ func (r *TeleponDocCollRepository) CountQuery(cq *util.CommonQuery) (int64, erro
 	return count, nil
 }
 
-func (r *TeleponDocCollRepository) DeleteOneByID(id bson.ObjectID) error {
+func (r *TeleponDocCollRepository) FindAllByPeriod(from, to time.Time) (*[]TeleponDoc, error) {
+	var teleponDocs []TeleponDoc
+	filter := bson.M{
+		"inserted.at": bson.M{"$gte": from, "$lt": to},
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      countedStatus,
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"inserted.at": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &teleponDocs)
+	if err != nil {
+		return nil, err
+	}
+	if teleponDocs == nil {
+		return &[]TeleponDoc{}, nil
+	}
+	return &teleponDocs, nil
+}
+
+// FindAllModifiedSince returns the docs written at or after since, or all of
+// them when since is zero.
+func (r *TeleponDocCollRepository) FindAllModifiedSince(since time.Time) (*[]TeleponDoc, error) {
+	var teleponDocs []TeleponDoc
+	filter := doc.ModifiedSince(since)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &teleponDocs)
+	if err != nil {
+		return nil, err
+	}
+	if teleponDocs == nil {
+		return &[]TeleponDoc{}, nil
+	}
+	return &teleponDocs, nil
+}
+
+// UpdateOneIfUnmodified updates the doc only while its modified_at is still
+// base, returning mongo.ErrNoDocuments when it was written since.
+func (r *TeleponDocCollRepository) UpdateOneIfUnmodified(id bson.ObjectID, base time.Time, teleponDoc *TeleponDoc) error {
+	teleponDoc.ModifiedAt = doc.Stamp()
+
 	filter := bson.M{
-		"_id": id,
+		"_id":         id,
+		"modified_at": base,
+		"is_deleted":  bson.M{"$ne": true},
+		"status":      bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
 	}
 
 	update := bson.M{
-		"$set": bson.M{"is_deleted": true},
+		"$set": teleponDoc,
 	}
 
-	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
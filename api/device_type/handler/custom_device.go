@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"sipamit-be/api/device_type/repo"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+)
+
+type customDeviceForm struct {
+	Fields map[string]interface{} `json:"fields" form:"fields"`
+}
+
+func newCustomDeviceForm(c echo.Context) (*customDeviceForm, error) {
+	f := new(customDeviceForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind device form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+	return f, nil
+}
+
+type CustomDeviceHandler struct {
+	typeRepo   *repo.DeviceTypeCollRepository
+	deviceRepo *repo.CustomDeviceCollRepository
+}
+
+func NewCustomDeviceAPIHandler(e *echo.Echo, db *mongo.Database) *CustomDeviceHandler {
+	h := &CustomDeviceHandler{
+		typeRepo:   repo.NewDeviceTypeRepository(db),
+		deviceRepo: repo.NewCustomDeviceRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/devices/:type", h.findAll)
+	group.GET("/devices/:type/:id", h.findOne)
+
+	group.POST("/devices/:type", h.create)
+
+	group.PUT("/devices/:type/:id", h.update)
+
+	group.DELETE("/devices/:type/:id", h.delete)
+
+	return h
+}
+
+// findAll
+// @Tags Device Custom
+// @Summary Get all devices of a custom device type
+// @ID get-all-custom-devices
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param q query string false "Search the searchable fields"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Router /api/devices/{type} [GET]
+// @Produce json
+// @Success 200
+func (h *CustomDeviceHandler) findAll(c echo.Context) error {
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	cq := util.NewCommonQuery(c)
+
+	devices, err := h.deviceRepo.FindAll(deviceType, cq)
+	if err != nil {
+		log.Errorf("Failed to get %s devices: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	total, err := h.deviceRepo.CountQuery(deviceType, cq)
+	if err != nil {
+		log.Errorf("Failed to count %s devices: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(devices, total, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// findOne
+// @Tags Device Custom
+// @Summary Get a device of a custom device type
+// @ID get-custom-device
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Device ID"
+// @Router /api/devices/{type}/{id} [GET]
+// @Produce json
+// @Success 200
+func (h *CustomDeviceHandler) findOne(c echo.Context) error {
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	device, err := h.findDevice(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, device)
+}
+
+// create
+// @Tags Device Custom
+// @Summary Create a device of a custom device type, validated against its schema
+// @ID create-custom-device
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param body body customDeviceForm true "Device Form"
+// @Router /api/devices/{type} [POST]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *CustomDeviceHandler) create(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	f, err := newCustomDeviceForm(c)
+	if err != nil {
+		return err
+	}
+
+	fields, err := deviceType.Validate(f.Fields)
+	if err != nil {
+		return err
+	}
+
+	device := &repo.CustomDevice{
+		ID:        bson.NewObjectID(),
+		Type:      deviceType.Slug,
+		Fields:    fields,
+		Inserted:  nc.Claims.ByAt(),
+		IsDeleted: false,
+	}
+
+	err = h.deviceRepo.InsertOne(device)
+	if err != nil {
+		log.Errorf("Failed to create %s device: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, device)
+}
+
+// update
+// @Tags Device Custom
+// @Summary Update a device of a custom device type, validated against its schema
+// @ID update-custom-device
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Device ID"
+// @Param body body customDeviceForm true "Device Form"
+// @Router /api/devices/{type}/{id} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *CustomDeviceHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	device, err := h.findDevice(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	f, err := newCustomDeviceForm(c)
+	if err != nil {
+		return err
+	}
+
+	device.Fields, err = deviceType.Validate(f.Fields)
+	if err != nil {
+		return err
+	}
+	device.Updated = nc.Claims.ByAtPtr()
+
+	err = h.deviceRepo.UpdateOneByID(device.ID, device)
+	if err != nil {
+		log.Errorf("Failed to update %s device: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, device)
+}
+
+// delete
+// @Tags Device Custom
+// @Summary Delete a device of a custom device type
+// @ID delete-custom-device
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Device ID"
+// @Router /api/devices/{type}/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *CustomDeviceHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	device, err := h.findDevice(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	err = h.deviceRepo.DeleteOneByID(deviceType.Slug, device.ID, nc.Claims.ByAt())
+	if err != nil {
+		log.Errorf("Failed to delete %s device: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceType.Name+" deleted")
+}
+
+func (h *CustomDeviceHandler) findDevice(deviceType *repo.DeviceType, id string) (*repo.CustomDevice, error) {
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	device, err := h.deviceRepo.FindOneByID(deviceType.Slug, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, deviceType.Name+" not found")
+		}
+		log.Errorf("Failed to get %s device: %v", deviceType.Slug, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return device, nil
+}
@@ -0,0 +1,336 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device_cp/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/api/device_type/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+)
+
+type CustomDocHandler struct {
+	typeRepo     *repo.DeviceTypeCollRepository
+	deviceRepo   *repo.CustomDeviceCollRepository
+	docRepo      *repo.CustomDocCollRepository
+	cpRepo       *repo2.CheckpointCollRepository
+	revisionRepo *repo3.DocRevisionCollRepository
+}
+
+func NewCustomDocAPIHandler(e *echo.Echo, db *mongo.Database) *CustomDocHandler {
+	h := &CustomDocHandler{
+		typeRepo:     repo.NewDeviceTypeRepository(db),
+		deviceRepo:   repo.NewCustomDeviceRepository(db),
+		docRepo:      repo.NewCustomDocRepository(db),
+		cpRepo:       repo2.NewCheckpointRepository(db),
+		revisionRepo: repo3.NewDocRevisionRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/doc/:type", h.findAll)
+	group.GET("/doc/:type/:id", h.findOne)
+
+	group.POST("/doc/:type", h.create)
+
+	group.PUT("/doc/:type/:id", h.update)
+
+	group.DELETE("/doc/:type/:id", h.delete)
+
+	return h
+}
+
+// findAll
+// @Tags Doc Custom
+// @Summary Get all documents of a custom device type
+// @ID get-all-custom-documents
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param status query string false "Filter by status" enums(draft,submitted,approved,rejected)
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Router /api/doc/{type} [GET]
+// @Produce json
+// @Success 200
+func (h *CustomDocHandler) findAll(c echo.Context) error {
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	cq, err := util.NewStatusQuery(c, _const.ValidDocStatus)
+	if err != nil {
+		return err
+	}
+
+	docs, err := h.docRepo.FindAll(deviceType.Slug, cq)
+	if err != nil {
+		log.Errorf("Failed to get %s docs: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	total, err := h.docRepo.CountQuery(deviceType.Slug, cq)
+	if err != nil {
+		log.Errorf("Failed to count %s docs: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(docs, total, cq.Page, cq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// findOne
+// @Tags Doc Custom
+// @Summary Get a document of a custom device type
+// @ID get-custom-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id} [GET]
+// @Produce json
+// @Success 200
+func (h *CustomDocHandler) findOne(c echo.Context) error {
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	customDoc, err := h.findDoc(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, customDoc)
+}
+
+// create
+// @Tags Doc Custom
+// @Summary Create a document of a custom device type
+// @ID create-custom-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param body body doc.DeviceDocForm true "Doc Form"
+// @Router /api/doc/{type} [POST]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *CustomDocHandler) create(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	f, err := doc.NewDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	device, err := h.deviceRepo.FindOneByID(deviceType.Slug, f.DeviceOID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, deviceType.Name+" not found")
+		}
+		log.Errorf("Failed to get %s device: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	cp, err := h.validateCheckpoint(deviceType.Slug, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	customDoc := &repo.CustomDoc{
+		ID:                bson.NewObjectID(),
+		Type:              deviceType.Slug,
+		DeviceID:          device.ID,
+		Fields:            device.Fields,
+		Checkpoint:        f.Checkpoint,
+		CheckpointVersion: cp.Version,
+		Status:            _const.DocDraft,
+		Inserted:          nc.Claims.ByAt(),
+		IsDeleted:         false,
+	}
+
+	if f.Submit {
+		customDoc.Status = _const.DocSubmitted
+		customDoc.Submitted = nc.Claims.ByAtPtr()
+	}
+
+	err = h.docRepo.InsertOne(customDoc)
+	if err != nil {
+		log.Errorf("Failed to create %s doc: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, customDoc)
+}
+
+// update
+// @Tags Doc Custom
+// @Summary Update the checkpoint results of a document of a custom device type
+// @ID update-custom-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Document ID"
+// @Param body body doc.UpdateDeviceDocForm true "Doc Form"
+// @Router /api/doc/{type}/{id} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *CustomDocHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	f, err := doc.NewUpdateDeviceDocForm(c)
+	if err != nil {
+		return err
+	}
+
+	cp, err := h.validateCheckpoint(deviceType.Slug, f.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	customDoc, err := h.findDoc(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	if customDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved "+deviceType.Name+" Doc is read-only")
+	}
+	if customDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted "+deviceType.Name+" Doc is under review, reject it to edit")
+	}
+
+	updated := nc.Claims.ByAtPtr()
+	err = h.saveRevision(customDoc, *updated)
+	if err != nil {
+		log.Errorf("Failed to save %s doc revision: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	customDoc.Checkpoint = f.Checkpoint
+	customDoc.CheckpointVersion = cp.Version
+	customDoc.Updated = updated
+	err = h.docRepo.UpdateOneByID(customDoc.ID, customDoc)
+	if err != nil {
+		log.Errorf("Failed to update %s doc: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, customDoc)
+}
+
+// delete
+// @Tags Doc Custom
+// @Summary Delete a document of a custom device type
+// @ID delete-custom-document
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param id path string true "Document ID"
+// @Router /api/doc/{type}/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *CustomDocHandler) delete(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findCustomType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+
+	customDoc, err := h.findDoc(deviceType, c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	if customDoc.Status == _const.DocApproved {
+		return echo.NewHTTPError(http.StatusForbidden, "Approved "+deviceType.Name+" Doc is read-only")
+	}
+	if customDoc.Status == _const.DocSubmitted {
+		return echo.NewHTTPError(http.StatusConflict, "Submitted "+deviceType.Name+" Doc is under review, reject it to delete")
+	}
+
+	err = h.docRepo.DeleteOneByID(deviceType.Slug, customDoc.ID, nc.Claims.ByAt())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusConflict, deviceType.Name+" Doc was changed, please reload")
+		}
+		log.Errorf("Failed to delete %s doc: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceType.Name+" Doc deleted")
+}
+
+func (h *CustomDocHandler) findDoc(deviceType *repo.DeviceType, id string) (*repo.CustomDoc, error) {
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	customDoc, err := h.docRepo.FindOneByID(deviceType.Slug, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, deviceType.Name+" Doc not found")
+		}
+		log.Errorf("Failed to get %s doc: %v", deviceType.Slug, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return customDoc, nil
+}
+
+// validateCheckpoint checks submitted results against the checkpoint template
+// of the type.
+func (h *CustomDocHandler) validateCheckpoint(slug string, checkpoint []doc.CPDetail) (*repo2.Checkpoint, error) {
+	cp, err := h.cpRepo.FindByDevice(slug)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Checkpoint not found")
+		}
+		log.Errorf("Failed to get %s checkpoint: %v", slug, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	err = doc.ValidateCheckpoint(cp.Checkpoint, cp.Items, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// saveRevision stores the results being replaced as the next revision of the
+// doc.
+func (h *CustomDocHandler) saveRevision(d *repo.CustomDoc, replaced doc.ByAt) error {
+	count, err := h.revisionRepo.CountByDoc(d.Type, d.ID)
+	if err != nil {
+		return err
+	}
+
+	author := d.Inserted
+	if d.Updated != nil {
+		author = *d.Updated
+	}
+
+	return h.revisionRepo.InsertOne(&repo3.DocRevision{
+		ID:                bson.NewObjectID(),
+		Device:            d.Type,
+		DocID:             d.ID,
+		Revision:          int(count) + 1,
+		Checkpoint:        d.Checkpoint,
+		CheckpointVersion: d.CheckpointVersion,
+		Author:            author,
+		Inserted:          replaced,
+	})
+}
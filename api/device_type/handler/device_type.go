@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device_cp/repo"
+	"sipamit-be/api/device_type/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/log"
+	"strings"
+)
+
+// customTypeRoutes are the path prefixes the routes of custom types take the
+// :type param after.
+var customTypeRoutes = []string{"/api/devices/", "/api/doc/"}
+
+type deviceTypeForm struct {
+	Name   string             `json:"name" form:"name"`
+	Slug   string             `json:"slug" form:"slug"`
+	Fields []repo.DeviceField `json:"fields" form:"fields"`
+}
+
+func newDeviceTypeForm(c echo.Context) (*deviceTypeForm, error) {
+	f := new(deviceTypeForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind device type form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+
+	err := repo.ValidateFields(f.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// slugFromParam converts a route segment such as "network-switch" to the
+// slug of a type.
+func slugFromParam(param string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(param)), "-", "_")
+}
+
+// findType returns the registered type of the :type route param.
+func findType(typeRepo *repo.DeviceTypeCollRepository, c echo.Context) (*repo.DeviceType, error) {
+	deviceType, err := typeRepo.FindBySlug(slugFromParam(c.Param("type")))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Device type not found")
+		}
+		log.Errorf("Failed to get device type: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return deviceType, nil
+}
+
+// findCustomType returns the registered type of the :type route param,
+// pointing built-in types to their own routes.
+func findCustomType(typeRepo *repo.DeviceTypeCollRepository, c echo.Context) (*repo.DeviceType, error) {
+	deviceType, err := findType(typeRepo, c)
+	if err != nil {
+		return nil, err
+	}
+	if deviceType.BuiltIn {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("%s is a built-in type, use /api/%s", deviceType.Name, _const.DeviceParam(deviceType.Slug)))
+	}
+	return deviceType, nil
+}
+
+type DeviceTypeHandler struct {
+	echo       *echo.Echo
+	typeRepo   *repo.DeviceTypeCollRepository
+	deviceRepo *repo.CustomDeviceCollRepository
+	cpRepo     *repo2.CheckpointCollRepository
+}
+
+func NewDeviceTypeAPIHandler(e *echo.Echo, db *mongo.Database) *DeviceTypeHandler {
+	h := &DeviceTypeHandler{
+		echo:       e,
+		typeRepo:   repo.NewDeviceTypeRepository(db),
+		deviceRepo: repo.NewCustomDeviceRepository(db),
+		cpRepo:     repo2.NewCheckpointRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/device-types", h.findAll)
+	group.GET("/device-types/:type", h.findOne)
+
+	group.POST("/device-types", h.create, context.AdminOrSuperAdminOnly)
+
+	group.PUT("/device-types/:type", h.update, context.AdminOrSuperAdminOnly)
+
+	group.DELETE("/device-types/:type", h.delete, context.AdminOrSuperAdminOnly)
+
+	return h
+}
+
+// findAll
+// @Tags Device Type
+// @Summary Get all device types, built-in ones first
+// @ID get-all-device-types
+// @Security ApiKeyAuth
+// @Router /api/device-types [GET]
+// @Produce json
+// @Success 200
+func (h *DeviceTypeHandler) findAll(c echo.Context) error {
+	deviceTypes, err := h.typeRepo.FindAll()
+	if err != nil {
+		log.Errorf("Failed to get device types: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceTypes)
+}
+
+// findOne
+// @Tags Device Type
+// @Summary Get a device type with its field schema
+// @ID get-device-type
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Router /api/device-types/{type} [GET]
+// @Produce json
+// @Success 200
+func (h *DeviceTypeHandler) findOne(c echo.Context) error {
+	deviceType, err := findType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, deviceType)
+}
+
+// reservedSlug reports whether a static route takes the segment of slug next
+// to the :type param of custom types, like /api/devices/labels. The routes
+// are read as registered, so a route added later is reserved as well.
+func (h *DeviceTypeHandler) reservedSlug(slug string) bool {
+	for _, route := range h.echo.Routes() {
+		for _, prefix := range customTypeRoutes {
+			segment, ok := strings.CutPrefix(route.Path, prefix)
+			if !ok {
+				continue
+			}
+			segment, _, _ = strings.Cut(segment, "/")
+			if segment != "" && !strings.HasPrefix(segment, ":") && slugFromParam(segment) == slug {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// create
+// @Tags Device Type
+// @Summary Register a device type with an empty checkpoint template
+// @Description Devices and docs of custom types are managed under /api/devices/{type} and /api/doc/{type} only. They are not covered by maintenance schedules, the compliance and trend reports, device search, the trash, offline sync, import or export, which handle the built-in types.
+// @ID create-device-type
+// @Security ApiKeyAuth
+// @Param body body deviceTypeForm true "Device Type Form"
+// @Router /api/device-types [POST]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *DeviceTypeHandler) create(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := newDeviceTypeForm(c)
+	if err != nil {
+		return err
+	}
+
+	slug := slugFromParam(f.Slug)
+	if !repo.ValidName(slug) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Slug must start with a letter and contain only a-z, 0-9 and _")
+	}
+	if h.reservedSlug(slug) || _const.ValidDevice(slug) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Slug is reserved")
+	}
+
+	exists, err := h.typeRepo.ExistsSlug(slug)
+	if err != nil {
+		log.Errorf("Failed to check device type slug: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if exists {
+		return echo.NewHTTPError(http.StatusConflict, "Slug already exists")
+	}
+
+	deviceType := &repo.DeviceType{
+		ID:        bson.NewObjectID(),
+		Name:      f.Name,
+		Slug:      slug,
+		Fields:    f.Fields,
+		BuiltIn:   false,
+		Inserted:  nc.Claims.ByAt(),
+		IsDeleted: false,
+	}
+
+	err = h.typeRepo.InsertOne(deviceType)
+	if err != nil {
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
+		log.Errorf("Failed to create device type: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	err = h.cpRepo.InsertMany([]repo2.Checkpoint{
+		{ID: bson.NewObjectID(), Device: slug, Checkpoint: []string{}},
+	})
+	if err != nil {
+		log.Errorf("Failed to create %s checkpoint: %v", slug, err)
+		if err := h.typeRepo.RemoveOneByID(deviceType.ID); err != nil {
+			log.Errorf("Failed to remove device type %s: %v", slug, err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceType)
+}
+
+// update
+// @Tags Device Type
+// @Summary Update the name and field schema of a device type
+// @ID update-device-type
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Param body body deviceTypeForm true "Device Type Form"
+// @Router /api/device-types/{type} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *DeviceTypeHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	deviceType, err := findType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+	if deviceType.BuiltIn {
+		return echo.NewHTTPError(http.StatusForbidden, "Built-in device type is read-only")
+	}
+
+	f, err := newDeviceTypeForm(c)
+	if err != nil {
+		return err
+	}
+
+	deviceType.Name = f.Name
+	deviceType.Fields = f.Fields
+	deviceType.Updated = nc.Claims.ByAtPtr()
+
+	err = h.typeRepo.UpdateOneByID(deviceType.ID, deviceType)
+	if err != nil {
+		log.Errorf("Failed to update device type: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, deviceType)
+}
+
+// delete
+// @Tags Device Type
+// @Summary Delete a device type without devices
+// @ID delete-device-type
+// @Security ApiKeyAuth
+// @Param type path string true "Device type slug"
+// @Router /api/device-types/{type} [DELETE]
+// @Produce json
+// @Success 200
+func (h *DeviceTypeHandler) delete(c echo.Context) error {
+	deviceType, err := findType(h.typeRepo, c)
+	if err != nil {
+		return err
+	}
+	if deviceType.BuiltIn {
+		return echo.NewHTTPError(http.StatusForbidden, "Built-in device type is read-only")
+	}
+
+	count, err := h.deviceRepo.CountByType(deviceType.Slug)
+	if err != nil {
+		log.Errorf("Failed to count %s devices: %v", deviceType.Slug, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if count > 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Device type still has %d devices", count))
+	}
+
+	err = h.typeRepo.DeleteOneByID(deviceType.ID)
+	if err != nil {
+		log.Errorf("Failed to delete device type: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Device type deleted")
+}
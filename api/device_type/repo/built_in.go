@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"sipamit-be/internal/pkg/const"
+)
+
+func text(name, label string, searchable bool) DeviceField {
+	return DeviceField{
+		Name:       name,
+		Label:      label,
+		Type:       _const.FieldText,
+		Required:   true,
+		Searchable: searchable,
+	}
+}
+
+// BuiltInTypes describes the collections of the eight built-in types. Their
+// slug is the device type constant.
+var BuiltInTypes = []DeviceType{
+	{Slug: _const.CCTV, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("lokasi", "Lokasi", false),
+		text("kode", "Kode", false),
+	}},
+	{Slug: _const.Fingerprint, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("lokasi", "Lokasi", false),
+		text("kode", "Kode", false),
+	}},
+	{Slug: _const.KomputerPH1, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("merk", "Merk", false),
+		text("pc", "PC", false),
+		text("monitor", "Monitor", false),
+		text("cpu", "CPU", false),
+		text("ram", "RAM", false),
+		text("internal", "Internal", false),
+		text("lokasi", "Lokasi", false),
+	}},
+	{Slug: _const.KomputerPH2, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("merk", "Merk", false),
+		text("pc", "PC", false),
+		text("monitor", "Monitor", false),
+		text("cpu", "CPU", false),
+		text("ram", "RAM", false),
+		text("internal", "Internal", false),
+		text("lokasi", "Lokasi", false),
+	}},
+	{Slug: _const.Printer, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("departemen", "Departemen", false),
+		text("tipe_printer", "Tipe Printer", false),
+		text("no_seri", "No Seri", false),
+	}},
+	{Slug: _const.Telepon, Fields: []DeviceField{
+		text("lokasi", "Lokasi", false),
+		text("departemen", "Departemen", false),
+		text("user", "User", false),
+		text("ext", "Ext", false),
+		text("merk", "Merk", false),
+		text("tipe", "Tipe", true),
+	}},
+	{Slug: _const.Toa, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("lokasi", "Lokasi", false),
+		text("kode", "Kode", false),
+		text("posisi", "Posisi", false),
+	}},
+	{Slug: _const.Ups, Fields: []DeviceField{
+		text("nama", "Nama", true),
+		text("departemen", "Departemen", false),
+		text("tipe", "Tipe", false),
+		text("no_seri", "No Seri", false),
+		text("lokasi", "Lokasi", false),
+	}},
+}
+
+func init() {
+	for i := range BuiltInTypes {
+		BuiltInTypes[i].Name = _const.DeviceLabels[BuiltInTypes[i].Slug]
+		BuiltInTypes[i].BuiltIn = true
+	}
+}
+
+// BuiltInType returns the schema of a built-in type.
+func BuiltInType(slug string) (*DeviceType, bool) {
+	for i := range BuiltInTypes {
+		if BuiltInTypes[i].Slug == slug {
+			return &BuiltInTypes[i], true
+		}
+	}
+	return nil, false
+}
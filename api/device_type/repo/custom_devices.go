@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/util"
+	"time"
+)
+
+// CustomDevice is a device of a registered, non built-in type. Fields holds
+// the values declared by the type schema.
+type CustomDevice struct {
+	ID         bson.ObjectID          `json:"_id" bson:"_id"`
+	Type       string                 `json:"type" bson:"type"`
+	Fields     map[string]interface{} `json:"fields" bson:"fields"`
+	Inserted   doc.ByAt               `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated    *doc.ByAt              `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted    *doc.ByAt              `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt time.Time              `json:"modified_at" bson:"modified_at"`
+	IsDeleted  bool                   `json:"-" bson:"is_deleted"`
+}
+
+type CustomDeviceCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewCustomDeviceRepository(db *mongo.Database) *CustomDeviceCollRepository {
+	return &CustomDeviceCollRepository{
+		coll: db.Collection("custom_devices"),
+	}
+}
+
+// filter matches the devices of the type whose searchable fields contain q.
+func (r *CustomDeviceCollRepository) filter(deviceType *DeviceType, q string) bson.M {
+	filter := bson.M{
+		"type":       deviceType.Slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if len(q) > 0 {
+		var pattern = bson.Regex{Pattern: q, Options: "i"}
+		var or bson.A
+		for _, name := range deviceType.SearchFields() {
+			or = append(or, bson.M{"fields." + name: bson.M{"$regex": pattern}})
+		}
+		if len(or) > 0 {
+			filter["$or"] = or
+		}
+	}
+	return filter
+}
+
+func (r *CustomDeviceCollRepository) FindAll(deviceType *DeviceType, cq *util.CommonQuery) (*[]CustomDevice, error) {
+	var devices []CustomDevice
+	filter := r.filter(deviceType, cq.Q)
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &devices)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		return &[]CustomDevice{}, nil
+	}
+	return &devices, nil
+}
+
+func (r *CustomDeviceCollRepository) CountQuery(deviceType *DeviceType, cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(deviceType, cq.Q)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *CustomDeviceCollRepository) CountByType(slug string) (int64, error) {
+	filter := bson.M{
+		"type":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *CustomDeviceCollRepository) FindOneByID(slug string, id bson.ObjectID) (*CustomDevice, error) {
+	var device CustomDevice
+	filter := bson.M{
+		"_id":        id,
+		"type":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&device)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *CustomDeviceCollRepository) InsertOne(device *CustomDevice) error {
+	device.ModifiedAt = doc.Stamp()
+	_, err := r.coll.InsertOne(context.TODO(), device)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CustomDeviceCollRepository) UpdateOneByID(id bson.ObjectID, device *CustomDevice) error {
+	device.ModifiedAt = doc.Stamp()
+
+	filter := bson.M{
+		"_id":        id,
+		"type":       device.Type,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	update := bson.M{
+		"$set": device,
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CustomDeviceCollRepository) DeleteOneByID(slug string, id bson.ObjectID, deleted doc.ByAt) error {
+	filter := bson.M{
+		"_id":        id,
+		"type":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/util"
+	"time"
+)
+
+// CustomDoc is a maintenance doc of a custom device. Fields is a snapshot of
+// the device fields when the doc was created.
+type CustomDoc struct {
+	ID                bson.ObjectID          `json:"_id" bson:"_id"`
+	Type              string                 `json:"type" bson:"type"`
+	DeviceID          bson.ObjectID          `json:"device_id" bson:"device_id"`
+	Fields            map[string]interface{} `json:"fields" bson:"fields"`
+	Checkpoint        []doc.CPDetail         `json:"checkpoint" bson:"checkpoint"`
+	CheckpointVersion int                    `json:"checkpoint_version" bson:"checkpoint_version"`
+	Status            string                 `json:"status" bson:"status"`
+	Submitted         *doc.ByAt              `json:"submitted,omitempty" bson:"submitted,omitempty"`
+	Reviewed          *doc.ByAt              `json:"reviewed,omitempty" bson:"reviewed,omitempty"`
+	RejectReason      string                 `json:"reject_reason,omitempty" bson:"reject_reason,omitempty"`
+	Inserted          doc.ByAt               `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated           *doc.ByAt              `json:"updated,omitempty" bson:"updated,omitempty"`
+	Deleted           *doc.ByAt              `json:"deleted,omitempty" bson:"deleted,omitempty"`
+	ModifiedAt        time.Time              `json:"modified_at" bson:"modified_at"`
+	IsDeleted         bool                   `json:"-" bson:"is_deleted"`
+}
+
+type CustomDocCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewCustomDocRepository(db *mongo.Database) *CustomDocCollRepository {
+	return &CustomDocCollRepository{
+		coll: db.Collection("custom_docs"),
+	}
+}
+
+func (r *CustomDocCollRepository) filter(slug string, cq *util.CommonQuery) bson.M {
+	filter := bson.M{
+		"type":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if cq.Status != "" {
+		filter["status"] = cq.Status
+	}
+	return filter
+}
+
+func (r *CustomDocCollRepository) FindAll(slug string, cq *util.CommonQuery) (*[]CustomDoc, error) {
+	var docs []CustomDoc
+	filter := r.filter(slug, cq)
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"_id": cq.Sort}, cq.Page, cq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &docs)
+	if err != nil {
+		return nil, err
+	}
+	if docs == nil {
+		return &[]CustomDoc{}, nil
+	}
+	return &docs, nil
+}
+
+func (r *CustomDocCollRepository) CountQuery(slug string, cq *util.CommonQuery) (int64, error) {
+	filter := r.filter(slug, cq)
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *CustomDocCollRepository) FindOneByID(slug string, id bson.ObjectID) (*CustomDoc, error) {
+	var customDoc CustomDoc
+	filter := bson.M{
+		"_id":        id,
+		"type":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&customDoc)
+	if err != nil {
+		return nil, err
+	}
+	return &customDoc, nil
+}
+
+func (r *CustomDocCollRepository) InsertOne(customDoc *CustomDoc) error {
+	customDoc.ModifiedAt = doc.Stamp()
+	_, err := r.coll.InsertOne(context.TODO(), customDoc)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CustomDocCollRepository) UpdateOneByID(id bson.ObjectID, customDoc *CustomDoc) error {
+	customDoc.ModifiedAt = doc.Stamp()
+
+	filter := bson.M{
+		"_id":        id,
+		"type":       customDoc.Type,
+		"is_deleted": bson.M{"$ne": true},
+		"status":     bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
+	}
+
+	update := bson.M{
+		"$set": customDoc,
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteOneByID soft deletes the doc unless it is submitted or approved,
+// returning mongo.ErrNoDocuments when it is, or is gone.
+func (r *CustomDocCollRepository) DeleteOneByID(slug string, id bson.ObjectID, deleted doc.ByAt) error {
+	filter := bson.M{
+		"_id":    id,
+		"type":   slug,
+		"status": bson.M{"$nin": bson.A{_const.DocSubmitted, _const.DocApproved}},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  true,
+			"deleted":     deleted,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	res, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
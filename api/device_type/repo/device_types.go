@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+	"time"
+)
+
+// DeviceField declares one field of a device type. Searchable fields are
+// matched by the q query param.
+type DeviceField struct {
+	Name       string `json:"name" bson:"name"`
+	Label      string `json:"label" bson:"label"`
+	Type       string `json:"type" bson:"type" enums:"text,number,boolean,date"`
+	Required   bool   `json:"required" bson:"required"`
+	Searchable bool   `json:"searchable" bson:"searchable"`
+}
+
+// DeviceType is a registered device category. Built-in types are the eight
+// types with their own collections and routes; their schema describes those
+// collections and cannot be changed. Other types are stored in the generic
+// custom_devices and custom_docs collections, and are left out of the
+// features built on the eight types, like schedules, reports and sync.
+type DeviceType struct {
+	ID         bson.ObjectID `json:"_id" bson:"_id"`
+	Name       string        `json:"name" bson:"name"`
+	Slug       string        `json:"slug" bson:"slug"`
+	Fields     []DeviceField `json:"fields" bson:"fields"`
+	BuiltIn    bool          `json:"built_in" bson:"built_in"`
+	Inserted   doc.ByAt      `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated    *doc.ByAt     `json:"updated,omitempty" bson:"updated,omitempty"`
+	ModifiedAt time.Time     `json:"modified_at" bson:"modified_at"`
+	IsDeleted  bool          `json:"-" bson:"is_deleted"`
+}
+
+// Field returns the declaration of the named field.
+func (t *DeviceType) Field(name string) (*DeviceField, bool) {
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// SearchFields returns the names of the searchable fields.
+func (t *DeviceType) SearchFields() []string {
+	var names []string
+	for _, field := range t.Fields {
+		if field.Searchable {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+type DeviceTypeCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewDeviceTypeRepository(db *mongo.Database) *DeviceTypeCollRepository {
+	return &DeviceTypeCollRepository{
+		coll: db.Collection("device_types"),
+	}
+}
+
+// EnsureIndexes creates the indexes of the device_types collection, so two
+// types created at the same time can't share a slug.
+func (r *DeviceTypeCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Unique("slug"))
+}
+
+func (r *DeviceTypeCollRepository) FindAll() (*[]DeviceType, error) {
+	var deviceTypes []DeviceType
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "built_in", Value: -1}, {Key: "name", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &deviceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if deviceTypes == nil {
+		return &[]DeviceType{}, nil
+	}
+	return &deviceTypes, nil
+}
+
+func (r *DeviceTypeCollRepository) FindBySlug(slug string) (*DeviceType, error) {
+	var deviceType DeviceType
+	filter := bson.M{
+		"slug":       slug,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&deviceType)
+	if err != nil {
+		return nil, err
+	}
+	return &deviceType, nil
+}
+
+// ExistsSlug reports whether the slug is taken, by deleted types too, so
+// the records of a deleted type are never picked up by a new one.
+func (r *DeviceTypeCollRepository) ExistsSlug(slug string) (bool, error) {
+	filter := bson.M{
+		"slug": slug,
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *DeviceTypeCollRepository) InsertOne(deviceType *DeviceType) error {
+	deviceType.ModifiedAt = doc.Stamp()
+	_, err := r.coll.InsertOne(context.TODO(), deviceType)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DeviceTypeCollRepository) UpdateOneByID(id bson.ObjectID, deviceType *DeviceType) error {
+	deviceType.ModifiedAt = doc.Stamp()
+
+	filter := bson.M{
+		"_id":        id,
+		"built_in":   bson.M{"$ne": true},
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	update := bson.M{
+		"$set": deviceType,
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DeviceTypeCollRepository) DeleteOneByID(id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":      id,
+		"built_in": bson.M{"$ne": true},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  true,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveOneByID permanently removes a custom type whose registration failed
+// part way, freeing its slug.
+func (r *DeviceTypeCollRepository) RemoveOneByID(id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":      id,
+		"built_in": bson.M{"$ne": true},
+	}
+
+	_, err := r.coll.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpsertBuiltIn registers a built-in type, replacing the name and schema of
+// an earlier registration.
+func (r *DeviceTypeCollRepository) UpsertBuiltIn(deviceType *DeviceType) error {
+	filter := bson.M{
+		"slug": deviceType.Slug,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":        deviceType.Name,
+			"fields":      deviceType.Fields,
+			"built_in":    true,
+			"is_deleted":  false,
+			"modified_at": doc.Stamp(),
+		},
+		"$setOnInsert": bson.M{
+			"_id":      bson.NewObjectID(),
+			"inserted": deviceType.Inserted,
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"math"
+	"net/http"
+	"regexp"
+	"sipamit-be/internal/pkg/const"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type FieldValidationError struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// ValidName reports whether name can be used as a slug or field name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ValidateFields checks the field declarations of a type, defaulting an empty
+// type to text and an empty label to the name.
+func ValidateFields(fields []DeviceField) error {
+	var errs []FieldError
+
+	if len(fields) == 0 {
+		errs = append(errs, FieldError{Field: "fields", Message: "At least one field is required"})
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for i := range fields {
+		field := &fields[i]
+		path := fmt.Sprintf("fields[%d]", i)
+
+		field.Name = strings.ToLower(strings.TrimSpace(field.Name))
+		if !ValidName(field.Name) {
+			errs = append(errs, FieldError{Field: path + ".name", Message: "Name must start with a letter and contain only a-z, 0-9 and _"})
+			continue
+		}
+		if seen[field.Name] {
+			errs = append(errs, FieldError{Field: path + ".name", Message: "Duplicate field"})
+			continue
+		}
+		seen[field.Name] = true
+
+		field.Label = strings.TrimSpace(field.Label)
+		if field.Label == "" {
+			field.Label = field.Name
+		}
+
+		field.Type = strings.ToLower(strings.TrimSpace(field.Type))
+		if field.Type == "" {
+			field.Type = _const.FieldText
+		}
+		if !_const.ValidFieldType(field.Type) {
+			errs = append(errs, FieldError{Field: path + ".type", Message: "Invalid type"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, FieldValidationError{
+			Message: "Invalid fields",
+			Errors:  errs,
+		})
+	}
+	return nil
+}
+
+// Validate checks submitted values against the schema of the type and
+// returns them converted to the field types. Empty optional fields are left
+// out.
+func (t *DeviceType) Validate(values map[string]interface{}) (map[string]interface{}, error) {
+	var errs []FieldError
+	result := make(map[string]interface{}, len(t.Fields))
+
+	for name := range values {
+		if _, ok := t.Field(name); !ok {
+			errs = append(errs, FieldError{Field: name, Message: "Unknown field"})
+		}
+	}
+
+	for _, field := range t.Fields {
+		v, err := field.convert(values[field.Name])
+		if err != "" {
+			errs = append(errs, FieldError{Field: field.Name, Message: err})
+			continue
+		}
+		if v == nil {
+			if field.Required {
+				errs = append(errs, FieldError{Field: field.Name, Message: field.Label + " is required"})
+			}
+			continue
+		}
+		result[field.Name] = v
+	}
+
+	if len(errs) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, FieldValidationError{
+			Message: "Invalid " + t.Name,
+			Errors:  errs,
+		})
+	}
+	return result, nil
+}
+
+// convert reads a submitted value as the field type, returning nil for an
+// empty value.
+func (field *DeviceField) convert(value interface{}) (interface{}, string) {
+	if s, ok := value.(string); ok {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, ""
+		}
+		value = s
+	}
+	if value == nil {
+		return nil, ""
+	}
+
+	switch field.Type {
+	case _const.FieldNumber:
+		switch v := value.(type) {
+		case float64:
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				return v, ""
+			}
+		case string:
+			f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+			if err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return f, ""
+			}
+		}
+		return nil, "Value must be a number"
+	case _const.FieldBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, ""
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err == nil {
+				return b, ""
+			}
+		}
+		return nil, "Value must be true or false"
+	case _const.FieldDate:
+		if s, ok := value.(string); ok {
+			if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+				return t, ""
+			}
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t, ""
+			}
+		}
+		return nil, "Value must be a date (YYYY-MM-DD)"
+	default:
+		switch v := value.(type) {
+		case string:
+			return v, ""
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), ""
+		case bool:
+			return strconv.FormatBool(v), ""
+		}
+		return nil, "Value must be text"
+	}
+}
@@ -4,9 +4,17 @@ import (
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	appHandler "sipamit-be/api/app/handler"
+	attachmentHandler "sipamit-be/api/attachment/handler"
+	departmentHandler "sipamit-be/api/department/handler"
 	deviceHandler "sipamit-be/api/device/handler"
 	checkpointHandler "sipamit-be/api/device_cp/handler"
 	deviceDocHandler "sipamit-be/api/device_doc/handler"
+	deviceTypeHandler "sipamit-be/api/device_type/handler"
+	locationHandler "sipamit-be/api/location/handler"
+	reportHandler "sipamit-be/api/report/handler"
+	scheduleHandler "sipamit-be/api/schedule/handler"
+	ticketHandler "sipamit-be/api/ticket/handler"
+	trashHandler "sipamit-be/api/trash/handler"
 )
 
 func NewInitHandler(e *echo.Echo, db *mongo.Database) {
@@ -23,6 +31,13 @@ func NewInitHandler(e *echo.Echo, db *mongo.Database) {
 	deviceHandler.NewTeleponAPIHandler(e, db)
 	deviceHandler.NewTOAAPIHandler(e, db)
 	deviceHandler.NewUPSAPIHandler(e, db)
+	deviceHandler.NewLabelAPIHandler(e, db)
+	deviceHandler.NewImportAPIHandler(e, db)
+	deviceHandler.NewExportAPIHandler(e, db)
+	deviceTypeHandler.NewDeviceTypeAPIHandler(e, db)
+	deviceTypeHandler.NewCustomDeviceAPIHandler(e, db)
+	locationHandler.NewLocationAPIHandler(e, db)
+	departmentHandler.NewDepartmentAPIHandler(e, db)
 
 	checkpointHandler.NewCheckpointAPIHandler(e, db)
 
@@ -34,4 +49,22 @@ func NewInitHandler(e *echo.Echo, db *mongo.Database) {
 	deviceDocHandler.NewTeleponDocAPIHandler(e, db)
 	deviceDocHandler.NewTOADocAPIHandler(e, db)
 	deviceDocHandler.NewUPSDocAPIHandler(e, db)
+	deviceDocHandler.NewDocPDFAPIHandler(e, db)
+	deviceDocHandler.NewDocExportAPIHandler(e, db)
+	deviceDocHandler.NewDocReviewAPIHandler(e, db)
+	deviceDocHandler.NewDocRevisionAPIHandler(e, db)
+	deviceDocHandler.NewDocSyncAPIHandler(e, db)
+	deviceTypeHandler.NewCustomDocAPIHandler(e, db)
+	attachmentHandler.NewAttachmentAPIHandler(e, db)
+
+	scheduleHandler.NewScheduleAPIHandler(e, db)
+	reportHandler.NewReportAPIHandler(e, db)
+	ticketHandler.NewTicketAPIHandler(e, db)
+	trashHandler.NewTrashAPIHandler(e, db)
+}
+
+// StartJobs starts the background jobs of the API. It is called once at
+// startup, after the handlers are registered.
+func StartJobs(db *mongo.Database) {
+	trashHandler.StartRetention(db)
 }
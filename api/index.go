@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	appRepo "sipamit-be/api/app/repo"
+	deviceRepo "sipamit-be/api/device/repo"
+	checkpointRepo "sipamit-be/api/device_cp/repo"
+	docRepo "sipamit-be/api/device_doc/repo"
+	typeRepo "sipamit-be/api/device_type/repo"
+	locationRepo "sipamit-be/api/location/repo"
+	ticketRepo "sipamit-be/api/ticket/repo"
+	"sort"
+)
+
+type indexed interface {
+	EnsureIndexes() error
+}
+
+// EnsureIndexes creates the indexes the repositories declare and returns the
+// failure of every collection, like one holding duplicated values for a
+// unique index, so startup stops until the data is fixed instead of serving
+// without the constraints.
+func EnsureIndexes(db *mongo.Database) error {
+	repos := map[string]indexed{
+		"users":               appRepo.NewUserRepository(db),
+		"cctvs":               deviceRepo.NewCCTVRepository(db),
+		"fingerprints":        deviceRepo.NewFingerPrintRepository(db),
+		"komputer_ph1s":       deviceRepo.NewKomputerPH1Repository(db),
+		"komputer_ph2s":       deviceRepo.NewKomputerPH2Repository(db),
+		"printers":            deviceRepo.NewPrinterRepository(db),
+		"telepons":            deviceRepo.NewTeleponRepository(db),
+		"toas":                deviceRepo.NewTOARepository(db),
+		"ups":                 deviceRepo.NewUPSRepository(db),
+		"locations":           locationRepo.NewLocationRepository(db),
+		"checkpoint_versions": checkpointRepo.NewCheckpointVersionRepository(db),
+		"device_types":        typeRepo.NewDeviceTypeRepository(db),
+		"doc_revisions":       docRepo.NewDocRevisionRepository(db),
+		"tickets":             ticketRepo.NewTicketRepository(db),
+	}
+
+	colls := make([]string, 0, len(repos))
+	for coll := range repos {
+		colls = append(colls, coll)
+	}
+	sort.Strings(colls)
+
+	var errs []error
+	for _, coll := range colls {
+		err := repos[coll].EnsureIndexes()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", coll, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,446 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	"sipamit-be/api/location/repo"
+	"sipamit-be/internal/migration"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+)
+
+type locationForm struct {
+	Name     string   `json:"name" form:"name"`
+	Kind     string   `json:"kind" form:"kind"`
+	ParentID string   `json:"parent_id" form:"parent_id"`
+	Aliases  []string `json:"aliases" form:"aliases"`
+}
+
+func newLocationForm(c echo.Context) (*locationForm, error) {
+	f := new(locationForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind location form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Name = strings.Join(strings.Fields(f.Name), " ")
+	if f.Name == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name is required")
+	}
+	if strings.Contains(f.Name, strings.TrimSpace(repo.PathSeparator)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Name cannot contain "+strings.TrimSpace(repo.PathSeparator))
+	}
+
+	f.Kind = strings.ToLower(strings.TrimSpace(f.Kind))
+	f.ParentID = strings.TrimSpace(f.ParentID)
+
+	aliases := make([]string, 0, len(f.Aliases))
+	for _, alias := range f.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	f.Aliases = aliases
+	return f, nil
+}
+
+// locationNode is a location with the locations under it.
+type locationNode struct {
+	*repo.Location
+	Children []*locationNode `json:"children"`
+}
+
+type LocationHandler struct {
+	locationRepo *repo.LocationCollRepository
+	deviceRepo   *repo2.DeviceRepository
+	db           *mongo.Database
+}
+
+func NewLocationAPIHandler(e *echo.Echo, db *mongo.Database) *LocationHandler {
+	h := &LocationHandler{
+		locationRepo: repo.NewLocationRepository(db),
+		deviceRepo:   repo2.NewDeviceRepository(db),
+		db:           db,
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/locations", h.findAll)
+	group.GET("/locations/tree", h.tree)
+	group.GET("/location/:id", h.findOne)
+
+	group.POST("/location", h.create, context.AdminOrSuperAdminOnly)
+	group.POST("/locations/migrate", h.migrate, context.SuperAdminOnly)
+
+	group.PUT("/location/:id", h.update, context.AdminOrSuperAdminOnly)
+
+	group.DELETE("/location/:id", h.delete, context.AdminOrSuperAdminOnly)
+
+	return h
+}
+
+// findAll
+// @Tags Location
+// @Summary Get all locations
+// @ID get-all-locations
+// @Security ApiKeyAuth
+// @Param q query string false "Search by path or alias"
+// @Param kind query string false "Filter by kind" enums(site, area, room)
+// @Param parent query string false "Filter by parent location ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort" enums(asc,desc)
+// @Router /api/locations [GET]
+// @Produce json
+// @Success 200
+func (h *LocationHandler) findAll(c echo.Context) error {
+	lq := &repo.LocationQuery{
+		CommonQuery: util.NewCommonQuery(c),
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(c.QueryParam("kind")))
+	if _const.ValidLocationKind(kind) {
+		lq.Kind = kind
+	}
+
+	if parent := strings.TrimSpace(c.QueryParam("parent")); parent != "" {
+		oId, err := bson.ObjectIDFromHex(parent)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid parent ID")
+		}
+		lq.ParentID = &oId
+	}
+
+	locations, err := h.locationRepo.FindAll(lq)
+	if err != nil {
+		log.Errorf("Failed to get locations: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	total, err := h.locationRepo.CountQuery(lq)
+	if err != nil {
+		log.Errorf("Failed to count locations: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(locations, total, lq.Page, lq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// tree
+// @Tags Location
+// @Summary Get the location hierarchy from sites down to rooms
+// @ID get-location-tree
+// @Security ApiKeyAuth
+// @Router /api/locations/tree [GET]
+// @Produce json
+// @Success 200
+func (h *LocationHandler) tree(c echo.Context) error {
+	locations, err := h.locationRepo.FindAllSorted()
+	if err != nil {
+		log.Errorf("Failed to get locations: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	nodes := make(map[bson.ObjectID]*locationNode, len(locations))
+	roots := []*locationNode{}
+	for i := range locations {
+		node := &locationNode{Location: &locations[i], Children: []*locationNode{}}
+		nodes[node.ID] = node
+
+		if node.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return c.JSON(http.StatusOK, roots)
+}
+
+// findOne
+// @Tags Location
+// @Summary Get location by id
+// @ID get-location-by-id
+// @Security ApiKeyAuth
+// @Param id path string true "Location ID"
+// @Router /api/location/{id} [GET]
+// @Produce json
+// @Success 200
+func (h *LocationHandler) findOne(c echo.Context) error {
+	location, err := h.findLocation(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, location)
+}
+
+// create
+// @Tags Location
+// @Summary Create a site, an area under a site or a room under an area
+// @ID create-location
+// @Security ApiKeyAuth
+// @Param body body locationForm true "Location Form"
+// @Router /api/location [POST]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *LocationHandler) create(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := newLocationForm(c)
+	if err != nil {
+		return err
+	}
+
+	if !_const.ValidLocationKind(f.Kind) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Kind must be site, area or room")
+	}
+
+	parent, err := h.findParent(f.Kind, f.ParentID)
+	if err != nil {
+		return err
+	}
+
+	location := &repo.Location{
+		ID:        bson.NewObjectID(),
+		Name:      f.Name,
+		Kind:      f.Kind,
+		Aliases:   f.Aliases,
+		Inserted:  nc.Claims.ByAt(),
+		IsDeleted: false,
+	}
+	location.Place(parent)
+
+	err = h.checkName(location)
+	if err != nil {
+		return err
+	}
+
+	err = h.locationRepo.InsertOne(location)
+	if err != nil {
+		log.Errorf("Failed to create location: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, location)
+}
+
+// update
+// @Tags Location
+// @Summary Rename a location or move it under another parent of the same kind
+// @ID update-location
+// @Security ApiKeyAuth
+// @Param id path string true "Location ID"
+// @Param body body locationForm true "Location Form"
+// @Router /api/location/{id} [PUT]
+// @Accept json
+// @Produce json
+// @Success 200
+func (h *LocationHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	location, err := h.findLocation(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	f, err := newLocationForm(c)
+	if err != nil {
+		return err
+	}
+
+	if f.Kind != "" && f.Kind != location.Kind {
+		return echo.NewHTTPError(http.StatusBadRequest, "Kind of a location cannot be changed")
+	}
+
+	parent, err := h.findParent(location.Kind, f.ParentID)
+	if err != nil {
+		return err
+	}
+
+	renamed := location.Name != f.Name
+	path := location.Path
+
+	location.Name = f.Name
+	location.Aliases = f.Aliases
+	location.Place(parent)
+	location.Updated = nc.Claims.ByAtPtr()
+
+	err = h.checkName(location)
+	if err != nil {
+		return err
+	}
+
+	err = h.locationRepo.UpdateOneByID(location.ID, location)
+	if err != nil {
+		log.Errorf("Failed to update location: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	if location.Path != path {
+		err = h.replaceDescendants(location)
+		if err != nil {
+			log.Errorf("Failed to update locations under %s: %v", location.Path, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+
+	if renamed {
+		err = h.deviceRepo.SetLokasi(location.ID, location.Name)
+		if err != nil {
+			log.Errorf("Failed to rename lokasi of devices at %s: %v", location.Path, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+	return c.JSON(http.StatusOK, location)
+}
+
+// delete
+// @Tags Location
+// @Summary Delete a location without locations or devices under it
+// @ID delete-location
+// @Security ApiKeyAuth
+// @Param id path string true "Location ID"
+// @Router /api/location/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *LocationHandler) delete(c echo.Context) error {
+	location, err := h.findLocation(c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	children, err := h.locationRepo.CountChildren(location.ID)
+	if err != nil {
+		log.Errorf("Failed to count locations under %s: %v", location.Path, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if children > 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Location still has %d locations under it", children))
+	}
+
+	devices, err := h.deviceRepo.CountByLocation([]bson.ObjectID{location.ID})
+	if err != nil {
+		log.Errorf("Failed to count devices at %s: %v", location.Path, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if devices > 0 {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Location still has %d devices", devices))
+	}
+
+	err = h.locationRepo.DeleteOneByID(location.ID)
+	if err != nil {
+		log.Errorf("Failed to delete location: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Location deleted")
+}
+
+// migrate
+// @Tags Location
+// @Summary Map the free text lokasi of devices without a location to locations
+// @Description Lokasi matching the name, alias or path of exactly one location, ignoring case and spacing, reference that location. The lokasi values left unmatched are reported.
+// @ID migrate-locations
+// @Security ApiKeyAuth
+// @Router /api/locations/migrate [POST]
+// @Produce json
+// @Success 200
+func (h *LocationHandler) migrate(c echo.Context) error {
+	report, err := migration.MapLocations(h.db)
+	if err != nil {
+		log.Errorf("Failed to map lokasi to locations: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *LocationHandler) findLocation(id string) (*repo.Location, error) {
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid location ID")
+	}
+
+	location, err := h.locationRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Location not found")
+		}
+		log.Errorf("Failed to get location: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return location, nil
+}
+
+// findParent returns the parent of a location of the given kind, checking it
+// is a site for areas and an area for rooms. Sites have no parent.
+func (h *LocationHandler) findParent(kind, parentID string) (*repo.Location, error) {
+	parentKind, ok := _const.LocationParents[kind]
+	if !ok {
+		if parentID != "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "A site cannot have a parent")
+		}
+		return nil, nil
+	}
+
+	if parentID == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Parent %s is required", parentKind))
+	}
+
+	parent, err := h.findLocation(parentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Kind != parentKind {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Parent of a %s must be a %s", kind, parentKind))
+	}
+	return parent, nil
+}
+
+// checkName rejects a location named like another one under the same parent.
+func (h *LocationHandler) checkName(location *repo.Location) error {
+	exists, err := h.locationRepo.ExistsName(location.ParentID, location.Name, location.ID)
+	if err != nil {
+		log.Errorf("Failed to check location name: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if exists {
+		return echo.NewHTTPError(http.StatusConflict, location.Path+" already exists")
+	}
+	return nil
+}
+
+// replaceDescendants updates the ancestors and path of the locations under a
+// location that was renamed or moved.
+func (h *LocationHandler) replaceDescendants(location *repo.Location) error {
+	descendants, err := h.locationRepo.FindDescendants(location.ID)
+	if err != nil {
+		return err
+	}
+
+	placed := map[bson.ObjectID]*repo.Location{location.ID: location}
+	for i := range descendants {
+		d := &descendants[i]
+		parent, ok := placed[*d.ParentID]
+		if !ok {
+			continue
+		}
+
+		d.Place(parent)
+		err = h.locationRepo.UpdateOneByID(d.ID, d)
+		if err != nil {
+			return err
+		}
+		placed[d.ID] = d
+	}
+	return nil
+}
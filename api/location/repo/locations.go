@@ -0,0 +1,309 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"regexp"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+	"time"
+)
+
+// PathSeparator joins the names of a location and its ancestors in Path.
+const PathSeparator = " / "
+
+// Location is a site, an area or building of a site, or a room of an area.
+// Ancestors holds the ids from the site down to the parent, so the subtree of
+// a location is found with a single query. Aliases are other spellings of the
+// name matched when mapping free text lokasi.
+type Location struct {
+	ID         bson.ObjectID   `json:"_id" bson:"_id"`
+	Name       string          `json:"name" bson:"name"`
+	Kind       string          `json:"kind" bson:"kind"`
+	ParentID   *bson.ObjectID  `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Ancestors  []bson.ObjectID `json:"ancestors" bson:"ancestors"`
+	Path       string          `json:"path" bson:"path"`
+	Aliases    []string        `json:"aliases,omitempty" bson:"aliases,omitempty"`
+	Inserted   doc.ByAt        `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated    *doc.ByAt       `json:"updated,omitempty" bson:"updated,omitempty"`
+	ModifiedAt time.Time       `json:"modified_at" bson:"modified_at"`
+	IsDeleted  bool            `json:"-" bson:"is_deleted"`
+}
+
+// Ref returns the id devices store to reference the location, or nil for no
+// location.
+func (l *Location) Ref() *bson.ObjectID {
+	if l == nil {
+		return nil
+	}
+	return &l.ID
+}
+
+// Place sets the ancestors and path of the location under parent, or as a
+// root when parent is nil.
+func (l *Location) Place(parent *Location) {
+	if parent == nil {
+		l.ParentID = nil
+		l.Ancestors = []bson.ObjectID{}
+		l.Path = l.Name
+		return
+	}
+	l.ParentID = &parent.ID
+	l.Ancestors = append(append([]bson.ObjectID{}, parent.Ancestors...), parent.ID)
+	l.Path = parent.Path + PathSeparator + l.Name
+}
+
+type LocationQuery struct {
+	*util.CommonQuery
+	Kind     string
+	ParentID *bson.ObjectID
+}
+
+type LocationCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewLocationRepository(db *mongo.Database) *LocationCollRepository {
+	return &LocationCollRepository{
+		coll: db.Collection("locations"),
+	}
+}
+
+// EnsureIndexes creates the indexes of the locations collection, ancestors
+// serving the subtree queries.
+func (r *LocationCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Field("ancestors"))
+}
+
+func (r *LocationCollRepository) filter(lq *LocationQuery) bson.M {
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if len(lq.Q) > 0 {
+		var pattern = bson.Regex{Pattern: lq.Q, Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"path": bson.M{"$regex": pattern}},
+			bson.M{"aliases": bson.M{"$regex": pattern}},
+		}
+	}
+
+	if lq.Kind != "" {
+		filter["kind"] = lq.Kind
+	}
+
+	if lq.ParentID != nil {
+		filter["parent_id"] = *lq.ParentID
+	}
+	return filter
+}
+
+func (r *LocationCollRepository) FindAll(lq *LocationQuery) (*[]Location, error) {
+	var locations []Location
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"path": lq.Sort}, lq.Page, lq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), r.filter(lq), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &locations)
+	if err != nil {
+		return nil, err
+	}
+	if locations == nil {
+		return &[]Location{}, nil
+	}
+	return &locations, nil
+}
+
+func (r *LocationCollRepository) CountQuery(lq *LocationQuery) (int64, error) {
+	count, err := r.coll.CountDocuments(context.TODO(), r.filter(lq))
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindAllSorted returns every location, parents before their children.
+func (r *LocationCollRepository) FindAllSorted() ([]Location, error) {
+	var locations []Location
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "path", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &locations)
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+func (r *LocationCollRepository) FindOneByID(id bson.ObjectID) (*Location, error) {
+	var location Location
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&location)
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// FindDescendants returns the locations under the given one, parents before
+// their children.
+func (r *LocationCollRepository) FindDescendants(id bson.ObjectID) ([]Location, error) {
+	var locations []Location
+	filter := bson.M{
+		"ancestors":  id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "path", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &locations)
+	if err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// FindSubtreeIDs returns the id of the location and of every location under
+// it, or mongo.ErrNoDocuments when the location does not exist.
+func (r *LocationCollRepository) FindSubtreeIDs(id bson.ObjectID) ([]bson.ObjectID, error) {
+	filter := bson.M{
+		"$or":        bson.A{bson.M{"_id": id}, bson.M{"ancestors": id}},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var records []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err = cur.All(context.TODO(), &records)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	ids := make([]bson.ObjectID, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	return ids, nil
+}
+
+// ExistsName reports whether another location under the same parent already
+// has the name, ignoring case.
+func (r *LocationCollRepository) ExistsName(parentID *bson.ObjectID, name string, exclude bson.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":        bson.M{"$ne": exclude},
+		"name":       bson.Regex{Pattern: "^" + regexp.QuoteMeta(strings.TrimSpace(name)) + "$", Options: "i"},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	if parentID != nil {
+		filter["parent_id"] = *parentID
+	} else {
+		filter["parent_id"] = bson.M{"$exists": false}
+	}
+
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountChildren returns the number of locations directly under the given one.
+func (r *LocationCollRepository) CountChildren(id bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"parent_id":  id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *LocationCollRepository) InsertOne(location *Location) error {
+	location.ModifiedAt = doc.Stamp()
+	_, err := r.coll.InsertOne(context.TODO(), location)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LocationCollRepository) UpdateOneByID(id bson.ObjectID, location *Location) error {
+	location.ModifiedAt = doc.Stamp()
+
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": location,
+	}
+	if location.ParentID == nil {
+		update["$unset"] = bson.M{"parent_id": ""}
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LocationCollRepository) DeleteOneByID(id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  true,
+			"modified_at": doc.Stamp(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
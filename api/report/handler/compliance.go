@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type complianceRow struct {
+	Device         string                `json:"device"`
+	Lokasi         string                `json:"lokasi"`
+	Total          int                   `json:"total"`
+	Maintained     int                   `json:"maintained"`
+	MaintainedRate float64               `json:"maintained_rate"`
+	Checkpoints    int64                 `json:"checkpoints"`
+	Failed         int64                 `json:"failed"`
+	FailedRate     float64               `json:"failed_rate"`
+	Missing        []repo2.DeviceSummary `json:"missing"`
+}
+
+func (r *complianceRow) add(summary repo2.DeviceSummary, stat repo3.DocStat, maintained bool) {
+	r.Total++
+	if !maintained {
+		r.Missing = append(r.Missing, summary)
+		return
+	}
+	r.Maintained++
+	r.Checkpoints += stat.Checkpoints
+	r.Failed += stat.Failed
+}
+
+func (r *complianceRow) rate() {
+	if r.Total > 0 {
+		r.MaintainedRate = float64(r.Maintained) / float64(r.Total)
+	}
+	if r.Checkpoints > 0 {
+		r.FailedRate = float64(r.Failed) / float64(r.Checkpoints)
+	}
+}
+
+type complianceReport struct {
+	From      time.Time        `json:"from"`
+	To        time.Time        `json:"to"`
+	Types     []*complianceRow `json:"types"`
+	Locations []*complianceRow `json:"locations"`
+}
+
+type ReportHandler struct {
+	deviceRepo *repo2.DeviceRepository
+	docRepo    *repo3.DocRepository
+}
+
+func NewReportAPIHandler(e *echo.Echo, db *mongo.Database) *ReportHandler {
+	h := &ReportHandler{
+		deviceRepo: repo2.NewDeviceRepository(db),
+		docRepo:    repo3.NewDocRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/report/compliance", h.compliance)
+	group.GET("/report/checkpoint-failures", h.checkpointFailures)
+	group.GET("/report/rising-failures", h.risingFailures)
+	group.GET("/report/failing-devices", h.failingDevices)
+
+	return h
+}
+
+// newPeriod parses the from and to query params as an inclusive date range,
+// defaulting to the current month. The returned end is exclusive.
+func newPeriod(c echo.Context) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := from.AddDate(0, 1, 0)
+
+	if s := strings.TrimSpace(c.QueryParam("from")); s != "" {
+		t, err := time.ParseInLocation(dateLayout, s, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date")
+		}
+		from = t
+		to = from.AddDate(0, 1, 0)
+	}
+	if s := strings.TrimSpace(c.QueryParam("to")); s != "" {
+		t, err := time.ParseInLocation(dateLayout, s, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid date range")
+	}
+	return from, to, nil
+}
+
+// compliance
+// @Tags Report
+// @Summary Get maintenance compliance per device type and lokasi
+// @ID get-compliance-report
+// @Security ApiKeyAuth
+// @Param from query string false "Start date (YYYY-MM-DD), default first day of this month"
+// @Param to query string false "End date inclusive (YYYY-MM-DD), default one month after from"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param q query string false "Search by lokasi"
+// @Param format query string false "Output format" enums(json,csv)
+// @Router /api/report/compliance [GET]
+// @Produce json
+// @Produce text/csv
+// @Success 200
+func (h *ReportHandler) compliance(c echo.Context) error {
+	cq := util.NewCommonQuery(c)
+
+	from, to, err := newPeriod(c)
+	if err != nil {
+		return err
+	}
+
+	types := _const.Devices
+	if cq.Device != "" {
+		types = []string{cq.Device}
+	}
+
+	report := &complianceReport{
+		From:      from,
+		To:        to,
+		Types:     []*complianceRow{},
+		Locations: []*complianceRow{},
+	}
+
+	for _, device := range types {
+		summaries, err := h.deviceRepo.FindAllSummary(device)
+		if err != nil {
+			log.Errorf("Failed to get %s devices: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		stats, err := h.docRepo.DocStats(device, from, to)
+		if err != nil {
+			log.Errorf("Failed to get %s doc stats: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		typeRow := &complianceRow{Device: device, Missing: []repo2.DeviceSummary{}}
+		locations := make(map[string]*complianceRow)
+		for _, summary := range summaries {
+			if !summary.Scheduled() {
+				continue
+			}
+			if cq.Q != "" && !strings.Contains(strings.ToLower(summary.Lokasi), cq.Q) {
+				continue
+			}
+
+			stat, maintained := stats[summary.ID]
+			typeRow.add(summary, stat, maintained)
+
+			locRow, ok := locations[summary.Lokasi]
+			if !ok {
+				locRow = &complianceRow{Device: device, Lokasi: summary.Lokasi, Missing: []repo2.DeviceSummary{}}
+				locations[summary.Lokasi] = locRow
+			}
+			locRow.add(summary, stat, maintained)
+		}
+
+		typeRow.rate()
+		report.Types = append(report.Types, typeRow)
+
+		locRows := make([]*complianceRow, 0, len(locations))
+		for _, row := range locations {
+			row.rate()
+			locRows = append(locRows, row)
+		}
+		sort.Slice(locRows, func(i, j int) bool {
+			return locRows[i].Lokasi < locRows[j].Lokasi
+		})
+		report.Locations = append(report.Locations, locRows...)
+	}
+
+	if strings.ToLower(c.QueryParam("format")) == "csv" {
+		return writeComplianceCSV(c, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func writeComplianceCSV(c echo.Context, report *complianceReport) error {
+	filename := fmt.Sprintf("compliance_%s_%s.csv",
+		report.From.Format(dateLayout), report.To.AddDate(0, 0, -1).Format(dateLayout))
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	w := csv.NewWriter(c.Response())
+	_ = w.Write([]string{
+		"Device", "Lokasi", "Total Devices", "Maintained", "Maintained %",
+		"Checkpoints", "Failed", "Failed %", "Missing Devices",
+	})
+
+	rows := append(append([]*complianceRow{}, report.Types...), report.Locations...)
+	for i, row := range rows {
+		lokasi := row.Lokasi
+		if i < len(report.Types) {
+			lokasi = "(all)"
+		}
+
+		missing := make([]string, 0, len(row.Missing))
+		for _, m := range row.Missing {
+			missing = append(missing, m.Nama)
+		}
+
+		_ = w.Write([]string{
+			row.Device,
+			lokasi,
+			strconv.Itoa(row.Total),
+			strconv.Itoa(row.Maintained),
+			strconv.FormatFloat(row.MaintainedRate*100, 'f', 2, 64),
+			strconv.FormatInt(row.Checkpoints, 10),
+			strconv.FormatInt(row.Failed, 10),
+			strconv.FormatFloat(row.FailedRate*100, 'f', 2, 64),
+			strings.Join(missing, "; "),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	monthLayout    = "2006-01"
+	maxMonths      = 36
+	minTrendMonths = 3
+)
+
+// failureSeries holds the monthly results of a checkpoint aligned with the
+// months of the trend. Rate is null for months without results.
+type failureSeries struct {
+	Device     string     `json:"device"`
+	Checkpoint string     `json:"checkpoint"`
+	Total      []int64    `json:"total"`
+	Failed     []int64    `json:"failed"`
+	Rate       []*float64 `json:"rate"`
+}
+
+type failureTrend struct {
+	Months []string         `json:"months"`
+	Series []*failureSeries `json:"series"`
+}
+
+// risingFailure is a checkpoint whose failure rate rises. Slope is the least
+// squares change of the rate per month, Change the difference between the
+// last and the first month with results.
+type risingFailure struct {
+	*failureSeries
+	Slope  float64 `json:"slope"`
+	Change float64 `json:"change"`
+}
+
+type risingFailureReport struct {
+	Months []string         `json:"months"`
+	Series []*risingFailure `json:"series"`
+}
+
+type failingDevice struct {
+	repo2.DeviceSummary
+	repo3.DeviceFailureStat
+}
+
+type failingDeviceReport struct {
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Devices []failingDevice `json:"devices"`
+}
+
+// newMonthRange returns the months of the months query param, by default 12,
+// ending with the until month, by default the current one. The returned end
+// is exclusive.
+func newMonthRange(c echo.Context, defaultMonths int) ([]string, time.Time, time.Time, error) {
+	now := time.Now()
+	until := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	if s := strings.TrimSpace(c.QueryParam("until")); s != "" {
+		t, err := time.ParseInLocation(monthLayout, s, now.Location())
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid until month")
+		}
+		until = t
+	}
+
+	n := defaultMonths
+	if s := strings.TrimSpace(c.QueryParam("months")); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > maxMonths {
+			return nil, time.Time{}, time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Months must be between 1 and 36")
+		}
+		n = v
+	}
+
+	from := until.AddDate(0, 1-n, 0)
+	months := make([]string, 0, n)
+	for m := from; !m.After(until); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format(monthLayout))
+	}
+	return months, from, until.AddDate(0, 1, 0), nil
+}
+
+// deviceTypes returns the device types of the device query param, or all.
+func deviceTypes(c echo.Context) ([]string, error) {
+	device := strings.ToLower(strings.TrimSpace(c.QueryParam("device")))
+	if device == "" {
+		return _const.Devices, nil
+	}
+	if !_const.ValidDevice(device) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+	return []string{device}, nil
+}
+
+// checkpointSeries aggregates the monthly checkpoint results of the types into
+// one series per device type and checkpoint.
+func (h *ReportHandler) checkpointSeries(types, months []string, from, to time.Time, checkpoint string) ([]*failureSeries, error) {
+	index := make(map[string]int, len(months))
+	for i, m := range months {
+		index[m] = i
+	}
+
+	var result []*failureSeries
+	for _, device := range types {
+		stats, err := h.docRepo.CheckpointMonthStats(device, from, to, checkpoint)
+		if err != nil {
+			log.Errorf("Failed to get %s checkpoint stats: %v", device, err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+
+		series := make(map[string]*failureSeries)
+		for _, stat := range stats {
+			i, ok := index[stat.Month]
+			if !ok {
+				continue
+			}
+
+			s, ok := series[stat.Checkpoint]
+			if !ok {
+				s = &failureSeries{
+					Device:     device,
+					Checkpoint: stat.Checkpoint,
+					Total:      make([]int64, len(months)),
+					Failed:     make([]int64, len(months)),
+					Rate:       make([]*float64, len(months)),
+				}
+				series[stat.Checkpoint] = s
+				result = append(result, s)
+			}
+			s.Total[i] += stat.Total
+			s.Failed[i] += stat.Failed
+		}
+	}
+
+	for _, s := range result {
+		for i := range months {
+			if s.Total[i] > 0 {
+				rate := float64(s.Failed[i]) / float64(s.Total[i])
+				s.Rate[i] = &rate
+			}
+		}
+	}
+	return result, nil
+}
+
+// checkpointFailures
+// @Tags Report
+// @Summary Get the monthly failure rate of every checkpoint per device type
+// @ID get-checkpoint-failure-trend
+// @Security ApiKeyAuth
+// @Param months query int false "Number of months" default(12)
+// @Param until query string false "Last month (YYYY-MM), default this month"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param checkpoint query string false "Only this checkpoint"
+// @Router /api/report/checkpoint-failures [GET]
+// @Produce json
+// @Success 200
+func (h *ReportHandler) checkpointFailures(c echo.Context) error {
+	months, from, to, err := newMonthRange(c, 12)
+	if err != nil {
+		return err
+	}
+
+	types, err := deviceTypes(c)
+	if err != nil {
+		return err
+	}
+
+	series, err := h.checkpointSeries(types, months, from, to, strings.TrimSpace(c.QueryParam("checkpoint")))
+	if err != nil {
+		return err
+	}
+
+	if series == nil {
+		series = []*failureSeries{}
+	}
+	return c.JSON(http.StatusOK, &failureTrend{
+		Months: months,
+		Series: series,
+	})
+}
+
+// risingFailures
+// @Tags Report
+// @Summary Get the checkpoints whose failure rate is rising
+// @ID get-rising-checkpoint-failures
+// @Security ApiKeyAuth
+// @Param months query int false "Number of months" default(6)
+// @Param until query string false "Last month (YYYY-MM), default this month"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Router /api/report/rising-failures [GET]
+// @Produce json
+// @Success 200
+func (h *ReportHandler) risingFailures(c echo.Context) error {
+	months, from, to, err := newMonthRange(c, 6)
+	if err != nil {
+		return err
+	}
+
+	types, err := deviceTypes(c)
+	if err != nil {
+		return err
+	}
+
+	series, err := h.checkpointSeries(types, months, from, to, "")
+	if err != nil {
+		return err
+	}
+
+	report := &risingFailureReport{
+		Months: months,
+		Series: []*risingFailure{},
+	}
+	for _, s := range series {
+		slope, change, ok := trend(s.Rate)
+		if ok && slope > 0 {
+			report.Series = append(report.Series, &risingFailure{
+				failureSeries: s,
+				Slope:         slope,
+				Change:        change,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Series, func(i, j int) bool {
+		return report.Series[i].Slope > report.Series[j].Slope
+	})
+	return c.JSON(http.StatusOK, report)
+}
+
+// trend fits a line through the months with results, reporting false when
+// fewer than minTrendMonths months have results.
+func trend(rates []*float64) (float64, float64, bool) {
+	var n, sumX, sumY, sumXY, sumXX float64
+	var first, last *float64
+	for i, rate := range rates {
+		if rate == nil {
+			continue
+		}
+		if first == nil {
+			first = rate
+		}
+		last = rate
+
+		x := float64(i)
+		n++
+		sumX += x
+		sumY += *rate
+		sumXY += x * *rate
+		sumXX += x * x
+	}
+
+	if n < minTrendMonths {
+		return 0, 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	return slope, *last - *first, true
+}
+
+// failingDevices
+// @Tags Report
+// @Summary Get the devices with the most failed checkpoints
+// @ID get-failing-devices
+// @Security ApiKeyAuth
+// @Param months query int false "Number of months" default(12)
+// @Param until query string false "Last month (YYYY-MM), default this month"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param limit query int false "Number of devices" default(10)
+// @Router /api/report/failing-devices [GET]
+// @Produce json
+// @Success 200
+func (h *ReportHandler) failingDevices(c echo.Context) error {
+	_, from, to, err := newMonthRange(c, 12)
+	if err != nil {
+		return err
+	}
+
+	types, err := deviceTypes(c)
+	if err != nil {
+		return err
+	}
+
+	limit := 10
+	if s := strings.TrimSpace(c.QueryParam("limit")); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > 100 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Limit must be between 1 and 100")
+		}
+	}
+
+	var devices []failingDevice
+	for _, device := range types {
+		stats, err := h.docRepo.DeviceFailureStats(device, from, to, int64(limit))
+		if err != nil {
+			log.Errorf("Failed to get %s device failure stats: %v", device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		for _, stat := range stats {
+			devices = append(devices, failingDevice{
+				DeviceSummary:     repo2.DeviceSummary{Device: device, ID: stat.DeviceID},
+				DeviceFailureStat: stat,
+			})
+		}
+	}
+
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].Failed != devices[j].Failed {
+			return devices[i].Failed > devices[j].Failed
+		}
+		return devices[i].LastFailed.After(devices[j].LastFailed)
+	})
+	if len(devices) > limit {
+		devices = devices[:limit]
+	}
+
+	for i := range devices {
+		summary, err := h.deviceRepo.FindOneSummary(devices[i].Device, devices[i].ID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue
+			}
+			log.Errorf("Failed to get %s device: %v", devices[i].Device, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		devices[i].DeviceSummary = *summary
+	}
+
+	if devices == nil {
+		devices = []failingDevice{}
+	}
+	return c.JSON(http.StatusOK, &failingDeviceReport{
+		From:    from,
+		To:      to,
+		Devices: devices,
+	})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"math"
+	"testing"
+)
+
+func rates(values ...float64) []*float64 {
+	r := make([]*float64, len(values))
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			r[i] = &v
+		}
+	}
+	return r
+}
+
+func TestTrend(t *testing.T) {
+	none := math.NaN()
+
+	tests := []struct {
+		name   string
+		rates  []*float64
+		slope  float64
+		change float64
+		ok     bool
+	}{
+		{name: "rising", rates: rates(10, 20, 30), slope: 10, change: 20, ok: true},
+		{name: "falling", rates: rates(30, 20, 10), slope: -10, change: -20, ok: true},
+		{name: "flat", rates: rates(5, 5, 5, 5), slope: 0, change: 0, ok: true},
+		{name: "months without results skipped", rates: rates(10, none, 30, 40), slope: 10, change: 30, ok: true},
+		{name: "noisy rise", rates: rates(0, 2, 1, 3), slope: 0.8, change: 3, ok: true},
+		{name: "fewer than min months", rates: rates(10, none, 20), ok: false},
+		{name: "leading and trailing gaps", rates: rates(none, 10, 20, none), ok: false},
+		{name: "no results", rates: rates(none, none, none, none), ok: false},
+		{name: "empty", rates: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slope, change, ok := trend(tt.rates)
+			if ok != tt.ok {
+				t.Fatalf("trend() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(slope-tt.slope) > 1e-9 {
+				t.Errorf("slope = %v, want %v", slope, tt.slope)
+			}
+			if math.Abs(change-tt.change) > 1e-9 {
+				t.Errorf("change = %v, want %v", change, tt.change)
+			}
+		})
+	}
+}
+
+func TestTrendMinMonths(t *testing.T) {
+	values := make([]float64, minTrendMonths)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	if _, _, ok := trend(rates(values[:minTrendMonths-1]...)); ok {
+		t.Errorf("trend() of %d months ok, want fewer than minTrendMonths refused", minTrendMonths-1)
+	}
+	if _, _, ok := trend(rates(values...)); !ok {
+		t.Errorf("trend() of %d months refused, want minTrendMonths accepted", minTrendMonths)
+	}
+}
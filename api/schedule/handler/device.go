@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	"sipamit-be/api/schedule/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/log"
+	"time"
+)
+
+type scheduleDevice struct {
+	repo2.DeviceSummary
+	Interval int        `json:"interval"`
+	LastDoc  *time.Time `json:"last_doc"`
+	NextDue  time.Time  `json:"next_due"`
+	Overdue  bool       `json:"overdue"`
+}
+
+// scheduleDevices lists the devices of the given type, or of every type when
+// device is empty, with their next due date computed from the configured
+// interval. Devices never maintained are due one interval after insertion.
+// Devices that are not active are left out.
+func (h *ScheduleHandler) scheduleDevices(device string) ([]scheduleDevice, error) {
+	schedules, err := h.scheduleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := make(map[string]int)
+	overrides := make(map[bson.ObjectID]int)
+	for _, s := range *schedules {
+		if s.DeviceID != nil {
+			overrides[*s.DeviceID] = s.Interval
+			continue
+		}
+		intervals[s.Device] = s.Interval
+	}
+
+	types := _const.Devices
+	if device != "" {
+		types = []string{device}
+	}
+
+	now := time.Now()
+	var result []scheduleDevice
+	for _, t := range types {
+		summaries, err := h.deviceRepo.FindAllSummary(t)
+		if err != nil {
+			return nil, err
+		}
+
+		lastDocs, err := h.docRepo.LastDocDates(t)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range summaries {
+			if !summary.Scheduled() {
+				continue
+			}
+
+			d := scheduleDevice{
+				DeviceSummary: summary,
+				Interval:      repo.DefaultInterval,
+			}
+			if interval, ok := intervals[d.Device]; ok {
+				d.Interval = interval
+			}
+			if interval, ok := overrides[d.ID]; ok {
+				d.Interval = interval
+			}
+
+			since := d.Inserted
+			if at, ok := lastDocs[d.ID]; ok {
+				d.LastDoc = &at
+				since = at
+			}
+			d.NextDue = since.AddDate(0, d.Interval, 0)
+			d.Overdue = now.After(d.NextDue)
+
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// locationScope returns the set of the location with the given id and every
+// location under it, or nil when id is empty.
+func (h *ScheduleHandler) locationScope(id string) (map[bson.ObjectID]bool, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	oId, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid location ID")
+	}
+
+	ids, err := h.locationRepo.FindSubtreeIDs(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Location not found")
+		}
+		log.Errorf("Failed to get location: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	scope := make(map[bson.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		scope[id] = true
+	}
+	return scope, nil
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/device/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	repo4 "sipamit-be/api/location/repo"
+	"sipamit-be/api/schedule/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type scheduleForm struct {
+	Interval int `form:"interval" json:"interval"`
+}
+
+func newScheduleForm(c echo.Context) (*scheduleForm, error) {
+	f := new(scheduleForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind schedule form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	if f.Interval < 1 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Interval is required")
+	}
+	return f, nil
+}
+
+type scheduleQuery struct {
+	*util.CommonQuery
+	Lokasi     string
+	Departemen string
+	Status     string
+	Days       int
+}
+
+func newScheduleQuery(c echo.Context) *scheduleQuery {
+	status := strings.ToLower(strings.TrimSpace(c.QueryParam("status")))
+	if status != "due" && status != "overdue" {
+		status = ""
+	}
+
+	days, err := strconv.Atoi(strings.TrimSpace(c.QueryParam("days")))
+	if err != nil || days < 0 {
+		days = 7
+	}
+
+	return &scheduleQuery{
+		CommonQuery: util.NewCommonQuery(c),
+		Lokasi:      strings.TrimSpace(c.QueryParam("lokasi")),
+		Departemen:  strings.TrimSpace(c.QueryParam("departemen")),
+		Status:      status,
+		Days:        days,
+	}
+}
+
+type ScheduleHandler struct {
+	scheduleRepo *repo.ScheduleCollRepository
+	deviceRepo   *repo2.DeviceRepository
+	docRepo      *repo3.DocRepository
+	locationRepo *repo4.LocationCollRepository
+}
+
+func NewScheduleAPIHandler(e *echo.Echo, db *mongo.Database) *ScheduleHandler {
+	h := &ScheduleHandler{
+		scheduleRepo: repo.NewScheduleRepository(db),
+		deviceRepo:   repo2.NewDeviceRepository(db),
+		docRepo:      repo3.NewDocRepository(db),
+		locationRepo: repo4.NewLocationRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/schedules", h.findAll)
+	group.GET("/schedule/devices", h.devices)
+
+	group.PUT("/schedule/:device", h.update, context.AdminOrSuperAdminOnly)
+	group.PUT("/schedule/:device/:id", h.updateDevice, context.AdminOrSuperAdminOnly)
+
+	group.DELETE("/schedule/:device/:id", h.deleteDevice, context.AdminOrSuperAdminOnly)
+
+	return h
+}
+
+// findAll
+// @Tags Schedule
+// @Summary Get all maintenance schedules
+// @ID get-all-schedules
+// @Security ApiKeyAuth
+// @Router /api/schedules [GET]
+// @Produce json
+// @Success 200
+func (h *ScheduleHandler) findAll(c echo.Context) error {
+	schedules, err := h.scheduleRepo.FindAll()
+	if err != nil {
+		log.Errorf("Failed to get schedules: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// devices
+// @Tags Schedule
+// @Summary Get devices with their last doc, next due date and overdue status
+// @ID get-schedule-devices
+// @Security ApiKeyAuth
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param lokasi query string false "Filter by lokasi"
+// @Param location query string false "Filter by location ID, including the locations under it"
+// @Param departemen query string false "Filter by departemen"
+// @Param status query string false "Filter by status" enums(due,overdue)
+// @Param days query int false "Days ahead counted as due" default(7)
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Router /api/schedule/devices [GET]
+// @Produce json
+// @Success 200
+func (h *ScheduleHandler) devices(c echo.Context) error {
+	sq := newScheduleQuery(c)
+
+	locations, err := h.locationScope(sq.Location)
+	if err != nil {
+		return err
+	}
+
+	devices, err := h.scheduleDevices(sq.Device)
+	if err != nil {
+		log.Errorf("Failed to get schedule devices: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	now := time.Now()
+	dueBefore := now.AddDate(0, 0, sq.Days)
+
+	filtered := make([]scheduleDevice, 0, len(devices))
+	for _, d := range devices {
+		if sq.Lokasi != "" && !strings.EqualFold(d.Lokasi, sq.Lokasi) {
+			continue
+		}
+		if locations != nil && (d.LocationID == nil || !locations[*d.LocationID]) {
+			continue
+		}
+		if sq.Departemen != "" && !strings.EqualFold(d.Departemen, sq.Departemen) {
+			continue
+		}
+		if sq.Status == "overdue" && !d.Overdue {
+			continue
+		}
+		if sq.Status == "due" && d.NextDue.After(dueBefore) {
+			continue
+		}
+		filtered = append(filtered, d)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].NextDue.Before(filtered[j].NextDue)
+	})
+
+	start, end := util.PageBounds(len(filtered), sq.Page, sq.Limit)
+	result := util.MakeResult(filtered[start:end], int64(len(filtered)), sq.Page, sq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// update
+// @Tags Schedule
+// @Summary Set maintenance interval of a device type
+// @ID update-schedule
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param body body scheduleForm true "Schedule Form"
+// @Router /api/schedule/{device} [PUT]
+// @Produce json
+// @Success 200
+func (h *ScheduleHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	f, err := newScheduleForm(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.scheduleRepo.UpsertByDevice(device, f.Interval, nc.Claims.ByAtPtr())
+	if err != nil {
+		log.Errorf("Failed to update %s schedule: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Schedule updated")
+}
+
+// updateDevice
+// @Tags Schedule
+// @Summary Override maintenance interval of a device
+// @ID update-schedule-device
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Param body body scheduleForm true "Schedule Form"
+// @Router /api/schedule/{device}/{id} [PUT]
+// @Produce json
+// @Success 200
+func (h *ScheduleHandler) updateDevice(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device ID")
+	}
+
+	f, err := newScheduleForm(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.deviceRepo.FindOneSummary(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Device not found")
+		}
+		log.Errorf("Failed to get %s: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	err = h.scheduleRepo.UpsertByDeviceID(device, oId, f.Interval, nc.Claims.ByAtPtr())
+	if err != nil {
+		log.Errorf("Failed to update %s schedule: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Schedule updated")
+}
+
+// deleteDevice
+// @Tags Schedule
+// @Summary Remove maintenance interval override of a device
+// @ID delete-schedule-device
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Router /api/schedule/{device}/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *ScheduleHandler) deleteDevice(c echo.Context) error {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device ID")
+	}
+
+	deleted, err := h.scheduleRepo.DeleteByDeviceID(oId)
+	if err != nil {
+		log.Errorf("Failed to delete schedule: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if deleted == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Schedule not found")
+	}
+	return c.JSON(http.StatusOK, "Schedule deleted")
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/doc"
+)
+
+// DefaultInterval is used for device types without a configured schedule.
+const DefaultInterval = 1
+
+// Schedule is the preventive maintenance interval, in months, of a device
+// type. When DeviceID is set it overrides the interval of a single device.
+type Schedule struct {
+	ID       bson.ObjectID  `json:"_id" bson:"_id"`
+	Device   string         `json:"device" bson:"device"`
+	DeviceID *bson.ObjectID `json:"device_id,omitempty" bson:"device_id,omitempty"`
+	Interval int            `json:"interval" bson:"interval"`
+	Updated  *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+}
+
+type ScheduleCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewScheduleRepository(db *mongo.Database) *ScheduleCollRepository {
+	return &ScheduleCollRepository{
+		coll: db.Collection("schedules"),
+	}
+}
+
+func (r *ScheduleCollRepository) FindAll() (*[]Schedule, error) {
+	var schedules []Schedule
+	filter := bson.M{}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "device", Value: 1}, {Key: "device_id", Value: 1}})
+
+	cur, err := r.coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &schedules)
+	if err != nil {
+		return nil, err
+	}
+	if schedules == nil {
+		return &[]Schedule{}, nil
+	}
+	return &schedules, nil
+}
+
+func (r *ScheduleCollRepository) FindByDeviceID(deviceID bson.ObjectID) (*Schedule, error) {
+	var schedule Schedule
+	filter := bson.M{
+		"device_id": deviceID,
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *ScheduleCollRepository) InsertMany(schedules []Schedule) error {
+	_, err := r.coll.InsertMany(context.TODO(), schedules)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpsertByDevice sets the interval of a device type.
+func (r *ScheduleCollRepository) UpsertByDevice(device string, interval int, updated *doc.ByAt) error {
+	filter := bson.M{
+		"device":    device,
+		"device_id": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"interval": interval,
+			"updated":  updated,
+		},
+		"$setOnInsert": bson.M{
+			"_id": bson.NewObjectID(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpsertByDeviceID overrides the interval of a single device.
+func (r *ScheduleCollRepository) UpsertByDeviceID(device string, deviceID bson.ObjectID, interval int, updated *doc.ByAt) error {
+	filter := bson.M{
+		"device_id": deviceID,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"device":   device,
+			"interval": interval,
+			"updated":  updated,
+		},
+		"$setOnInsert": bson.M{
+			"_id": bson.NewObjectID(),
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ScheduleCollRepository) DeleteByDeviceID(deviceID bson.ObjectID) (int64, error) {
+	filter := bson.M{
+		"device_id": deviceID,
+	}
+
+	res, err := r.coll.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func (r *ScheduleCollRepository) Count() (int64, error) {
+	filter := bson.M{}
+	count, err := r.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
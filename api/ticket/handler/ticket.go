@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	repo2 "sipamit-be/api/app/repo"
+	"sipamit-be/api/ticket/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+)
+
+type ticketAssignForm struct {
+	AssigneeID string `form:"assignee_id" json:"assignee_id"`
+
+	AssigneeOID bson.ObjectID `form:"-" json:"-"`
+}
+
+func newTicketAssignForm(c echo.Context) (*ticketAssignForm, error) {
+	f := new(ticketAssignForm)
+	err := c.Bind(f)
+	if err != nil {
+		log.Errorf("Failed to bind ticket assign form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	if f.AssigneeID == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Assignee is required")
+	}
+
+	f.AssigneeOID, err = bson.ObjectIDFromHex(f.AssigneeID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid assignee id")
+	}
+	return f, nil
+}
+
+type ticketForm struct {
+	Status     string `form:"status" json:"status"`
+	Resolution string `form:"resolution" json:"resolution"`
+}
+
+func newTicketForm(c echo.Context) (*ticketForm, error) {
+	f := new(ticketForm)
+	if err := c.Bind(f); err != nil {
+		log.Errorf("Failed to bind ticket form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Status = strings.ToLower(strings.TrimSpace(f.Status))
+	f.Resolution = strings.TrimSpace(f.Resolution)
+
+	if !_const.ValidTicketStatus(f.Status) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid status")
+	}
+	if f.Status == _const.TicketResolved && f.Resolution == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Resolution is required")
+	}
+	return f, nil
+}
+
+func newTicketQuery(c echo.Context) (*repo.TicketQuery, error) {
+	tq := &repo.TicketQuery{
+		CommonQuery: util.NewCommonQuery(c),
+	}
+
+	if status := strings.ToLower(strings.TrimSpace(c.QueryParam("status"))); _const.ValidTicketStatus(status) {
+		tq.Status = status
+	}
+
+	if s := strings.TrimSpace(c.QueryParam("assignee_id")); s != "" {
+		oId, err := bson.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid assignee id")
+		}
+		tq.AssigneeID = &oId
+	}
+
+	if s := strings.TrimSpace(c.QueryParam("device_id")); s != "" {
+		oId, err := bson.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid device id")
+		}
+		tq.DeviceID = &oId
+	}
+	return tq, nil
+}
+
+type TicketHandler struct {
+	ticketRepo *repo.TicketCollRepository
+	userRepo   *repo2.UserCollRepository
+}
+
+func NewTicketAPIHandler(e *echo.Echo, db *mongo.Database) *TicketHandler {
+	h := &TicketHandler{
+		ticketRepo: repo.NewTicketRepository(db),
+		userRepo:   repo2.NewUserRepository(db),
+	}
+
+	group := e.Group("/api", context.Handler)
+
+	group.GET("/tickets", h.findAll)
+	group.GET("/ticket/:id", h.findByID)
+
+	group.PUT("/ticket/:id", h.update)
+	group.PUT("/ticket/:id/assign", h.assign, context.AdminOrSuperAdminOnly)
+
+	return h
+}
+
+// findAll
+// @Tags Ticket
+// @Summary Get all corrective maintenance tickets
+// @ID get-all-tickets
+// @Security ApiKeyAuth
+// @Param q query string false "Search by checkpoint"
+// @Param device query string false "Device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Param device_id query string false "Device ID"
+// @Param status query string false "Filter by status" enums(open,in_progress,waiting_parts,resolved)
+// @Param assignee_id query string false "Assigned technician ID"
+// @Param page query int false "Page number pagination" default(1)
+// @Param limit query int false "Limit pagination" default(10)
+// @Param sort query string false "Sort by date" enums(asc,desc)
+// @Router /api/tickets [GET]
+// @Produce json
+// @Success 200
+func (h *TicketHandler) findAll(c echo.Context) error {
+	tq, err := newTicketQuery(c)
+	if err != nil {
+		return err
+	}
+
+	tickets, err := h.ticketRepo.FindAll(tq)
+	if err != nil {
+		log.Errorf("Failed to get tickets: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	totalTickets, err := h.ticketRepo.CountQuery(tq)
+	if err != nil {
+		log.Errorf("Failed to count tickets: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	result := util.MakeResult(tickets, totalTickets, tq.Page, tq.Limit)
+	return c.JSON(http.StatusOK, result)
+}
+
+// findByID
+// @Tags Ticket
+// @Summary Get ticket by ID
+// @ID get-ticket-by-id
+// @Security ApiKeyAuth
+// @Param id path string true "Ticket ID"
+// @Router /api/ticket/{id} [GET]
+// @Produce json
+// @Success 200
+func (h *TicketHandler) findByID(c echo.Context) error {
+	ticket, err := h.findTicket(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, ticket)
+}
+
+// update
+// @Tags Ticket
+// @Summary Update ticket status and resolution notes
+// @ID update-ticket
+// @Security ApiKeyAuth
+// @Param id path string true "Ticket ID"
+// @Param body body ticketForm true "Ticket Form"
+// @Router /api/ticket/{id} [PUT]
+// @Produce json
+// @Success 200
+func (h *TicketHandler) update(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := newTicketForm(c)
+	if err != nil {
+		return err
+	}
+
+	ticket, err := h.findTicket(c)
+	if err != nil {
+		return err
+	}
+
+	if f.Resolution != "" {
+		ticket.Resolution = f.Resolution
+	}
+
+	if f.Status == _const.TicketResolved {
+		if ticket.Status != _const.TicketResolved {
+			ticket.Resolved = nc.Claims.ByAtPtr()
+		}
+	} else {
+		ticket.Resolved = nil
+		ticket.ResolvedDocID = nil
+	}
+
+	ticket.Status = f.Status
+	ticket.Updated = nc.Claims.ByAtPtr()
+	err = h.ticketRepo.UpdateOneByID(ticket.ID, ticket)
+	if err != nil {
+		if err := index.DuplicateError(err); err != nil {
+			return err
+		}
+		log.Errorf("Failed to update ticket: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, ticket)
+}
+
+// assign
+// @Tags Ticket
+// @Summary Assign ticket to a technician
+// @ID assign-ticket
+// @Security ApiKeyAuth
+// @Param id path string true "Ticket ID"
+// @Param body body ticketAssignForm true "Ticket Assign Form"
+// @Router /api/ticket/{id}/assign [PUT]
+// @Produce json
+// @Success 200
+func (h *TicketHandler) assign(c echo.Context) error {
+	nc := c.(*context.Context)
+
+	f, err := newTicketAssignForm(c)
+	if err != nil {
+		return err
+	}
+
+	ticket, err := h.findTicket(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.userRepo.FindByID(f.AssigneeOID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found")
+		}
+		log.Errorf("Failed to get user: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	ticket.AssigneeID = &f.AssigneeOID
+	if ticket.Status == _const.TicketOpen {
+		ticket.Status = _const.TicketInProgress
+	}
+	ticket.Updated = nc.Claims.ByAtPtr()
+	err = h.ticketRepo.UpdateOneByID(ticket.ID, ticket)
+	if err != nil {
+		log.Errorf("Failed to assign ticket: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, ticket)
+}
+
+func (h *TicketHandler) findTicket(c echo.Context) (*repo.Ticket, error) {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		log.Errorf("Failed to convert id to ObjectID: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	ticket, err := h.ticketRepo.FindOneByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Ticket not found")
+		}
+		log.Errorf("Failed to get ticket: %v", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return ticket, nil
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/util"
+	"time"
+)
+
+// Ticket is a corrective maintenance task opened for a checkpoint that failed
+// on a doc. A device has at most one unresolved ticket per checkpoint.
+type Ticket struct {
+	ID            bson.ObjectID  `json:"_id" bson:"_id"`
+	Device        string         `json:"device" bson:"device"`
+	DeviceID      bson.ObjectID  `json:"device_id" bson:"device_id"`
+	DocID         bson.ObjectID  `json:"doc_id" bson:"doc_id"`
+	DocAt         *time.Time     `json:"doc_at,omitempty" bson:"doc_at,omitempty"`
+	Checkpoint    string         `json:"checkpoint" bson:"checkpoint"`
+	Keterangan    string         `json:"keterangan" bson:"keterangan"`
+	Status        string         `json:"status" bson:"status"`
+	AssigneeID    *bson.ObjectID `json:"assignee_id,omitempty" bson:"assignee_id,omitempty"`
+	Resolution    string         `json:"resolution,omitempty" bson:"resolution"`
+	ResolvedDocID *bson.ObjectID `json:"resolved_doc_id,omitempty" bson:"resolved_doc_id"`
+	Resolved      *doc.ByAt      `json:"resolved,omitempty" bson:"resolved"`
+	Inserted      doc.ByAt       `json:"inserted,omitempty" bson:"inserted,omitempty"`
+	Updated       *doc.ByAt      `json:"updated,omitempty" bson:"updated,omitempty"`
+	IsDeleted     bool           `json:"-" bson:"is_deleted"`
+}
+
+type TicketQuery struct {
+	*util.CommonQuery
+	Status     string
+	AssigneeID *bson.ObjectID
+	DeviceID   *bson.ObjectID
+}
+
+type TicketCollRepository struct {
+	coll *mongo.Collection
+}
+
+func NewTicketRepository(db *mongo.Database) *TicketCollRepository {
+	return &TicketCollRepository{
+		coll: db.Collection("tickets"),
+	}
+}
+
+// openStatuses are the statuses of an unresolved ticket.
+var openStatuses = bson.A{_const.TicketOpen, _const.TicketInProgress, _const.TicketWaitingParts}
+
+// EnsureIndexes keeps a device to one unresolved ticket per checkpoint, so
+// docs saved at the same time can't open it twice.
+func (r *TicketCollRepository) EnsureIndexes() error {
+	return index.Ensure(r.coll, index.Index{
+		Name:   "open_ticket_unique",
+		Keys:   bson.D{{Key: "device_id", Value: 1}, {Key: "checkpoint", Value: 1}},
+		Unique: true,
+		Partial: bson.M{
+			"is_deleted": false,
+			"status":     bson.M{"$in": openStatuses},
+		},
+	})
+}
+
+func (r *TicketCollRepository) filter(tq *TicketQuery) bson.M {
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if len(tq.Q) > 0 {
+		var pattern = bson.Regex{Pattern: tq.Q, Options: "i"}
+		filter["checkpoint"] = bson.M{"$regex": pattern}
+	}
+
+	if tq.Device != "" {
+		filter["device"] = tq.Device
+	}
+
+	if tq.Status != "" {
+		filter["status"] = tq.Status
+	}
+
+	if tq.AssigneeID != nil {
+		filter["assignee_id"] = *tq.AssigneeID
+	}
+
+	if tq.DeviceID != nil {
+		filter["device_id"] = *tq.DeviceID
+	}
+	return filter
+}
+
+func (r *TicketCollRepository) FindAll(tq *TicketQuery) (*[]Ticket, error) {
+	var tickets []Ticket
+
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(bson.M{"inserted.at": tq.Sort}, tq.Page, tq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := r.coll.Find(context.TODO(), r.filter(tq), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	err = cur.All(context.TODO(), &tickets)
+	if err != nil {
+		return nil, err
+	}
+	if tickets == nil {
+		return &[]Ticket{}, nil
+	}
+	return &tickets, nil
+}
+
+func (r *TicketCollRepository) CountQuery(tq *TicketQuery) (int64, error) {
+	count, err := r.coll.CountDocuments(context.TODO(), r.filter(tq))
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *TicketCollRepository) FindOneByID(id bson.ObjectID) (*Ticket, error) {
+	var ticket Ticket
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	err := r.coll.FindOne(context.TODO(), filter).Decode(&ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (r *TicketCollRepository) UpdateOneByID(id bson.ObjectID, ticket *Ticket) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	update := bson.M{
+		"$set": ticket,
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Open opens a ticket for a failed checkpoint unless the device already has an
+// unresolved one for it. docAt is when the doc reporting the failure was
+// recorded.
+func (r *TicketCollRepository) Open(device string, deviceID, docID bson.ObjectID, docAt time.Time, cp doc.CPDetail, inserted doc.ByAt) error {
+	filter := bson.M{
+		"device_id":  deviceID,
+		"checkpoint": cp.Name,
+		"status":     bson.M{"$ne": _const.TicketResolved},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":        bson.NewObjectID(),
+			"device":     device,
+			"doc_id":     docID,
+			"doc_at":     docAt,
+			"keterangan": cp.Keterangan,
+			"status":     _const.TicketOpen,
+			"inserted":   inserted,
+			"is_deleted": false,
+		},
+	}
+
+	_, err := r.coll.UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// ResolveByDoc resolves the unresolved tickets of a checkpoint opened by docID
+// itself, corrected and resubmitted, or by a doc recorded before docAt, now
+// that docID reports it OK. A doc recorded earlier, like one synced late from
+// an offline client, leaves newer failures open. Tickets opened before doc_at
+// was kept are ordered by when they were opened.
+func (r *TicketCollRepository) ResolveByDoc(deviceID, docID bson.ObjectID, docAt time.Time, checkpoint string, resolved doc.ByAt) (int64, error) {
+	filter := bson.M{
+		"device_id":  deviceID,
+		"checkpoint": checkpoint,
+		"status":     bson.M{"$ne": _const.TicketResolved},
+		"is_deleted": bson.M{"$ne": true},
+		"$or": bson.A{
+			bson.M{"doc_id": docID},
+			bson.M{"doc_at": bson.M{"$lt": docAt}},
+			bson.M{"doc_at": bson.M{"$exists": false}, "inserted.at": bson.M{"$lt": docAt}},
+		},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status":          _const.TicketResolved,
+			"resolved_doc_id": docID,
+			"resolved":        resolved,
+			"resolution": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$resolution", ""}}, ""}},
+				"$resolution",
+				"Checkpoint reported OK by doc " + docID.Hex(),
+			}},
+		}}},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// ResolveRejected resolves the unresolved tickets opened by a doc the reviewer
+// rejected, whose failures are no longer reported. The doc opens them again
+// if it is resubmitted still failing.
+func (r *TicketCollRepository) ResolveRejected(docID bson.ObjectID, resolved doc.ByAt) (int64, error) {
+	filter := bson.M{
+		"doc_id":     docID,
+		"status":     bson.M{"$ne": _const.TicketResolved},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     _const.TicketResolved,
+			"resolution": "Doc " + docID.Hex() + " was rejected",
+			"resolved":   resolved,
+		},
+	}
+
+	res, err := r.coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
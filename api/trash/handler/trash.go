@@ -0,0 +1,482 @@
+package handler
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"net/http"
+	"sipamit-be/api/app/repo"
+	repo2 "sipamit-be/api/attachment/repo"
+	repo3 "sipamit-be/api/device/repo"
+	repo4 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/config"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/context"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/index"
+	"sipamit-be/internal/pkg/lock"
+	"sipamit-be/internal/pkg/log"
+	"sipamit-be/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	trashDevice = "device"
+	trashDoc    = "doc"
+	trashUser   = "user"
+)
+
+// retentionInterval is how often deleted records past the retention are
+// purged.
+const retentionInterval = time.Hour
+
+// retentionLock is the lock the replica purging holds, for two intervals so
+// it renews the lock before it expires.
+const retentionLock = "trash_retention"
+
+type trashItem struct {
+	Kind   string `json:"kind"`
+	Device string `json:"device,omitempty"`
+	doc.TrashRecord
+}
+
+type purgeResult struct {
+	Devices int
+	Docs    int
+	Users   int
+}
+
+type TrashHandler struct {
+	userRepo       *repo.UserCollRepository
+	attachmentRepo *repo2.AttachmentCollRepository
+	deviceRepo     *repo3.DeviceRepository
+	docRepo        *repo4.DocRepository
+	revisionRepo   *repo4.DocRevisionCollRepository
+	storage        *storage.Storage
+}
+
+func newTrashHandler(db *mongo.Database) *TrashHandler {
+	return &TrashHandler{
+		userRepo:       repo.NewUserRepository(db),
+		attachmentRepo: repo2.NewAttachmentRepository(db),
+		deviceRepo:     repo3.NewDeviceRepository(db),
+		docRepo:        repo4.NewDocRepository(db),
+		revisionRepo:   repo4.NewDocRevisionRepository(db),
+		storage:        storage.New(db),
+	}
+}
+
+func NewTrashAPIHandler(e *echo.Echo, db *mongo.Database) *TrashHandler {
+	h := newTrashHandler(db)
+
+	group := e.Group("/api", context.Handler, context.SuperAdminOnly)
+
+	group.GET("/trash", h.list)
+	group.POST("/trash/device/:device/:id/restore", h.restoreDevice)
+	group.DELETE("/trash/device/:device/:id", h.purgeDevice)
+	group.POST("/trash/doc/:device/:id/restore", h.restoreDoc)
+	group.DELETE("/trash/doc/:device/:id", h.purgeDoc)
+	group.POST("/trash/user/:id/restore", h.restoreUser)
+	group.DELETE("/trash/user/:id", h.purgeUser)
+
+	return h
+}
+
+// StartRetention starts purging the records deleted longer than
+// TRASH_RETENTION_DAYS ago in the background, unless no retention is set. It
+// is called once at startup; with several replicas running, only the one
+// holding the retention lock purges.
+func StartRetention(db *mongo.Database) {
+	if config.Trash.RetentionDays <= 0 {
+		return
+	}
+	go newTrashHandler(db).retain(db)
+}
+
+// list
+// @Tags Trash
+// @Summary Get deleted devices, docs and users with who deleted them and when
+// @ID get-trash
+// @Security ApiKeyAuth
+// @Param kind query string false "Only this kind" enums(device, doc, user)
+// @Param device query string false "Only this device type" enums(cctv, fingerprint, komputer_ph1, komputer_ph2, printer, telepon, toa, ups)
+// @Router /api/trash [GET]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) list(c echo.Context) error {
+	kind := strings.ToLower(strings.TrimSpace(c.QueryParam("kind")))
+	if kind != "" && kind != trashDevice && kind != trashDoc && kind != trashUser {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid kind")
+	}
+
+	types := _const.Devices
+	if s := c.QueryParam("device"); s != "" {
+		device := _const.DeviceFromParam(s)
+		if device == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+		}
+		types = []string{device}
+	}
+
+	items := []trashItem{}
+	for _, device := range types {
+		if kind == "" || kind == trashDevice {
+			records, err := h.deviceRepo.FindTrash(device)
+			if err != nil {
+				log.Errorf("Failed to get deleted %s devices: %v", device, err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+			}
+			for _, record := range records {
+				items = append(items, trashItem{Kind: trashDevice, Device: device, TrashRecord: record})
+			}
+		}
+
+		if kind == "" || kind == trashDoc {
+			records, err := h.docRepo.FindTrash(device)
+			if err != nil {
+				log.Errorf("Failed to get deleted %s docs: %v", device, err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+			}
+			for _, record := range records {
+				items = append(items, trashItem{Kind: trashDoc, Device: device, TrashRecord: record})
+			}
+		}
+	}
+
+	// users have no device type, a device filter leaves them out
+	if (kind == "" && c.QueryParam("device") == "") || kind == trashUser {
+		users, err := h.userRepo.FindAllDeleted()
+		if err != nil {
+			log.Errorf("Failed to get deleted users: %v", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+		}
+		for _, user := range *users {
+			items = append(items, trashItem{Kind: trashUser, TrashRecord: userTrashRecord(&user)})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deleted.At.After(items[j].Deleted.At)
+	})
+	return c.JSON(http.StatusOK, items)
+}
+
+// userTrashRecord lists a deleted user, falling back to its last update for
+// users deleted before the deleter was tracked.
+func userTrashRecord(user *repo.User) doc.TrashRecord {
+	record := doc.TrashRecord{
+		ID:   user.ID,
+		Name: user.FullName,
+	}
+	switch {
+	case user.Deleted != nil:
+		record.Deleted = doc.ByAt{ID: user.Deleted.ID, At: user.Deleted.At}
+	case user.Updated != nil:
+		record.Deleted = doc.ByAt{At: user.Updated.At}
+	default:
+		record.Deleted = doc.ByAt{At: user.Inserted.At}
+	}
+	return record
+}
+
+// restoreDevice
+// @Tags Trash
+// @Summary Restore a deleted device
+// @ID restore-trash-device
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Router /api/trash/device/{device}/{id}/restore [POST]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) restoreDevice(c echo.Context) error {
+	device, oId, err := deviceAndID(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.deviceRepo.Restore(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Device not found in trash")
+		}
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
+		log.Errorf("Failed to restore %s device: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Device restored")
+}
+
+// purgeDevice
+// @Tags Trash
+// @Summary Permanently delete a deleted device
+// @ID purge-trash-device
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Device ID"
+// @Router /api/trash/device/{device}/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) purgeDevice(c echo.Context) error {
+	device, oId, err := deviceAndID(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.deviceRepo.Purge(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Device not found in trash")
+		}
+		log.Errorf("Failed to purge %s device: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Device purged")
+}
+
+// restoreDoc
+// @Tags Trash
+// @Summary Restore a deleted document
+// @ID restore-trash-doc
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/trash/doc/{device}/{id}/restore [POST]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) restoreDoc(c echo.Context) error {
+	device, oId, err := deviceAndID(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.docRepo.Restore(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Document not found in trash")
+		}
+		log.Errorf("Failed to restore %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Document restored")
+}
+
+// purgeDoc
+// @Tags Trash
+// @Summary Permanently delete a deleted document with its attachments and revisions
+// @ID purge-trash-doc
+// @Security ApiKeyAuth
+// @Param device path string true "Device type" enums(cctv, fingerprint, komputer-ph1, komputer-ph2, printer, telepon, toa, ups)
+// @Param id path string true "Document ID"
+// @Router /api/trash/doc/{device}/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) purgeDoc(c echo.Context) error {
+	device, oId, err := deviceAndID(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.purgeDocByID(device, oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "Document not found in trash")
+		}
+		log.Errorf("Failed to purge %s doc: %v", device, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "Document purged")
+}
+
+// purgeDocByID removes the files, attachments and revisions of a deleted doc
+// before the doc itself, so a failed purge can be retried.
+func (h *TrashHandler) purgeDocByID(device string, id bson.ObjectID) error {
+	state, err := h.docRepo.FindSyncState(device, id)
+	if err != nil {
+		return err
+	}
+	if !state.IsDeleted {
+		return mongo.ErrNoDocuments
+	}
+
+	keys, err := h.attachmentRepo.FindAllKeysByDoc(device, id)
+	if err != nil {
+		return err
+	}
+	err = h.storage.Delete(keys...)
+	if err != nil {
+		return err
+	}
+	err = h.attachmentRepo.DeleteAllByDoc(device, id)
+	if err != nil {
+		return err
+	}
+
+	err = h.revisionRepo.DeleteAllByDoc(device, id)
+	if err != nil {
+		return err
+	}
+	return h.docRepo.Purge(device, id)
+}
+
+// restoreUser
+// @Tags Trash
+// @Summary Restore a deleted user
+// @ID restore-trash-user
+// @Security ApiKeyAuth
+// @Param id path string true "User ID"
+// @Router /api/trash/user/{id}/restore [POST]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) restoreUser(c echo.Context) error {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	user, err := h.userRepo.FindDeletedByID(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found in trash")
+		}
+		log.Errorf("Failed to get deleted user: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+
+	usr, err := h.userRepo.FindByUsername(user.Username)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Errorf("Failed to find user by username: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	if usr != nil {
+		return echo.NewHTTPError(http.StatusConflict, "Username is taken by another user")
+	}
+
+	err = h.userRepo.Restore(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found in trash")
+		}
+		if dup := index.DuplicateError(err); dup != nil {
+			return dup
+		}
+		log.Errorf("Failed to restore user: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "User restored")
+}
+
+// purgeUser
+// @Tags Trash
+// @Summary Permanently delete a deleted user
+// @ID purge-trash-user
+// @Security ApiKeyAuth
+// @Param id path string true "User ID"
+// @Router /api/trash/user/{id} [DELETE]
+// @Produce json
+// @Success 200
+func (h *TrashHandler) purgeUser(c echo.Context) error {
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+
+	err = h.userRepo.Purge(oId)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return echo.NewHTTPError(http.StatusNotFound, "User not found in trash")
+		}
+		log.Errorf("Failed to purge user: %v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Internal server error")
+	}
+	return c.JSON(http.StatusOK, "User purged")
+}
+
+func deviceAndID(c echo.Context) (string, bson.ObjectID, error) {
+	device := _const.DeviceFromParam(c.Param("device"))
+	if device == "" {
+		return "", bson.ObjectID{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	oId, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return "", bson.ObjectID{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid ID")
+	}
+	return device, oId, nil
+}
+
+// retain purges the records deleted longer than TRASH_RETENTION_DAYS ago,
+// once at startup and then every retentionInterval, while this process holds
+// the retention lock.
+func (h *TrashHandler) retain(db *mongo.Database) {
+	defer log.RecoverWithTrace()
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := lock.Acquire(db, retentionLock, 2*retentionInterval)
+		if err != nil {
+			log.Errorf("Failed to take the trash retention lock: %v", err)
+		}
+		if ok {
+			before := time.Now().AddDate(0, 0, -config.Trash.RetentionDays)
+			result := h.purgeBefore(before)
+			if result.Devices+result.Docs+result.Users > 0 {
+				log.Infof("Purged %d devices, %d docs and %d users deleted before %s",
+					result.Devices, result.Docs, result.Users, before.Format(time.RFC3339))
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// purgeBefore purges every record deleted before the given date. Failures
+// are logged and left for the next run.
+func (h *TrashHandler) purgeBefore(before time.Time) purgeResult {
+	var result purgeResult
+
+	for _, device := range _const.Devices {
+		ids, err := h.deviceRepo.FindTrashedBefore(device, before)
+		if err != nil {
+			log.Errorf("Failed to get expired %s devices: %v", device, err)
+		}
+		for _, id := range ids {
+			if err := h.deviceRepo.Purge(device, id); err != nil {
+				log.Errorf("Failed to purge %s device %s: %v", device, id.Hex(), err)
+				continue
+			}
+			result.Devices++
+		}
+
+		ids, err = h.docRepo.FindTrashedBefore(device, before)
+		if err != nil {
+			log.Errorf("Failed to get expired %s docs: %v", device, err)
+		}
+		for _, id := range ids {
+			if err := h.purgeDocByID(device, id); err != nil {
+				log.Errorf("Failed to purge %s doc %s: %v", device, id.Hex(), err)
+				continue
+			}
+			result.Docs++
+		}
+	}
+
+	ids, err := h.userRepo.FindDeletedBefore(before)
+	if err != nil {
+		log.Errorf("Failed to get expired users: %v", err)
+	}
+	for _, id := range ids {
+		if err := h.userRepo.Purge(id); err != nil {
+			log.Errorf("Failed to purge user %s: %v", id.Hex(), err)
+			continue
+		}
+		result.Users++
+	}
+	return result
+}
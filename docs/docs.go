@@ -15,6 +15,98 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/attachment/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Download an attachment",
+                "operationId": "download-attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Only the uploader or an admin can delete an attachment, and not one of a submitted or approved document.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Delete an attachment",
+                "operationId": "delete-attachment",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/attachment/{id}/thumbnail": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Download the thumbnail of an image attachment",
+                "operationId": "download-attachment-thumbnail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Attachment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
         "/api/cctv": {
             "post": {
                 "security": [
@@ -146,6 +238,61 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/cctv/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc CCTV"
+                ],
+                "summary": "Get maintenance history of a cctv",
+                "operationId": "get-cctv-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CCTV ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
         "/api/cctvs": {
             "get": {
                 "security": [
@@ -168,6 +315,25 @@ const docTemplate = `{
                         "name": "q",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -648,7 +814,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/device/count": {
+        "/api/checkpoint/{device}": {
             "get": {
                 "security": [
                     {
@@ -659,26 +825,58 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device"
+                    "Checkpoint"
                 ],
-                "summary": "Count all devices",
-                "operationId": "device-count",
+                "summary": "Get the checkpoint of a registered device type",
+                "operationId": "get-checkpoint-by-type",
                 "parameters": [
                     {
-                        "enum": [
-                            "cctv",
-                            "fingerprint",
-                            "komputer_ph1",
-                            "komputer_ph2",
-                            "printer",
-                            "telepon",
-                            "toa",
-                            "ups"
-                        ],
                         "type": "string",
-                        "description": "Device type",
+                        "description": "Device type slug",
                         "name": "device",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checkpoint"
+                ],
+                "summary": "Update the checkpoint of a registered device type",
+                "operationId": "update-checkpoint-by-type",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checkpoint",
+                        "name": "checkpoint",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.updateCheckpointForm"
+                        }
                     }
                 ],
                 "responses": {
@@ -688,29 +886,63 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/cctv": {
+        "/api/checkpoint/{device}/versions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checkpoint"
+                ],
+                "summary": "Get checkpoint template version history",
+                "operationId": "get-checkpoint-versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type or registered device type slug",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/department": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc CCTV"
+                    "Department"
                 ],
-                "summary": "Create cctv document",
-                "operationId": "create-cctv-document",
+                "summary": "Create new department",
+                "operationId": "create-department",
                 "parameters": [
                     {
-                        "description": "CCTV Document Form",
+                        "description": "Department Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.DeviceDocForm"
+                            "$ref": "#/definitions/handler.departmentForm"
                         }
                     }
                 ],
@@ -721,7 +953,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/cctv/{id}": {
+        "/api/department/{id}": {
             "get": {
                 "security": [
                     {
@@ -732,14 +964,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc CCTV"
+                    "Department"
                 ],
-                "summary": "Get cctv document by ID",
-                "operationId": "get-cctv-document-by-id",
+                "summary": "Get department by id",
+                "operationId": "get-department-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "CCTV Document ID",
+                        "description": "Department ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -757,29 +989,32 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc CCTV"
+                    "Department"
                 ],
-                "summary": "Update cctv document by ID",
-                "operationId": "update-cctv-document-by-id",
+                "summary": "Update department by id, renaming the departemen of its devices",
+                "operationId": "update-department",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "CCTV Document ID",
+                        "description": "Department ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "CCTV Document Form",
+                        "description": "Department Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                            "$ref": "#/definitions/handler.departmentForm"
                         }
                     }
                 ],
@@ -799,14 +1034,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc CCTV"
+                    "Department"
                 ],
-                "summary": "Delete cctv document by ID",
-                "operationId": "delete-cctv-document-by-id",
+                "summary": "Delete a department without devices",
+                "operationId": "delete-department",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "CCTV Document ID",
+                        "description": "Department ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -819,7 +1054,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/cctvs": {
+        "/api/departments": {
             "get": {
                 "security": [
                     {
@@ -830,14 +1065,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc CCTV"
+                    "Department"
                 ],
-                "summary": "Get all cctv documents",
-                "operationId": "get-all-cctv-documents",
+                "summary": "Get all departments",
+                "operationId": "get-all-departments",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by nama",
+                        "description": "Search by name, cost center or alias",
                         "name": "q",
                         "in": "query"
                     },
@@ -873,8 +1108,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/fingerprint": {
-            "post": {
+        "/api/device-types": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -884,18 +1119,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Fingerprint"
+                    "Device Type"
                 ],
-                "summary": "Create fingerprint document",
-                "operationId": "create-fingerprint-document",
+                "summary": "Get all device types, built-in ones first",
+                "operationId": "get-all-device-types",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Devices and docs of custom types are managed under /api/devices/{type} and /api/doc/{type} only. They are not covered by maintenance schedules, the compliance and trend reports, device search, the trash, offline sync, import or export, which handle the built-in types.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Type"
+                ],
+                "summary": "Register a device type with an empty checkpoint template",
+                "operationId": "create-device-type",
                 "parameters": [
                     {
-                        "description": "Fingerprint Document Form",
+                        "description": "Device Type Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.DeviceDocForm"
+                            "$ref": "#/definitions/handler.deviceTypeForm"
                         }
                     }
                 ],
@@ -906,7 +1165,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/fingerprint/{id}": {
+        "/api/device-types/{type}": {
             "get": {
                 "security": [
                     {
@@ -917,15 +1176,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Fingerprint"
+                    "Device Type"
                 ],
-                "summary": "Get fingerprint document by ID",
-                "operationId": "get-fingerprint-document-by-id",
+                "summary": "Get a device type with its field schema",
+                "operationId": "get-device-type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint Document ID",
-                        "name": "id",
+                        "description": "Device type slug",
+                        "name": "type",
                         "in": "path",
                         "required": true
                     }
@@ -942,29 +1201,32 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Fingerprint"
+                    "Device Type"
                 ],
-                "summary": "Update fingerprint document by ID",
-                "operationId": "update-fingerprint-document-by-id",
+                "summary": "Update the name and field schema of a device type",
+                "operationId": "update-device-type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint Document ID",
-                        "name": "id",
+                        "description": "Device type slug",
+                        "name": "type",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Fingerprint Document Form",
+                        "description": "Device Type Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                            "$ref": "#/definitions/handler.deviceTypeForm"
                         }
                     }
                 ],
@@ -984,15 +1246,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Fingerprint"
+                    "Device Type"
                 ],
-                "summary": "Delete fingerprint document by ID",
-                "operationId": "delete-fingerprint-document-by-id",
+                "summary": "Delete a device type without devices",
+                "operationId": "delete-device-type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint Document ID",
-                        "name": "id",
+                        "description": "Device type slug",
+                        "name": "type",
                         "in": "path",
                         "required": true
                     }
@@ -1004,7 +1266,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/fingerprints": {
+        "/api/device/count": {
             "get": {
                 "security": [
                     {
@@ -1015,39 +1277,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Fingerprint"
+                    "Device"
                 ],
-                "summary": "Get all fingerprint documents",
-                "operationId": "get-all-fingerprint-documents",
+                "summary": "Count devices in service, or in the given lifecycle status",
+                "operationId": "device-count",
                 "parameters": [
                     {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
                         "type": "string",
-                        "description": "Search by nama",
-                        "name": "q",
+                        "description": "Device type",
+                        "name": "device",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number pagination",
-                        "name": "page",
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle status",
+                        "name": "status",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit pagination",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "Location ID, including the locations under it",
+                        "name": "location",
                         "in": "query"
                     },
                     {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
                         "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
+                        "description": "Department ID",
+                        "name": "department",
                         "in": "query"
                     }
                 ],
@@ -1058,40 +1331,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/komputer-ph1": {
-            "post": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Doc Komputer PH1"
-                ],
-                "summary": "Create new komputer ph1 document",
-                "operationId": "create-new-komputer-ph1-document",
-                "parameters": [
-                    {
-                        "description": "Komputer PH1 Document Form",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/doc.DeviceDocForm"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/api/doc/komputer-ph1/{id}": {
+        "/api/device/{device}/{id}/qr": {
             "get": {
                 "security": [
                     {
@@ -1099,20 +1339,56 @@ const docTemplate = `{
                     }
                 ],
                 "produces": [
-                    "application/json"
+                    "image/png",
+                    "image/svg+xml"
                 ],
                 "tags": [
-                    "Doc Komputer PH1"
+                    "Label"
                 ],
-                "summary": "Get komputer ph1 document by ID",
-                "operationId": "get-komputer-ph1-document-by-id",
+                "summary": "Get the QR code of a device asset tag",
+                "operationId": "get-device-qr",
                 "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
-                        "description": "Komputer PH1 Document ID",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Image size in pixels",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1120,36 +1396,59 @@ const docTemplate = `{
                         "description": "OK"
                     }
                 }
-            },
+            }
+        },
+        "/api/device/{device}/{id}/status": {
             "put": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "Allowed transitions: active to in_repair, spare or retired; in_repair and spare back to active or to retired; retired to disposed.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH1"
+                    "Device"
                 ],
-                "summary": "Update komputer ph1 document by ID",
-                "operationId": "update-komputer-ph1-document-by-id",
+                "summary": "Move a device to another lifecycle status",
+                "operationId": "update-device-status",
                 "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
-                        "description": "Komputer PH1 Document ID",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Komputer PH1 Document Form",
+                        "description": "Status Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                            "$ref": "#/definitions/handler.statusForm"
                         }
                     }
                 ],
@@ -1158,28 +1457,71 @@ const docTemplate = `{
                         "description": "OK"
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/devices/labels": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "At most 2400 labels, 100 pages, are printed at once. More devices matching the filters is a 400.",
                 "produces": [
-                    "application/json"
+                    "application/pdf"
                 ],
                 "tags": [
-                    "Doc Komputer PH1"
+                    "Label"
                 ],
-                "summary": "Delete komputer ph1 document by ID",
-                "operationId": "delete-komputer-ph1-document-by-id",
+                "summary": "Get a printable PDF sheet of asset tag labels of the filtered devices",
+                "operationId": "get-device-labels",
                 "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Komputer PH1 Document ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Department ID",
+                        "name": "department",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1189,7 +1531,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/komputer-ph1s": {
+        "/api/devices/search": {
             "get": {
                 "security": [
                     {
@@ -1200,15 +1542,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH1"
+                    "Device"
                 ],
-                "summary": "Get all komputer ph1 documents",
-                "operationId": "get-all-komputer-ph1-documents",
+                "summary": "Search devices of every type by asset tag, nama, kode, no seri, ext, user, lokasi and departemen",
+                "operationId": "device-search",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by nama",
+                        "description": "Search text",
                         "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
                         "in": "query"
                     },
                     {
@@ -1224,16 +1583,6 @@ const docTemplate = `{
                         "description": "Limit pagination",
                         "name": "limit",
                         "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
-                        "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
-                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1243,8 +1592,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/komputer-ph2": {
-            "post": {
+        "/api/devices/{type}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -1254,18 +1603,87 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH2"
+                    "Device Custom"
                 ],
-                "summary": "Create new komputer ph2 document",
-                "operationId": "create-new-komputer-ph2-document",
+                "summary": "Get all devices of a custom device type",
+                "operationId": "get-all-custom-devices",
                 "parameters": [
                     {
-                        "description": "Komputer PH2 Document Form",
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search the searchable fields",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Custom"
+                ],
+                "summary": "Create a device of a custom device type, validated against its schema",
+                "operationId": "create-custom-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Device Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.DeviceDocForm"
+                            "$ref": "#/definitions/handler.customDeviceForm"
                         }
                     }
                 ],
@@ -1276,7 +1694,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/komputer-ph2/{id}": {
+        "/api/devices/{type}/{id}": {
             "get": {
                 "security": [
                     {
@@ -1287,14 +1705,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH2"
+                    "Device Custom"
                 ],
-                "summary": "Get komputer ph2 document by ID",
-                "operationId": "get-komputer-ph2-document-by-id",
+                "summary": "Get a device of a custom device type",
+                "operationId": "get-custom-device",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Komputer PH2 Document ID",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1312,29 +1737,39 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH2"
+                    "Device Custom"
                 ],
-                "summary": "Update komputer ph2 document by ID",
-                "operationId": "update-komputer-ph2-document-by-id",
+                "summary": "Update a device of a custom device type, validated against its schema",
+                "operationId": "update-custom-device",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Komputer PH2 Document ID",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Komputer PH2 Document Form",
+                        "description": "Device Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                            "$ref": "#/definitions/handler.customDeviceForm"
                         }
                     }
                 ],
@@ -1354,14 +1789,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH2"
+                    "Device Custom"
                 ],
-                "summary": "Delete komputer ph2 document by ID",
-                "operationId": "delete-komputer-ph2-document-by-id",
+                "summary": "Delete a device of a custom device type",
+                "operationId": "delete-custom-device",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Komputer PH2 Document ID",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1374,8 +1816,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/komputer-ph2s": {
-            "get": {
+        "/api/doc/cctv": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -1385,40 +1827,19 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Komputer PH2"
+                    "Doc CCTV"
                 ],
-                "summary": "Get all komputer ph2 documents",
-                "operationId": "get-all-komputer-ph2-documents",
+                "summary": "Create cctv document",
+                "operationId": "create-cctv-document",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Search by nama",
-                        "name": "q",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit pagination",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
-                        "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
-                        "in": "query"
+                        "description": "CCTV Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.DeviceDocForm"
+                        }
                     }
                 ],
                 "responses": {
@@ -1428,7 +1849,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/printer": {
+        "/api/doc/cctv/bulk": {
             "post": {
                 "security": [
                     {
@@ -1439,18 +1860,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Printer"
+                    "Doc CCTV"
                 ],
-                "summary": "Create printer document",
-                "operationId": "create-printer-document",
+                "summary": "Create many cctv documents at once",
+                "operationId": "bulk-create-cctv-document",
                 "parameters": [
                     {
-                        "description": "Printer Document Form",
+                        "description": "Bulk CCTV Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/doc.DeviceDocForm"
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
                         }
                     }
                 ],
@@ -1461,7 +1882,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/printer/{id}": {
+        "/api/doc/cctv/{id}": {
             "get": {
                 "security": [
                     {
@@ -1472,14 +1893,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Printer"
+                    "Doc CCTV"
                 ],
-                "summary": "Get printer document by ID",
-                "operationId": "get-printer-document-by-id",
+                "summary": "Get cctv document by ID",
+                "operationId": "get-cctv-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer Document ID",
+                        "description": "CCTV Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1501,20 +1922,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Printer"
+                    "Doc CCTV"
                 ],
-                "summary": "Update printer document by ID",
-                "operationId": "update-printer-document-by-id",
+                "summary": "Update cctv document by ID",
+                "operationId": "update-cctv-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer Document ID",
+                        "description": "CCTV Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Printer Document Form",
+                        "description": "CCTV Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -1535,15 +1956,18 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Doc Printer"
+                    "Doc CCTV"
                 ],
-                "summary": "Delete printer document by ID",
-                "operationId": "delete-printer-document-by-id",
+                "summary": "Delete cctv document by ID",
+                "operationId": "delete-cctv-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer Document ID",
+                        "description": "CCTV Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1556,7 +1980,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/printers": {
+        "/api/doc/cctvs": {
             "get": {
                 "security": [
                     {
@@ -1567,10 +1991,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Printer"
+                    "Doc CCTV"
                 ],
-                "summary": "Get all printer documents",
-                "operationId": "get-all-printer-documents",
+                "summary": "Get all cctv documents",
+                "operationId": "get-all-cctv-documents",
                 "parameters": [
                     {
                         "type": "string",
@@ -1601,6 +2025,66 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1610,27 +2094,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/telepon": {
+        "/api/doc/fingerprint": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "consumes": [
-                    "application/json"
-                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Telepon"
+                    "Doc Fingerprint"
                 ],
-                "summary": "Create new telepon document",
-                "operationId": "create-new-telepon-document",
+                "summary": "Create fingerprint document",
+                "operationId": "create-fingerprint-document",
                 "parameters": [
                     {
-                        "description": "Telepon Document Form",
+                        "description": "Fingerprint Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -1646,8 +2127,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/telepon/{id}": {
-            "get": {
+        "/api/doc/fingerprint/bulk": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -1657,21 +2138,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Telepon"
+                    "Doc Fingerprint"
                 ],
-                "summary": "Get telepon document by ID",
-                "operationId": "get-telepon-document-by-id",
+                "summary": "Create many fingerprint documents at once",
+                "operationId": "bulk-create-fingerprint-document",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Telepon Document ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
+                        "description": "Bulk Fingerprint Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/fingerprint/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Fingerprint"
+                ],
+                "summary": "Get fingerprint document by ID",
+                "operationId": "get-fingerprint-document-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fingerprint Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
                         "description": "OK"
                     }
                 }
@@ -1686,20 +2200,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Telepon"
+                    "Doc Fingerprint"
                 ],
-                "summary": "Update telepon document by ID",
-                "operationId": "update-telepon-document-by-id",
+                "summary": "Update fingerprint document by ID",
+                "operationId": "update-fingerprint-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telepon Document ID",
+                        "description": "Fingerprint Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Telepon Document Form",
+                        "description": "Fingerprint Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -1720,15 +2234,18 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Doc Telepon"
+                    "Doc Fingerprint"
                 ],
-                "summary": "Delete telepon document by ID",
-                "operationId": "delete-telepon-document-by-id",
+                "summary": "Delete fingerprint document by ID",
+                "operationId": "delete-fingerprint-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telepon Document ID",
+                        "description": "Fingerprint Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1741,7 +2258,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/telepons": {
+        "/api/doc/fingerprints": {
             "get": {
                 "security": [
                     {
@@ -1752,14 +2269,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc Telepon"
+                    "Doc Fingerprint"
                 ],
-                "summary": "Get all telepons documents",
-                "operationId": "get-all-telepons-documents",
+                "summary": "Get all fingerprint documents",
+                "operationId": "get-all-fingerprint-documents",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by tipe",
+                        "description": "Search by nama",
                         "name": "q",
                         "in": "query"
                     },
@@ -1786,6 +2303,66 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1795,7 +2372,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/toa": {
+        "/api/doc/komputer-ph1": {
             "post": {
                 "security": [
                     {
@@ -1806,13 +2383,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc TOA"
+                    "Doc Komputer PH1"
                 ],
-                "summary": "Create toa document",
-                "operationId": "create-toa-document",
+                "summary": "Create new komputer ph1 document",
+                "operationId": "create-new-komputer-ph1-document",
                 "parameters": [
                     {
-                        "description": "TOA Document Form",
+                        "description": "Komputer PH1 Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -1828,7 +2405,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/toa/{id}": {
+        "/api/doc/komputer-ph1/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Komputer PH1"
+                ],
+                "summary": "Create many komputer ph1 documents at once",
+                "operationId": "bulk-create-komputer-ph1-document",
+                "parameters": [
+                    {
+                        "description": "Bulk Komputer PH1 Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/komputer-ph1/{id}": {
             "get": {
                 "security": [
                     {
@@ -1839,14 +2449,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc TOA"
+                    "Doc Komputer PH1"
                 ],
-                "summary": "Get toa document by ID",
-                "operationId": "get-toa-document-by-id",
+                "summary": "Get komputer ph1 document by ID",
+                "operationId": "get-komputer-ph1-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "TOA Document ID",
+                        "description": "Komputer PH1 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1868,20 +2478,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc TOA"
+                    "Doc Komputer PH1"
                 ],
-                "summary": "Update toa document by ID",
-                "operationId": "update-toa-document-by-id",
+                "summary": "Update komputer ph1 document by ID",
+                "operationId": "update-komputer-ph1-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "TOA Document ID",
+                        "description": "Komputer PH1 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "TOA Document Form",
+                        "description": "Komputer PH1 Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -1902,15 +2512,18 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Doc TOA"
+                    "Doc Komputer PH1"
                 ],
-                "summary": "Delete toa document by ID",
-                "operationId": "delete-toa-document-by-id",
+                "summary": "Delete komputer ph1 document by ID",
+                "operationId": "delete-komputer-ph1-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "TOA Document ID",
+                        "description": "Komputer PH1 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1923,7 +2536,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/toas": {
+        "/api/doc/komputer-ph1s": {
             "get": {
                 "security": [
                     {
@@ -1934,10 +2547,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc TOA"
+                    "Doc Komputer PH1"
                 ],
-                "summary": "Get all toas documents",
-                "operationId": "get-all-toas-documents",
+                "summary": "Get all komputer ph1 documents",
+                "operationId": "get-all-komputer-ph1-documents",
                 "parameters": [
                     {
                         "type": "string",
@@ -1968,59 +2581,65 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/api/doc/ups": {
-            "get": {
-                "security": [
+                    },
                     {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Doc UPS"
-                ],
-                "summary": "Get all ups documents",
-                "operationId": "get-all-ups-documents",
-                "parameters": [
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "Search by nama",
-                        "name": "q",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number pagination",
-                        "name": "page",
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit pagination",
-                        "name": "limit",
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
                         "in": "query"
                     },
                     {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
                         "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
                         "in": "query"
                     }
                 ],
@@ -2029,7 +2648,9 @@ const docTemplate = `{
                         "description": "OK"
                     }
                 }
-            },
+            }
+        },
+        "/api/doc/komputer-ph2": {
             "post": {
                 "security": [
                     {
@@ -2040,13 +2661,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Doc UPS"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Create new ups document",
-                "operationId": "create-new-ups-document",
+                "summary": "Create new komputer ph2 document",
+                "operationId": "create-new-komputer-ph2-document",
                 "parameters": [
                     {
-                        "description": "UPS Document Form",
+                        "description": "Komputer PH2 Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
@@ -2062,102 +2683,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/doc/ups/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Doc UPS"
-                ],
-                "summary": "Get ups document by ID",
-                "operationId": "get-ups-document-by-id",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "UPS Document ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            },
-            "put": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Doc UPS"
-                ],
-                "summary": "Update ups document by ID",
-                "operationId": "update-ups-document-by-id",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "UPS Document ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "UPS Document Form",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            },
-            "delete": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "tags": [
-                    "Doc UPS"
-                ],
-                "summary": "Delete ups document by ID",
-                "operationId": "delete-ups-document-by-id",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "UPS Document ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/api/fingerprint": {
+        "/api/doc/komputer-ph2/bulk": {
             "post": {
                 "security": [
                     {
@@ -2168,18 +2694,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Fingerprint"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Create new fingerprint",
-                "operationId": "create-fingerprint",
+                "summary": "Create many komputer ph2 documents at once",
+                "operationId": "bulk-create-komputer-ph2-document",
                 "parameters": [
                     {
-                        "description": "Fingerprint Form",
+                        "description": "Bulk Komputer PH2 Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.fingerPrintForm"
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
                         }
                     }
                 ],
@@ -2190,7 +2716,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/fingerprint/{id}": {
+        "/api/doc/komputer-ph2/{id}": {
             "get": {
                 "security": [
                     {
@@ -2201,14 +2727,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Fingerprint"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Get fingerprint by id",
-                "operationId": "get-fingerprint-by-id",
+                "summary": "Get komputer ph2 document by ID",
+                "operationId": "get-komputer-ph2-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint ID",
+                        "description": "Komputer PH2 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2230,25 +2756,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Fingerprint"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Update fingerprint by id",
-                "operationId": "update-fingerprint-by-id",
+                "summary": "Update komputer ph2 document by ID",
+                "operationId": "update-komputer-ph2-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint ID",
+                        "description": "Komputer PH2 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Fingerprint Form",
+                        "description": "Komputer PH2 Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.fingerPrintForm"
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
                         }
                     }
                 ],
@@ -2268,14 +2794,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Fingerprint"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Delete fingerprint by id",
-                "operationId": "delete-fingerprint-by-id",
+                "summary": "Delete komputer ph2 document by ID",
+                "operationId": "delete-komputer-ph2-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Fingerprint ID",
+                        "description": "Komputer PH2 Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2288,7 +2814,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/fingerprints": {
+        "/api/doc/komputer-ph2s": {
             "get": {
                 "security": [
                     {
@@ -2299,10 +2825,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Fingerprint"
+                    "Doc Komputer PH2"
                 ],
-                "summary": "Get all fingerprints",
-                "operationId": "get-all-fingerprints",
+                "summary": "Get all komputer ph2 documents",
+                "operationId": "get-all-komputer-ph2-documents",
                 "parameters": [
                     {
                         "type": "string",
@@ -2333,6 +2859,66 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -2342,7 +2928,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph1": {
+        "/api/doc/printer": {
             "post": {
                 "security": [
                     {
@@ -2353,18 +2939,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH1"
+                    "Doc Printer"
                 ],
-                "summary": "Create new komputer-ph1",
-                "operationId": "create-komputer-ph1",
+                "summary": "Create printer document",
+                "operationId": "create-printer-document",
                 "parameters": [
                     {
-                        "description": "KomputerPH1 Form",
+                        "description": "Printer Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.komputerPH1Form"
+                            "$ref": "#/definitions/doc.DeviceDocForm"
                         }
                     }
                 ],
@@ -2375,7 +2961,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph1/{id}": {
+        "/api/doc/printer/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Printer"
+                ],
+                "summary": "Create many printer documents at once",
+                "operationId": "bulk-create-printer-document",
+                "parameters": [
+                    {
+                        "description": "Bulk Printer Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/printer/{id}": {
             "get": {
                 "security": [
                     {
@@ -2386,14 +3005,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH1"
+                    "Doc Printer"
                 ],
-                "summary": "Get komputer-ph1 by id",
-                "operationId": "get-komputer-ph1-by-id",
+                "summary": "Get printer document by ID",
+                "operationId": "get-printer-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH1 ID",
+                        "description": "Printer Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2415,25 +3034,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH1"
+                    "Doc Printer"
                 ],
-                "summary": "Update komputer-ph1 by id",
-                "operationId": "update-komputer-ph1-by-id",
+                "summary": "Update printer document by ID",
+                "operationId": "update-printer-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH1 ID",
+                        "description": "Printer Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "KomputerPH1 Form",
+                        "description": "Printer Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.komputerPH1Form"
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
                         }
                     }
                 ],
@@ -2449,18 +3068,15 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "produces": [
-                    "application/json"
-                ],
                 "tags": [
-                    "Device KomputerPH1"
+                    "Doc Printer"
                 ],
-                "summary": "Delete komputer-ph1 by id",
-                "operationId": "delete-komputer-ph1-by-id",
+                "summary": "Delete printer document by ID",
+                "operationId": "delete-printer-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH1 ID",
+                        "description": "Printer Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2473,7 +3089,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph1s": {
+        "/api/doc/printers": {
             "get": {
                 "security": [
                     {
@@ -2484,10 +3100,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH1"
+                    "Doc Printer"
                 ],
-                "summary": "Get all komputer-ph1s",
-                "operationId": "get-all-komputer-ph1s",
+                "summary": "Get all printer documents",
+                "operationId": "get-all-printer-documents",
                 "parameters": [
                     {
                         "type": "string",
@@ -2518,6 +3134,66 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -2527,29 +3203,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph2": {
+        "/api/doc/telepon": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH2"
+                    "Doc Telepon"
                 ],
-                "summary": "Create new komputer-ph2",
-                "operationId": "create-komputer-ph2",
+                "summary": "Create new telepon document",
+                "operationId": "create-new-telepon-document",
                 "parameters": [
                     {
-                        "description": "KomputerPH2 Form",
+                        "description": "Telepon Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.komputerPH2Form"
+                            "$ref": "#/definitions/doc.DeviceDocForm"
                         }
                     }
                 ],
@@ -2560,7 +3239,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph2/{id}": {
+        "/api/doc/telepon/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Telepon"
+                ],
+                "summary": "Create many telepon documents at once",
+                "operationId": "bulk-create-telepon-document",
+                "parameters": [
+                    {
+                        "description": "Bulk Telepon Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/telepon/{id}": {
             "get": {
                 "security": [
                     {
@@ -2571,14 +3283,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH2"
+                    "Doc Telepon"
                 ],
-                "summary": "Get komputer-ph2 by id",
-                "operationId": "get-komputer-ph2-by-id",
+                "summary": "Get telepon document by ID",
+                "operationId": "get-telepon-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH2 ID",
+                        "description": "Telepon Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2600,25 +3312,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH2"
+                    "Doc Telepon"
                 ],
-                "summary": "Update komputer-ph2 by id",
-                "operationId": "update-komputer-ph2-by-id",
+                "summary": "Update telepon document by ID",
+                "operationId": "update-telepon-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH2 ID",
+                        "description": "Telepon Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "KomputerPH2 Form",
+                        "description": "Telepon Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.komputerPH2Form"
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
                         }
                     }
                 ],
@@ -2634,18 +3346,15 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "produces": [
-                    "application/json"
-                ],
                 "tags": [
-                    "Device KomputerPH2"
+                    "Doc Telepon"
                 ],
-                "summary": "Delete komputer-ph2 by id",
-                "operationId": "delete-komputer-ph2-by-id",
+                "summary": "Delete telepon document by ID",
+                "operationId": "delete-telepon-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "KomputerPH2 ID",
+                        "description": "Telepon Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2658,7 +3367,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/komputer-ph2s": {
+        "/api/doc/telepons": {
             "get": {
                 "security": [
                     {
@@ -2669,14 +3378,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device KomputerPH2"
+                    "Doc Telepon"
                 ],
-                "summary": "Get all komputer-ph2s",
-                "operationId": "get-all-komputer-ph2s",
+                "summary": "Get all telepons documents",
+                "operationId": "get-all-telepons-documents",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by nama",
+                        "description": "Search by tipe",
                         "name": "q",
                         "in": "query"
                     },
@@ -2703,6 +3412,66 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -2712,24 +3481,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/login": {
+        "/api/doc/toa": {
             "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Doc TOA"
                 ],
-                "summary": "Login",
-                "operationId": "login",
+                "summary": "Create toa document",
+                "operationId": "create-toa-document",
                 "parameters": [
                     {
-                        "description": "Login Form",
+                        "description": "TOA Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.loginForm"
+                            "$ref": "#/definitions/doc.DeviceDocForm"
                         }
                     }
                 ],
@@ -2740,7 +3514,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/printer": {
+        "/api/doc/toa/bulk": {
             "post": {
                 "security": [
                     {
@@ -2751,18 +3525,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Printer"
+                    "Doc TOA"
                 ],
-                "summary": "Create new printer",
-                "operationId": "create-new-printer",
+                "summary": "Create many toa documents at once",
+                "operationId": "bulk-create-toa-document",
                 "parameters": [
                     {
-                        "description": "Printer Form",
+                        "description": "Bulk TOA Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.printerForm"
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
                         }
                     }
                 ],
@@ -2773,7 +3547,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/printer/{id}": {
+        "/api/doc/toa/{id}": {
             "get": {
                 "security": [
                     {
@@ -2784,14 +3558,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Printer"
+                    "Doc TOA"
                 ],
-                "summary": "Get printer by id",
-                "operationId": "get-printer-by-id",
+                "summary": "Get toa document by ID",
+                "operationId": "get-toa-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer ID",
+                        "description": "TOA Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2813,25 +3587,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Printer"
+                    "Doc TOA"
                 ],
-                "summary": "Update printer by id",
-                "operationId": "update-printer-by-id",
+                "summary": "Update toa document by ID",
+                "operationId": "update-toa-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer ID",
+                        "description": "TOA Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Printer Form",
+                        "description": "TOA Document Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.printerForm"
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
                         }
                     }
                 ],
@@ -2847,18 +3621,15 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "produces": [
-                    "application/json"
-                ],
                 "tags": [
-                    "Device Printer"
+                    "Doc TOA"
                 ],
-                "summary": "Delete printer by id",
-                "operationId": "delete-printer-by-id",
+                "summary": "Delete toa document by ID",
+                "operationId": "delete-toa-document-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Printer ID",
+                        "description": "TOA Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2871,7 +3642,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/printers": {
+        "/api/doc/toas": {
             "get": {
                 "security": [
                     {
@@ -2882,10 +3653,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Printer"
+                    "Doc TOA"
                 ],
-                "summary": "Get all printers",
-                "operationId": "get-all-printers",
+                "summary": "Get all toas documents",
+                "operationId": "get-all-toas-documents",
                 "parameters": [
                     {
                         "type": "string",
@@ -2916,38 +3687,3535 @@ const docTemplate = `{
                         "description": "Sort",
                         "name": "sort",
                         "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK"
-                    }
-                }
-            }
-        },
-        "/api/telepon": {
-            "post": {
-                "security": [
+                    },
                     {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Device Telepon"
-                ],
-                "summary": "Create new telepon",
-                "operationId": "create-new-telepon",
-                "parameters": [
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/ups": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Get all ups documents",
+                "operationId": "get-all-ups-documents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Create new ups document",
+                "operationId": "create-new-ups-document",
+                "parameters": [
+                    {
+                        "description": "UPS Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.DeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/ups/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Create many ups documents at once",
+                "operationId": "bulk-create-ups-document",
+                "parameters": [
+                    {
+                        "description": "Bulk UPS Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.BulkDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/ups/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Get ups document by ID",
+                "operationId": "get-ups-document-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UPS Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Update ups document by ID",
+                "operationId": "update-ups-document-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UPS Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "UPS Document Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Delete ups document by ID",
+                "operationId": "delete-ups-document-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UPS Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Custom"
+                ],
+                "summary": "Get all documents of a custom device type",
+                "operationId": "get-all-custom-documents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Custom"
+                ],
+                "summary": "Create a document of a custom device type",
+                "operationId": "create-custom-document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Doc Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.DeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export the documents of a device type matching the list filters as CSV or XLSX",
+                "operationId": "export-documents",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "submitted",
+                            "approved",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created from date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created until date inclusive (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by technician user ID",
+                        "name": "technician_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only docs with failed checkpoints",
+                        "name": "failed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only docs where this checkpoint failed",
+                        "name": "failed_checkpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by date or a doc field, default _id",
+                        "name": "sort_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/pdf": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Only submitted, approved and legacy documents are printed, drafts and rejected documents are left out.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Doc PDF"
+                ],
+                "summary": "Get berita acara PDF of all documents of a device type in a month",
+                "operationId": "get-month-document-pdf",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Month (YYYY-MM), default this month",
+                        "name": "month",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Custom"
+                ],
+                "summary": "Get a document of a custom device type",
+                "operationId": "get-custom-document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Custom"
+                ],
+                "summary": "Update the checkpoint results of a document of a custom device type",
+                "operationId": "update-custom-document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Doc Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.UpdateDeviceDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Custom"
+                ],
+                "summary": "Delete a document of a custom device type",
+                "operationId": "delete-custom-document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device type slug",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/approve": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Review"
+                ],
+                "summary": "Approve a submitted document",
+                "operationId": "approve-document",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/attachments": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Get attachments of a document",
+                "operationId": "get-document-attachments",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only attachments of this checkpoint item",
+                        "name": "checkpoint",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Submitted and approved documents take no new attachments.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Attach a file to a document or one of its checkpoint items",
+                "operationId": "create-document-attachment",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Image or PDF file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checkpoint item name",
+                        "name": "checkpoint",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/pdf": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Doc PDF"
+                ],
+                "summary": "Get berita acara PDF of a document",
+                "operationId": "get-document-pdf",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/reject": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "The tickets the document opened are resolved, and opened again if it is resubmitted still failing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Review"
+                ],
+                "summary": "Reject a submitted document with a reason",
+                "operationId": "reject-document",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reject Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.RejectDocForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Revision"
+                ],
+                "summary": "Get the revision history of a document with changes per checkpoint item",
+                "operationId": "get-document-revisions",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/doc/{type}/{id}/submit": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Review"
+                ],
+                "summary": "Submit a draft or rejected document for review",
+                "operationId": "submit-document",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/fingerprint": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Fingerprint"
+                ],
+                "summary": "Create new fingerprint",
+                "operationId": "create-fingerprint",
+                "parameters": [
+                    {
+                        "description": "Fingerprint Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.fingerPrintForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/fingerprint/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Fingerprint"
+                ],
+                "summary": "Get fingerprint by id",
+                "operationId": "get-fingerprint-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fingerprint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Fingerprint"
+                ],
+                "summary": "Update fingerprint by id",
+                "operationId": "update-fingerprint-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fingerprint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fingerprint Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.fingerPrintForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Fingerprint"
+                ],
+                "summary": "Delete fingerprint by id",
+                "operationId": "delete-fingerprint-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fingerprint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/fingerprint/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Fingerprint"
+                ],
+                "summary": "Get maintenance history of a fingerprint",
+                "operationId": "get-fingerprint-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Fingerprint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/fingerprints": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Fingerprint"
+                ],
+                "summary": "Get all fingerprints",
+                "operationId": "get-all-fingerprints",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph1": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH1"
+                ],
+                "summary": "Create new komputer-ph1",
+                "operationId": "create-komputer-ph1",
+                "parameters": [
+                    {
+                        "description": "KomputerPH1 Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.komputerPH1Form"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph1/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH1"
+                ],
+                "summary": "Get komputer-ph1 by id",
+                "operationId": "get-komputer-ph1-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH1 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH1"
+                ],
+                "summary": "Update komputer-ph1 by id",
+                "operationId": "update-komputer-ph1-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH1 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "KomputerPH1 Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.komputerPH1Form"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH1"
+                ],
+                "summary": "Delete komputer-ph1 by id",
+                "operationId": "delete-komputer-ph1-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH1 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph1/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Komputer PH1"
+                ],
+                "summary": "Get maintenance history of a komputer ph1",
+                "operationId": "get-komputer-ph1-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Komputer PH1 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph1s": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH1"
+                ],
+                "summary": "Get all komputer-ph1s",
+                "operationId": "get-all-komputer-ph1s",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph2": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH2"
+                ],
+                "summary": "Create new komputer-ph2",
+                "operationId": "create-komputer-ph2",
+                "parameters": [
+                    {
+                        "description": "KomputerPH2 Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.komputerPH2Form"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph2/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH2"
+                ],
+                "summary": "Get komputer-ph2 by id",
+                "operationId": "get-komputer-ph2-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH2 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH2"
+                ],
+                "summary": "Update komputer-ph2 by id",
+                "operationId": "update-komputer-ph2-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH2 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "KomputerPH2 Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.komputerPH2Form"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH2"
+                ],
+                "summary": "Delete komputer-ph2 by id",
+                "operationId": "delete-komputer-ph2-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "KomputerPH2 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph2/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Komputer PH2"
+                ],
+                "summary": "Get maintenance history of a komputer ph2",
+                "operationId": "get-komputer-ph2-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Komputer PH2 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/komputer-ph2s": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device KomputerPH2"
+                ],
+                "summary": "Get all komputer-ph2s",
+                "operationId": "get-all-komputer-ph2s",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/location": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Create a site, an area under a site or a room under an area",
+                "operationId": "create-location",
+                "parameters": [
+                    {
+                        "description": "Location Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.locationForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/location/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Get location by id",
+                "operationId": "get-location-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Rename a location or move it under another parent of the same kind",
+                "operationId": "update-location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Location Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.locationForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Delete a location without locations or devices under it",
+                "operationId": "delete-location",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Location ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/locations": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Get all locations",
+                "operationId": "get-all-locations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by path or alias",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "site",
+                            "area",
+                            "room"
+                        ],
+                        "type": "string",
+                        "description": "Filter by kind",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by parent location ID",
+                        "name": "parent",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/locations/migrate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lokasi matching the name, alias or path of exactly one location, ignoring case and spacing, reference that location. The lokasi values left unmatched are reported.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Map the free text lokasi of devices without a location to locations",
+                "operationId": "migrate-locations",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/locations/tree": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Location"
+                ],
+                "summary": "Get the location hierarchy from sites down to rooms",
+                "operationId": "get-location-tree",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/login": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Login",
+                "operationId": "login",
+                "parameters": [
+                    {
+                        "description": "Login Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.loginForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/printer": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Printer"
+                ],
+                "summary": "Create new printer",
+                "operationId": "create-new-printer",
+                "parameters": [
+                    {
+                        "description": "Printer Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.printerForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/printer/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Printer"
+                ],
+                "summary": "Get printer by id",
+                "operationId": "get-printer-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Printer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Printer"
+                ],
+                "summary": "Update printer by id",
+                "operationId": "update-printer-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Printer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Printer Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.printerForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Printer"
+                ],
+                "summary": "Delete printer by id",
+                "operationId": "delete-printer-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Printer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/printer/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Printer"
+                ],
+                "summary": "Get maintenance history of a printer",
+                "operationId": "get-printer-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Printer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/printers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Printer"
+                ],
+                "summary": "Get all printers",
+                "operationId": "get-all-printers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by department ID",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/report/checkpoint-failures": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get the monthly failure rate of every checkpoint per device type",
+                "operationId": "get-checkpoint-failure-trend",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 12,
+                        "description": "Number of months",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month (YYYY-MM), default this month",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this checkpoint",
+                        "name": "checkpoint",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/report/compliance": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get maintenance compliance per device type and lokasi",
+                "operationId": "get-compliance-report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD), default first day of this month",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date inclusive (YYYY-MM-DD), default one month after from",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search by lokasi",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/report/failing-devices": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get the devices with the most failed checkpoints",
+                "operationId": "get-failing-devices",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 12,
+                        "description": "Number of months",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month (YYYY-MM), default this month",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of devices",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/report/rising-failures": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get the checkpoints whose failure rate is rising",
+                "operationId": "get-rising-checkpoint-failures",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 6,
+                        "description": "Number of months",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month (YYYY-MM), default this month",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/scan/{tag}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Label"
+                ],
+                "summary": "Resolve a scanned asset tag to its device, checkpoint template and last maintenance doc",
+                "operationId": "scan-asset-tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset tag, e.g. PRN-00042",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/schedule/devices": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedule"
+                ],
+                "summary": "Get devices with their last doc, next due date and overdue status",
+                "operationId": "get-schedule-devices",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by lokasi",
+                        "name": "lokasi",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by departemen",
+                        "name": "departemen",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "due",
+                            "overdue"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 7,
+                        "description": "Days ahead counted as due",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/schedule/{device}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedule"
+                ],
+                "summary": "Set maintenance interval of a device type",
+                "operationId": "update-schedule",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Schedule Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.scheduleForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/schedule/{device}/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedule"
+                ],
+                "summary": "Override maintenance interval of a device",
+                "operationId": "update-schedule-device",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Schedule Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.scheduleForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedule"
+                ],
+                "summary": "Remove maintenance interval override of a device",
+                "operationId": "delete-schedule-device",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Schedule"
+                ],
+                "summary": "Get all maintenance schedules",
+                "operationId": "get-all-schedules",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/sync/pull": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Get devices, checkpoints and docs changed since the cursor, with deleted ids",
+                "operationId": "sync-pull",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor returned by the previous pull, empty for the first pull",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/sync/push": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Sync"
+                ],
+                "summary": "Push docs created or edited offline, reporting conflicts per doc",
+                "operationId": "sync-push",
+                "parameters": [
+                    {
+                        "description": "Sync Push Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/doc.SyncPushForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/telepon": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Telepon"
+                ],
+                "summary": "Create new telepon",
+                "operationId": "create-new-telepon",
+                "parameters": [
+                    {
+                        "description": "Telepon Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.teleponForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/telepon/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Telepon"
+                ],
+                "summary": "Get telepon by id",
+                "operationId": "get-telepon-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telepon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Telepon"
+                ],
+                "summary": "Update telepon by id",
+                "operationId": "update-telepon-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telepon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Telepon Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.teleponForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Telepon"
+                ],
+                "summary": "Delete telepon by id",
+                "operationId": "delete-telepon-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telepon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/telepon/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc Telepon"
+                ],
+                "summary": "Get maintenance history of a telepon",
+                "operationId": "get-telepon-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telepon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/telepons": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device Telepon"
+                ],
+                "summary": "Get all telepons",
+                "operationId": "get-all-telepons",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by tipe",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by department ID",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/ticket/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ticket"
+                ],
+                "summary": "Get ticket by ID",
+                "operationId": "get-ticket-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ticket"
+                ],
+                "summary": "Update ticket status and resolution notes",
+                "operationId": "update-ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ticket Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ticketForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/ticket/{id}/assign": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ticket"
+                ],
+                "summary": "Assign ticket to a technician",
+                "operationId": "assign-ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ticket Assign Form",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ticketAssignForm"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/tickets": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ticket"
+                ],
+                "summary": "Get all corrective maintenance tickets",
+                "operationId": "get-all-tickets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by checkpoint",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "open",
+                            "in_progress",
+                            "waiting_parts",
+                            "resolved"
+                        ],
+                        "type": "string",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Assigned technician ID",
+                        "name": "assignee_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/toa": {
+            "post": {
+                "security": [
                     {
-                        "description": "Telepon Form",
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device TOA"
+                ],
+                "summary": "Create new toa",
+                "operationId": "create-new-toa",
+                "parameters": [
+                    {
+                        "description": "TOA Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.teleponForm"
+                            "$ref": "#/definitions/handler.toaForm"
                         }
                     }
                 ],
@@ -2958,7 +7226,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/telepon/{id}": {
+        "/api/toa/{id}": {
             "get": {
                 "security": [
                     {
@@ -2969,14 +7237,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Telepon"
+                    "Device TOA"
                 ],
-                "summary": "Get telepon by id",
-                "operationId": "get-telepon-by-id",
+                "summary": "Get toa by id",
+                "operationId": "get-toa-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telepon ID",
+                        "description": "TOA ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -2998,25 +7266,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Telepon"
+                    "Device TOA"
                 ],
-                "summary": "Update telepon by id",
-                "operationId": "update-telepon-by-id",
+                "summary": "Update toa by id",
+                "operationId": "update-toa-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telepon ID",
+                        "description": "TOA ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Telepon Form",
+                        "description": "TOA Form",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handler.teleponForm"
+                            "$ref": "#/definitions/handler.toaForm"
                         }
                     }
                 ],
@@ -3036,14 +7304,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Telepon"
+                    "Device TOA"
                 ],
-                "summary": "Delete telepon by id",
-                "operationId": "delete-telepon-by-id",
+                "summary": "Delete toa by id",
+                "operationId": "delete-toa-by-id",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telepon ID",
+                        "description": "TOA ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -3056,7 +7324,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/telepons": {
+        "/api/toa/{id}/docs": {
             "get": {
                 "security": [
                     {
@@ -3067,17 +7335,91 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device Telepon"
+                    "Doc TOA"
                 ],
-                "summary": "Get all telepons",
-                "operationId": "get-all-telepons",
+                "summary": "Get maintenance history of a toa",
+                "operationId": "get-toa-documents-by-device",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by tipe",
+                        "description": "TOA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/toas": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Device TOA"
+                ],
+                "summary": "Get all toas",
+                "operationId": "get-all-toas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
                         "name": "q",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -3110,7 +7452,106 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/toa": {
+        "/api/trash": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Get deleted devices, docs and users with who deleted them and when",
+                "operationId": "get-trash",
+                "parameters": [
+                    {
+                        "enum": [
+                            "device",
+                            "doc",
+                            "user"
+                        ],
+                        "type": "string",
+                        "description": "Only this kind",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer_ph1",
+                            "komputer_ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Only this device type",
+                        "name": "device",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/trash/device/{device}/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Permanently delete a deleted device",
+                "operationId": "purge-trash-device",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/trash/device/{device}/{id}/restore": {
             "post": {
                 "security": [
                     {
@@ -3121,19 +7562,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device TOA"
+                    "Trash"
                 ],
-                "summary": "Create new toa",
-                "operationId": "create-new-toa",
+                "summary": "Restore a deleted device",
+                "operationId": "restore-trash-device",
                 "parameters": [
                     {
-                        "description": "TOA Form",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.toaForm"
-                        }
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -3143,8 +7599,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/toa/{id}": {
-            "get": {
+        "/api/trash/doc/{device}/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -3154,14 +7610,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device TOA"
+                    "Trash"
                 ],
-                "summary": "Get toa by id",
-                "operationId": "get-toa-by-id",
+                "summary": "Permanently delete a deleted document with its attachments and revisions",
+                "operationId": "purge-trash-doc",
                 "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
-                        "description": "TOA ID",
+                        "description": "Document ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -3172,8 +7645,10 @@ const docTemplate = `{
                         "description": "OK"
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/trash/doc/{device}/{id}/restore": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -3183,26 +7658,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device TOA"
+                    "Trash"
                 ],
-                "summary": "Update toa by id",
-                "operationId": "update-toa-by-id",
+                "summary": "Restore a deleted document",
+                "operationId": "restore-trash-doc",
                 "parameters": [
                     {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
                         "type": "string",
-                        "description": "TOA ID",
-                        "name": "id",
+                        "description": "Device type",
+                        "name": "device",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "TOA Form",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.toaForm"
-                        }
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -3210,7 +7693,9 @@ const docTemplate = `{
                         "description": "OK"
                     }
                 }
-            },
+            }
+        },
+        "/api/trash/user/{id}": {
             "delete": {
                 "security": [
                     {
@@ -3221,14 +7706,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device TOA"
+                    "Trash"
                 ],
-                "summary": "Delete toa by id",
-                "operationId": "delete-toa-by-id",
+                "summary": "Permanently delete a deleted user",
+                "operationId": "purge-trash-user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "TOA ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -3241,8 +7726,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/toas": {
-            "get": {
+        "/api/trash/user/{id}/restore": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
@@ -3252,40 +7737,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Device TOA"
+                    "Trash"
                 ],
-                "summary": "Get all toas",
-                "operationId": "get-all-toas",
+                "summary": "Restore a deleted user",
+                "operationId": "restore-trash-user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by nama",
-                        "name": "q",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit pagination",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
-                        "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
-                        "in": "query"
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -3317,6 +7779,31 @@ const docTemplate = `{
                         "name": "q",
                         "in": "query"
                     },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by department ID",
+                        "name": "department",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -3478,6 +7965,61 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/ups/{id}/docs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Doc UPS"
+                ],
+                "summary": "Get maintenance history of a ups",
+                "operationId": "get-ups-documents-by-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UPS ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort by date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
         "/api/user": {
             "post": {
                 "security": [
@@ -3596,10 +8138,158 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "username",
-                        "name": "username",
-                        "in": "path",
-                        "required": true
+                        "description": "username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get all users",
+                "operationId": "user-find",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by fullname",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/api/{type}/export": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export the devices of a type matching the list filters as CSV or XLSX",
+                "operationId": "export-devices",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
+                        "type": "string",
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search by nama",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "in_repair",
+                            "spare",
+                            "retired",
+                            "disposed"
+                        ],
+                        "type": "string",
+                        "description": "Filter by lifecycle status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by location ID, including the locations under it",
+                        "name": "location",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by department ID, for printer, telepon and ups",
+                        "name": "department",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -3609,51 +8299,66 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users": {
-            "get": {
+        "/api/{type}/import": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
+                "description": "The first row holds the column headers. Columns are matched to fields by name or label unless mapped. A dry run only validates the rows; a commit inserts the valid ones. Lokasi and departemen reference the location and department matching them by name, alias or path; the values matching none or several are listed as unmatched and imported as free text.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "User"
+                    "Device"
                 ],
-                "summary": "Get all users",
-                "operationId": "user-find",
+                "summary": "Import devices from a CSV or XLSX file",
+                "operationId": "import-devices",
                 "parameters": [
                     {
+                        "enum": [
+                            "cctv",
+                            "fingerprint",
+                            "komputer-ph1",
+                            "komputer-ph2",
+                            "printer",
+                            "telepon",
+                            "toa",
+                            "ups"
+                        ],
                         "type": "string",
-                        "description": "Search by fullname",
-                        "name": "q",
-                        "in": "query"
+                        "description": "Device type",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number pagination",
-                        "name": "page",
-                        "in": "query"
+                        "type": "file",
+                        "description": "CSV or XLSX file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Limit pagination",
-                        "name": "limit",
-                        "in": "query"
+                        "type": "string",
+                        "description": "JSON object of field name to column header, e.g. {\\",
+                        "name": "mapping",
+                        "in": "formData"
                     },
                     {
                         "enum": [
-                            "asc",
-                            "desc"
+                            "dry-run",
+                            "commit"
                         ],
                         "type": "string",
-                        "description": "Sort",
-                        "name": "sort",
-                        "in": "query"
+                        "default": "dry-run",
+                        "description": "Import mode",
+                        "name": "mode",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
@@ -3665,6 +8370,20 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "doc.BulkDeviceDocForm": {
+            "type": "object",
+            "properties": {
+                "atomic": {
+                    "type": "boolean"
+                },
+                "docs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/doc.DeviceDocForm"
+                    }
+                }
+            }
+        },
         "doc.CPDetail": {
             "type": "object",
             "properties": {
@@ -3676,6 +8395,48 @@ const docTemplate = `{
                 },
                 "ok": {
                     "type": "boolean"
+                },
+                "unit": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "doc.CPItem": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "pass": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "boolean",
+                        "number",
+                        "enum",
+                        "text"
+                    ]
+                },
+                "unit": {
+                    "type": "string"
                 }
             }
         },
@@ -3690,6 +8451,54 @@ const docTemplate = `{
                 },
                 "device_id": {
                     "type": "string"
+                },
+                "submit": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "doc.RejectDocForm": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "doc.SyncDocForm": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string"
+                },
+                "base": {
+                    "type": "string"
+                },
+                "checkpoint": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/doc.CPDetail"
+                    }
+                },
+                "device": {
+                    "type": "string"
+                },
+                "device_id": {
+                    "type": "string"
+                },
+                "submit": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "doc.SyncPushForm": {
+            "type": "object",
+            "properties": {
+                "docs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/doc.SyncDocForm"
+                    }
                 }
             }
         },
@@ -3710,6 +8519,9 @@ const docTemplate = `{
                 "kode": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3718,12 +8530,61 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.customDeviceForm": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "handler.departmentForm": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cost_center": {
+                    "type": "string"
+                },
+                "head": {
+                    "$ref": "#/definitions/repo.DepartmentHead"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.deviceTypeForm": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/repo.DeviceField"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.fingerPrintForm": {
             "type": "object",
             "properties": {
                 "kode": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3741,6 +8602,9 @@ const docTemplate = `{
                 "internal": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3770,6 +8634,9 @@ const docTemplate = `{
                 "internal": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3790,6 +8657,26 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.locationForm": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.loginForm": {
             "type": "object",
             "properties": {
@@ -3807,6 +8694,12 @@ const docTemplate = `{
                 "departemen": {
                     "type": "string"
                 },
+                "department_id": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
                 "nama": {
                     "type": "string"
                 },
@@ -3818,15 +8711,40 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.scheduleForm": {
+            "type": "object",
+            "properties": {
+                "interval": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.statusForm": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.teleponForm": {
             "type": "object",
             "properties": {
                 "departemen": {
                     "type": "string"
                 },
+                "department_id": {
+                    "type": "string"
+                },
                 "ext": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3841,12 +8759,34 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.ticketAssignForm": {
+            "type": "object",
+            "properties": {
+                "assignee_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ticketForm": {
+            "type": "object",
+            "properties": {
+                "resolution": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "handler.toaForm": {
             "type": "object",
             "properties": {
                 "kode": {
                     "type": "string"
                 },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3866,6 +8806,12 @@ const docTemplate = `{
                     "items": {
                         "type": "string"
                     }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/doc.CPItem"
+                    }
                 }
             }
         },
@@ -3875,6 +8821,12 @@ const docTemplate = `{
                 "departemen": {
                     "type": "string"
                 },
+                "department_id": {
+                    "type": "string"
+                },
+                "location_id": {
+                    "type": "string"
+                },
                 "lokasi": {
                     "type": "string"
                 },
@@ -3902,6 +8854,46 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "repo.DepartmentHead": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                }
+            }
+        },
+        "repo.DeviceField": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "searchable": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "number",
+                        "boolean",
+                        "date"
+                    ]
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -16,6 +16,21 @@ var App struct {
 	SwaggerHost      string `mapstructure:"SWAGGER_HOST"`
 }
 
+var Company struct {
+	Name    string `mapstructure:"COMPANY_NAME"`
+	Address string `mapstructure:"COMPANY_ADDRESS"`
+}
+
+var Storage struct {
+	Driver  string `mapstructure:"STORAGE_DRIVER"`
+	Dir     string `mapstructure:"STORAGE_DIR"`
+	MaxSize int64  `mapstructure:"STORAGE_MAX_SIZE_MB"`
+}
+
+var Trash struct {
+	RetentionDays int `mapstructure:"TRASH_RETENTION_DAYS"`
+}
+
 var JWT struct {
 	Key    string `mapstructure:"AUTH_JWT_KEY"`
 	Expire int    `mapstructure:"AUTH_JWT_EXPIRE"`
@@ -55,6 +70,40 @@ func init() {
 		panic("SWAGGER_HOST is not set")
 	}
 
+	Company.Name = os.Getenv("COMPANY_NAME")
+	if Company.Name == "" {
+		Company.Name = "Sistem Pencatatan Maintenance IT"
+	}
+	Company.Address = os.Getenv("COMPANY_ADDRESS")
+
+	Storage.Driver = os.Getenv("STORAGE_DRIVER")
+	if Storage.Driver == "" {
+		Storage.Driver = "local"
+	}
+	if Storage.Driver != "local" && Storage.Driver != "gridfs" {
+		panic("STORAGE_DRIVER is not valid")
+	}
+	Storage.Dir = os.Getenv("STORAGE_DIR")
+	if Storage.Dir == "" {
+		Storage.Dir = path.Join(rootPath, "storage")
+	}
+	Storage.MaxSize = 10
+	if maxSize := os.Getenv("STORAGE_MAX_SIZE_MB"); maxSize != "" {
+		Storage.MaxSize, err = strconv.ParseInt(maxSize, 10, 64)
+		if err != nil || Storage.MaxSize < 1 {
+			panic("STORAGE_MAX_SIZE_MB is not valid")
+		}
+	}
+	Storage.MaxSize *= 1 << 20
+
+	Trash.RetentionDays = 30
+	if retention := os.Getenv("TRASH_RETENTION_DAYS"); retention != "" {
+		Trash.RetentionDays, err = strconv.Atoi(retention)
+		if err != nil || Trash.RetentionDays < 0 {
+			panic("TRASH_RETENTION_DAYS is not valid")
+		}
+	}
+
 	JWT.Key = os.Getenv("AUTH_JWT_KEY")
 	if JWT.Key == "" {
 		panic("AUTH_JWT_KEY is not set")
@@ -0,0 +1,22 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+// AssetTag gives an asset tag to the devices created before tags existed.
+func AssetTag(db *mongo.Database) {
+	deviceRepo := repo.NewDeviceRepository(db)
+
+	for _, device := range _const.Devices {
+		n, err := deviceRepo.TagUntagged(device)
+		if err != nil {
+			log.Errorf("Failed to tag %s devices: %v", device, err)
+			continue
+		}
+		log.Infof("%s devices tagged: %d", _const.DeviceLabels[device], n)
+	}
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device_cp/repo"
+	"sipamit-be/internal/pkg/doc"
+	"time"
+)
+
+// CheckpointVersion stores the current checkpoint templates that predate
+// versioning as their first version.
+func CheckpointVersion(db *mongo.Database) {
+	cpRepo := repo.NewCheckpointRepository(db)
+	cpVersionRepo := repo.NewCheckpointVersionRepository(db)
+
+	cps, err := cpRepo.FindAll()
+	if err != nil {
+		log.Errorf("Failed to get checkpoints: %v", err)
+		return
+	}
+
+	for _, cp := range *cps {
+		if cp.Version > 0 {
+			continue
+		}
+
+		inserted := doc.ByAt{At: time.Now()}
+		if cp.Updated != nil {
+			inserted = *cp.Updated
+		}
+
+		cp.Version = 1
+		err = cpVersionRepo.InsertOne(&repo.CheckpointVersion{
+			ID:         bson.NewObjectID(),
+			Device:     cp.Device,
+			Version:    cp.Version,
+			Checkpoint: cp.Checkpoint,
+			Inserted:   inserted,
+		})
+		if err != nil {
+			log.Errorf("Failed to store %s checkpoint version: %v", cp.Device, err)
+			continue
+		}
+
+		err = cpRepo.UpdateByDevice(cp.Device, &cp)
+		if err != nil {
+			log.Errorf("Failed to update %s checkpoint version: %v", cp.Device, err)
+			continue
+		}
+		log.Infof("%s checkpoint versioned", cp.Device)
+	}
+}
@@ -0,0 +1,42 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	repo3 "sipamit-be/api/app/repo"
+	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+// DeletedAt dates the devices, docs and users deleted before the deleter was
+// tracked as deleted now, so the trash retention purges them once it has
+// passed from the migration on.
+func DeletedAt(db *mongo.Database) {
+	deviceRepo := repo.NewDeviceRepository(db)
+	docRepo := repo2.NewDocRepository(db)
+	userRepo := repo3.NewUserRepository(db)
+
+	for _, device := range _const.Devices {
+		n, err := deviceRepo.InitDeletedAt(device)
+		if err != nil {
+			log.Errorf("Failed to date deleted %s devices: %v", device, err)
+		} else {
+			log.Infof("deleted %s devices dated: %d", _const.DeviceLabels[device], n)
+		}
+
+		n, err = docRepo.InitDeletedAt(device)
+		if err != nil {
+			log.Errorf("Failed to date deleted %s docs: %v", device, err)
+		} else {
+			log.Infof("deleted %s docs dated: %d", _const.DeviceLabels[device], n)
+		}
+	}
+
+	n, err := userRepo.InitDeletedAt()
+	if err != nil {
+		log.Errorf("Failed to date deleted users: %v", err)
+		return
+	}
+	log.Infof("deleted users dated: %d", n)
+}
@@ -0,0 +1,141 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/department/repo"
+	repo2 "sipamit-be/api/device/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/doc"
+	"sipamit-be/internal/pkg/util"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// departemenGroup holds the spellings of one departemen across the printers,
+// telepons and UPS without a department, with their number of devices.
+type departemenGroup struct {
+	spellings map[string]int64
+	devices   map[string][]string
+}
+
+// name returns the spelling used by the most devices, the first one in
+// alphabetical order on a tie, with its spacing collapsed.
+func (g *departemenGroup) name() string {
+	spellings := make([]string, 0, len(g.spellings))
+	for spelling := range g.spellings {
+		spellings = append(spellings, spelling)
+	}
+	sort.Slice(spellings, func(i, j int) bool {
+		if g.spellings[spellings[i]] != g.spellings[spellings[j]] {
+			return g.spellings[spellings[i]] > g.spellings[spellings[j]]
+		}
+		return spellings[i] < spellings[j]
+	})
+	return strings.Join(strings.Fields(spellings[0]), " ")
+}
+
+// Department turns the free text departemen of printers, telepons and UPS into
+// department records. Spellings differing only by case, punctuation or
+// spacing become one department, named after the most used spelling, with the
+// other spellings as aliases. Existing departments are matched by name or
+// alias, so running it again only handles devices added without a department.
+func Department(db *mongo.Database) {
+	deviceRepo := repo2.NewDeviceRepository(db)
+	departmentRepo := repo.NewDepartmentRepository(db)
+
+	departments, err := departmentRepo.FindAllSorted()
+	if err != nil {
+		log.Errorf("Failed to get departments: %v", err)
+		return
+	}
+
+	known := make(map[string]*repo.Department)
+	for i := range departments {
+		department := &departments[i]
+		known[util.NameKey(department.Name)] = department
+		for _, alias := range department.Aliases {
+			known[util.NameKey(alias)] = department
+		}
+	}
+
+	groups := make(map[string]*departemenGroup)
+	for _, device := range _const.DepartmentDevices {
+		counts, err := deviceRepo.CountUndepartmented(device)
+		if err != nil {
+			log.Errorf("Failed to get %s departemen: %v", device, err)
+			return
+		}
+
+		for departemen, count := range counts {
+			key := util.NameKey(departemen)
+			if key == "" {
+				continue
+			}
+
+			g, ok := groups[key]
+			if !ok {
+				g = &departemenGroup{spellings: map[string]int64{}, devices: map[string][]string{}}
+				groups[key] = g
+			}
+			g.spellings[departemen] += count
+			g.devices[departemen] = append(g.devices[departemen], device)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		g := groups[key]
+
+		department, ok := known[key]
+		if !ok {
+			department = &repo.Department{
+				ID:        bson.NewObjectID(),
+				Name:      g.name(),
+				Inserted:  doc.ByAt{At: time.Now()},
+				IsDeleted: false,
+			}
+			err = departmentRepo.InsertOne(department)
+			if err != nil {
+				log.Errorf("Failed to create department %s: %v", department.Name, err)
+				continue
+			}
+			known[key] = department
+			log.Infof("department %s created", department.Name)
+		}
+
+		aliased := false
+		for spelling := range g.spellings {
+			if spelling != department.Name && !slices.Contains(department.Aliases, spelling) {
+				department.Aliases = append(department.Aliases, spelling)
+				aliased = true
+			}
+		}
+		if aliased {
+			sort.Strings(department.Aliases)
+			err = departmentRepo.UpdateOneByID(department.ID, department)
+			if err != nil {
+				log.Errorf("Failed to update department %s aliases: %v", department.Name, err)
+			}
+		}
+
+		for spelling, devices := range g.devices {
+			for _, device := range devices {
+				n, err := deviceRepo.AssignDepartment(device, spelling, department.ID, department.Name)
+				if err != nil {
+					log.Errorf("Failed to assign %s departemen %q: %v", device, spelling, err)
+					continue
+				}
+				log.Infof("%s departemen %q assigned to %s: %d", _const.DeviceLabels[device], spelling, department.Name, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package migration
+
+import (
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device/repo"
+	docRepo "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/util"
+)
+
+type docLinker interface {
+	LinkDevice(deviceID bson.ObjectID, match bson.M) (int64, error)
+	CountUnlinked() (int64, error)
+}
+
+type deviceMatch struct {
+	ID    bson.ObjectID
+	Match bson.M
+}
+
+// matchDevice returns the doc filter identifying a device by the primary
+// fields snapshotted on its docs, or by the fallback fields when the first
+// primary field is empty. A fallback match only takes docs that lack the
+// primary field too. It returns nil when neither can be used.
+func matchDevice(primary, fallback bson.D) bson.M {
+	match := bson.M{}
+	switch {
+	case len(primary) > 0 && primary[0].Value != "":
+		for _, e := range primary {
+			match[e.Key] = e.Value
+		}
+	case len(fallback) > 0 && fallback[0].Value != "":
+		for _, e := range fallback {
+			match[e.Key] = e.Value
+		}
+		match[primary[0].Key] = bson.M{"$in": bson.A{nil, ""}}
+	default:
+		return nil
+	}
+	return match
+}
+
+// DeviceDoc back-links maintenance docs created before the device reference
+// was stored, matching them by the identifying fields snapshotted on the doc.
+func DeviceDoc(db *mongo.Database) {
+	cctvDoc(db)
+	fingerprintDoc(db)
+	komputerPH1Doc(db)
+	komputerPH2Doc(db)
+	printerDoc(db)
+	teleponDoc(db)
+	toaDoc(db)
+	upsDoc(db)
+}
+
+// linkDocs links the docs of every device, except for devices sharing the
+// same match with another device: their docs cannot be told apart and are
+// left unlinked and reported.
+func linkDocs(name string, linker docLinker, devices []deviceMatch) {
+	shared := make(map[string][]bson.ObjectID)
+	for _, d := range devices {
+		key := fmt.Sprint(d.Match)
+		shared[key] = append(shared[key], d.ID)
+	}
+
+	var linked int64
+	for _, d := range devices {
+		ids := shared[fmt.Sprint(d.Match)]
+		if len(ids) > 1 {
+			continue
+		}
+
+		n, err := linker.LinkDevice(d.ID, d.Match)
+		if err != nil {
+			log.Errorf("Failed to link %s doc to device %s: %v", name, d.ID.Hex(), err)
+			continue
+		}
+		linked += n
+	}
+
+	for key, ids := range shared {
+		if len(ids) > 1 {
+			log.Warnf("%s docs matching %s are shared by devices %v, left unlinked", name, key, ids)
+		}
+	}
+
+	unlinked, err := linker.CountUnlinked()
+	if err != nil {
+		log.Errorf("Failed to count unlinked %s docs: %v", name, err)
+		return
+	}
+	log.Infof("%s docs linked: %d, unmatched: %d", name, linked, unlinked)
+}
+
+func cctvDoc(db *mongo.Database) {
+	cctvs, err := repo.NewCCTVRepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get cctvs: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, cctv := range *cctvs {
+		match := matchDevice(
+			bson.D{{Key: "kode", Value: cctv.Kode}},
+			bson.D{{Key: "nama", Value: cctv.Nama}, {Key: "lokasi", Value: cctv.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: cctv.ID, Match: match})
+	}
+	linkDocs("CCTV", docRepo.NewCCTVDocRepository(db), devices)
+}
+
+func fingerprintDoc(db *mongo.Database) {
+	fps, err := repo.NewFingerPrintRepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get fingerprints: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, fp := range *fps {
+		match := matchDevice(
+			bson.D{{Key: "kode", Value: fp.Kode}},
+			bson.D{{Key: "nama", Value: fp.Nama}, {Key: "lokasi", Value: fp.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: fp.ID, Match: match})
+	}
+	linkDocs("Fingerprint", docRepo.NewFingerprintDocRepository(db), devices)
+}
+
+func komputerPH1Doc(db *mongo.Database) {
+	kph1s, err := repo.NewKomputerPH1Repository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get komputer ph1s: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, kph1 := range *kph1s {
+		match := matchDevice(
+			bson.D{{Key: "nama", Value: kph1.Nama}, {Key: "lokasi", Value: kph1.Lokasi}},
+			bson.D{{Key: "pc", Value: kph1.PC}, {Key: "lokasi", Value: kph1.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: kph1.ID, Match: match})
+	}
+	linkDocs("Komputer PH1", docRepo.NewKomputerPH1DocRepository(db), devices)
+}
+
+func komputerPH2Doc(db *mongo.Database) {
+	kph2s, err := repo.NewKomputerPH2Repository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get komputer ph2s: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, kph2 := range *kph2s {
+		match := matchDevice(
+			bson.D{{Key: "nama", Value: kph2.Nama}, {Key: "lokasi", Value: kph2.Lokasi}},
+			bson.D{{Key: "pc", Value: kph2.PC}, {Key: "lokasi", Value: kph2.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: kph2.ID, Match: match})
+	}
+	linkDocs("Komputer PH2", docRepo.NewKomputerPH2DocRepository(db), devices)
+}
+
+func printerDoc(db *mongo.Database) {
+	printers, err := repo.NewPrinterRepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get printers: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, printer := range *printers {
+		match := matchDevice(
+			bson.D{{Key: "no_seri", Value: printer.NoSeri}},
+			bson.D{{Key: "nama", Value: printer.Nama}, {Key: "departemen", Value: printer.Departemen}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: printer.ID, Match: match})
+	}
+	linkDocs("Printer", docRepo.NewPrinterDocRepository(db), devices)
+}
+
+// teleponDoc matches on Ext and Lokasi since telepons have no Kode, NoSeri or
+// Nama, falling back to User and Lokasi.
+func teleponDoc(db *mongo.Database) {
+	telepons, err := repo.NewTeleponRepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get telepons: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, telepon := range *telepons {
+		match := matchDevice(
+			bson.D{{Key: "ext", Value: telepon.Ext}, {Key: "lokasi", Value: telepon.Lokasi}},
+			bson.D{{Key: "user", Value: telepon.User}, {Key: "lokasi", Value: telepon.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: telepon.ID, Match: match})
+	}
+	linkDocs("Telepon", docRepo.NewTeleponDocRepository(db), devices)
+}
+
+func toaDoc(db *mongo.Database) {
+	toas, err := repo.NewTOARepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get toas: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, toa := range *toas {
+		match := matchDevice(
+			bson.D{{Key: "kode", Value: toa.Kode}},
+			bson.D{{Key: "nama", Value: toa.Nama}, {Key: "lokasi", Value: toa.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: toa.ID, Match: match})
+	}
+	linkDocs("TOA", docRepo.NewTOADocRepository(db), devices)
+}
+
+func upsDoc(db *mongo.Database) {
+	upss, err := repo.NewUPSRepository(db).FindAll(util.NilCommonQuery())
+	if err != nil {
+		log.Errorf("Failed to get ups: %v", err)
+		return
+	}
+
+	var devices []deviceMatch
+	for _, ups := range *upss {
+		match := matchDevice(
+			bson.D{{Key: "no_seri", Value: ups.NoSeri}},
+			bson.D{{Key: "nama", Value: ups.Nama}, {Key: "lokasi", Value: ups.Lokasi}},
+		)
+		if match == nil {
+			continue
+		}
+		devices = append(devices, deviceMatch{ID: ups.ID, Match: match})
+	}
+	linkDocs("UPS", docRepo.NewUPSDocRepository(db), devices)
+}
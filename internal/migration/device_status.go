@@ -0,0 +1,23 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+// DeviceStatus marks the devices created before the lifecycle status was
+// tracked as active.
+func DeviceStatus(db *mongo.Database) {
+	deviceRepo := repo.NewDeviceRepository(db)
+
+	for _, device := range _const.Devices {
+		n, err := deviceRepo.InitStatus(device)
+		if err != nil {
+			log.Errorf("Failed to set %s status: %v", device, err)
+			continue
+		}
+		log.Infof("%s devices marked active: %d", _const.DeviceLabels[device], n)
+	}
+}
@@ -0,0 +1,23 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+// DocStatus puts docs created before the approval workflow into the review
+// queue as submitted.
+func DocStatus(db *mongo.Database) {
+	docRepo := repo.NewDocRepository(db)
+
+	for _, device := range _const.Devices {
+		n, err := docRepo.InitStatus(device, _const.DocSubmitted)
+		if err != nil {
+			log.Errorf("Failed to set %s doc status: %v", device, err)
+			continue
+		}
+		log.Infof("%s docs submitted for review: %d", _const.DeviceLabels[device], n)
+	}
+}
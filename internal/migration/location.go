@@ -0,0 +1,132 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/location/repo"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/util"
+	"sort"
+	"strings"
+)
+
+// LocationMatch is a lokasi value mapped to a location.
+type LocationMatch struct {
+	Device     string        `json:"device"`
+	Lokasi     string        `json:"lokasi"`
+	LocationID bson.ObjectID `json:"location_id"`
+	Path       string        `json:"path"`
+	Count      int64         `json:"count"`
+}
+
+// UnmatchedLokasi is a lokasi value matching no location, or several ones
+// listed in Candidates.
+type UnmatchedLokasi struct {
+	Device     string   `json:"device"`
+	Lokasi     string   `json:"lokasi"`
+	Count      int64    `json:"count"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+type LocationReport struct {
+	Matched   []LocationMatch   `json:"matched"`
+	Unmatched []UnmatchedLokasi `json:"unmatched"`
+}
+
+// MapLocations references the location matching the lokasi of every device
+// without a location. A lokasi matches a location by its name, an alias or its
+// path. Values matching no location or several ones are left as they are and
+// reported.
+func MapLocations(db *mongo.Database) (*LocationReport, error) {
+	deviceRepo := repo.NewDeviceRepository(db)
+	locationRepo := repo2.NewLocationRepository(db)
+
+	locations, err := locationRepo.FindAllSorted()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string][]*repo2.Location)
+	for i := range locations {
+		location := &locations[i]
+
+		keys := map[string]bool{
+			util.NameKey(location.Name): true,
+			util.NameKey(location.Path): true,
+		}
+		for _, alias := range location.Aliases {
+			keys[util.NameKey(alias)] = true
+		}
+		for key := range keys {
+			if key != "" {
+				candidates[key] = append(candidates[key], location)
+			}
+		}
+	}
+
+	report := &LocationReport{
+		Matched:   []LocationMatch{},
+		Unmatched: []UnmatchedLokasi{},
+	}
+	for _, device := range _const.Devices {
+		counts, err := deviceRepo.CountUnlocated(device)
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]string, 0, len(counts))
+		for lokasi := range counts {
+			values = append(values, lokasi)
+		}
+		sort.Strings(values)
+
+		for _, lokasi := range values {
+			matches := candidates[util.NameKey(lokasi)]
+			if len(matches) != 1 {
+				unmatched := UnmatchedLokasi{Device: device, Lokasi: lokasi, Count: counts[lokasi]}
+				for _, location := range matches {
+					unmatched.Candidates = append(unmatched.Candidates, location.Path)
+				}
+				report.Unmatched = append(report.Unmatched, unmatched)
+				continue
+			}
+
+			location := matches[0]
+			n, err := deviceRepo.Locate(device, lokasi, location.ID, location.Name)
+			if err != nil {
+				return nil, err
+			}
+			report.Matched = append(report.Matched, LocationMatch{
+				Device:     device,
+				Lokasi:     lokasi,
+				LocationID: location.ID,
+				Path:       location.Path,
+				Count:      n,
+			})
+		}
+	}
+	return report, nil
+}
+
+// Location maps the lokasi of devices to the locations, logging the values it
+// could not match.
+func Location(db *mongo.Database) {
+	report, err := MapLocations(db)
+	if err != nil {
+		log.Errorf("Failed to map lokasi to locations: %v", err)
+		return
+	}
+
+	for _, m := range report.Matched {
+		log.Infof("%s lokasi %q mapped to %s: %d", _const.DeviceLabels[m.Device], m.Lokasi, m.Path, m.Count)
+	}
+	for _, u := range report.Unmatched {
+		if len(u.Candidates) > 0 {
+			log.Warnf("%s lokasi %q matches several locations (%s): %d devices left", _const.DeviceLabels[u.Device], u.Lokasi, strings.Join(u.Candidates, ", "), u.Count)
+			continue
+		}
+		log.Warnf("%s lokasi %q matches no location: %d devices left", _const.DeviceLabels[u.Device], u.Lokasi, u.Count)
+	}
+}
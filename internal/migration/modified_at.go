@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device/repo"
+	repo2 "sipamit-be/api/device_cp/repo"
+	repo3 "sipamit-be/api/device_doc/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+// ModifiedAt stamps the devices, checkpoints and docs written before the
+// sync cursor was tracked, so they are returned by the first pull.
+func ModifiedAt(db *mongo.Database) {
+	deviceRepo := repo.NewDeviceRepository(db)
+	cpRepo := repo2.NewCheckpointRepository(db)
+	docRepo := repo3.NewDocRepository(db)
+
+	for _, device := range _const.Devices {
+		n, err := deviceRepo.InitModifiedAt(device)
+		if err != nil {
+			log.Errorf("Failed to stamp %s devices: %v", device, err)
+		} else {
+			log.Infof("%s devices stamped: %d", _const.DeviceLabels[device], n)
+		}
+
+		n, err = docRepo.InitModifiedAt(device)
+		if err != nil {
+			log.Errorf("Failed to stamp %s docs: %v", device, err)
+		} else {
+			log.Infof("%s docs stamped: %d", _const.DeviceLabels[device], n)
+		}
+	}
+
+	n, err := cpRepo.InitModifiedAt()
+	if err != nil {
+		log.Errorf("Failed to stamp checkpoints: %v", err)
+		return
+	}
+	log.Infof("checkpoints stamped: %d", n)
+}
@@ -1,5 +1,7 @@
 package _const
 
+import "strings"
+
 const (
 	AdminRole      = "admin"
 	SuperAdminRole = "superadmin"
@@ -16,6 +18,121 @@ const (
 	Ups         = "ups"
 )
 
+const (
+	DocDraft     = "draft"
+	DocSubmitted = "submitted"
+	DocApproved  = "approved"
+	DocRejected  = "rejected"
+)
+
+const (
+	DeviceActive   = "active"
+	DeviceInRepair = "in_repair"
+	DeviceSpare    = "spare"
+	DeviceRetired  = "retired"
+	DeviceDisposed = "disposed"
+)
+
+// DeviceTransitions lists the lifecycle statuses a device can move to from
+// each status. Disposed is final.
+var DeviceTransitions = map[string][]string{
+	DeviceActive:   {DeviceInRepair, DeviceSpare, DeviceRetired},
+	DeviceInRepair: {DeviceActive, DeviceRetired},
+	DeviceSpare:    {DeviceActive, DeviceRetired},
+	DeviceRetired:  {DeviceDisposed},
+}
+
+const (
+	LocationSite = "site"
+	LocationArea = "area"
+	LocationRoom = "room"
+)
+
+// LocationParents maps each location kind to the kind of its parent. Sites
+// are the roots of the hierarchy.
+var LocationParents = map[string]string{
+	LocationArea: LocationSite,
+	LocationRoom: LocationArea,
+}
+
+const (
+	TicketOpen         = "open"
+	TicketInProgress   = "in_progress"
+	TicketWaitingParts = "waiting_parts"
+	TicketResolved     = "resolved"
+)
+
+const (
+	CPBoolean = "boolean"
+	CPNumber  = "number"
+	CPEnum    = "enum"
+	CPText    = "text"
+)
+
+const (
+	FieldText    = "text"
+	FieldNumber  = "number"
+	FieldBoolean = "boolean"
+	FieldDate    = "date"
+)
+
+var Devices = []string{
+	CCTV,
+	Fingerprint,
+	KomputerPH1,
+	KomputerPH2,
+	Printer,
+	Telepon,
+	Toa,
+	Ups,
+}
+
+// DepartmentDevices are the device types referencing a department.
+var DepartmentDevices = []string{
+	Printer,
+	Telepon,
+	Ups,
+}
+
+var DeviceLabels = map[string]string{
+	CCTV:        "CCTV",
+	Fingerprint: "Fingerprint",
+	KomputerPH1: "Komputer PH1",
+	KomputerPH2: "Komputer PH2",
+	Printer:     "Printer",
+	Telepon:     "Telepon",
+	Toa:         "TOA",
+	Ups:         "UPS",
+}
+
+// DeviceTagPrefixes are the asset tag prefixes of each device type, telling
+// the type of a scanned tag.
+var DeviceTagPrefixes = map[string]string{
+	CCTV:        "CCTV",
+	Fingerprint: "FP",
+	KomputerPH1: "PC1",
+	KomputerPH2: "PC2",
+	Printer:     "PRN",
+	Telepon:     "TEL",
+	Toa:         "TOA",
+	Ups:         "UPS",
+}
+
+// TagDevice returns the device type of an asset tag like "PRN-00042", or ""
+// when the prefix is unknown.
+func TagDevice(tag string) string {
+	prefix, _, ok := strings.Cut(tag, "-")
+	if !ok {
+		return ""
+	}
+	for device, p := range DeviceTagPrefixes {
+		if p == prefix {
+			return device
+		}
+	}
+	return ""
+}
+
 func ValidDevice(device string) bool {
 	switch device {
 	case CCTV, Fingerprint, KomputerPH1, KomputerPH2, Printer, Telepon, Toa, Ups:
@@ -24,3 +141,83 @@ func ValidDevice(device string) bool {
 		return false
 	}
 }
+
+func ValidDocStatus(status string) bool {
+	switch status {
+	case DocDraft, DocSubmitted, DocApproved, DocRejected:
+		return true
+	default:
+		return false
+	}
+}
+
+func ValidDeviceStatus(status string) bool {
+	switch status {
+	case DeviceActive, DeviceInRepair, DeviceSpare, DeviceRetired, DeviceDisposed:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransition reports whether a device can move from one lifecycle status
+// to another.
+func CanTransition(from, to string) bool {
+	for _, status := range DeviceTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidLocationKind(kind string) bool {
+	switch kind {
+	case LocationSite, LocationArea, LocationRoom:
+		return true
+	default:
+		return false
+	}
+}
+
+func ValidTicketStatus(status string) bool {
+	switch status {
+	case TicketOpen, TicketInProgress, TicketWaitingParts, TicketResolved:
+		return true
+	default:
+		return false
+	}
+}
+
+func ValidCPType(cpType string) bool {
+	switch cpType {
+	case CPBoolean, CPNumber, CPEnum, CPText:
+		return true
+	default:
+		return false
+	}
+}
+
+func ValidFieldType(fieldType string) bool {
+	switch fieldType {
+	case FieldText, FieldNumber, FieldBoolean, FieldDate:
+		return true
+	default:
+		return false
+	}
+}
+
+// DeviceFromParam converts a route segment such as "komputer-ph1" to its
+// device type, returning an empty string when it is not a valid device.
+func DeviceFromParam(param string) string {
+	device := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(param)), "-", "_")
+	if !ValidDevice(device) {
+		return ""
+	}
+	return device
+}
+
+// DeviceParam converts a device type to its route segment, e.g. "komputer-ph1".
+func DeviceParam(device string) string {
+	return strings.ReplaceAll(device, "_", "-")
+}
@@ -0,0 +1,45 @@
+package _const
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{DeviceActive, DeviceInRepair, true},
+		{DeviceActive, DeviceSpare, true},
+		{DeviceActive, DeviceRetired, true},
+		{DeviceActive, DeviceDisposed, false},
+		{DeviceActive, DeviceActive, false},
+		{DeviceInRepair, DeviceActive, true},
+		{DeviceInRepair, DeviceRetired, true},
+		{DeviceInRepair, DeviceSpare, false},
+		{DeviceSpare, DeviceActive, true},
+		{DeviceSpare, DeviceRetired, true},
+		{DeviceSpare, DeviceInRepair, false},
+		{DeviceRetired, DeviceDisposed, true},
+		{DeviceRetired, DeviceActive, false},
+		{DeviceDisposed, DeviceActive, false},
+		{DeviceDisposed, DeviceRetired, false},
+		{"", DeviceActive, false},
+		{DeviceActive, "", false},
+		{DeviceActive, "broken", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisposedIsFinal(t *testing.T) {
+	for _, to := range []string{DeviceActive, DeviceInRepair, DeviceSpare, DeviceRetired, DeviceDisposed} {
+		if CanTransition(DeviceDisposed, to) {
+			t.Errorf("CanTransition(%q, %q) = true, want disposed final", DeviceDisposed, to)
+		}
+	}
+}
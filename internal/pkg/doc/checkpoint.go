@@ -0,0 +1,212 @@
+package doc
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"math"
+	"net/http"
+	"sipamit-be/internal/pkg/const"
+	"strconv"
+	"strings"
+)
+
+// CPItem declares the result type of a checkpoint template item. Number
+// results are OK within Min and Max, enum results when they are one of Pass.
+// Boolean and text results, and enum results without Pass, keep the OK the
+// technician submitted. Items without a CPItem are boolean.
+type CPItem struct {
+	Name    string   `json:"name" bson:"name"`
+	Type    string   `json:"type" bson:"type" enums:"boolean,number,enum,text"`
+	Unit    string   `json:"unit,omitempty" bson:"unit,omitempty"`
+	Min     *float64 `json:"min,omitempty" bson:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty" bson:"max,omitempty"`
+	Options []string `json:"options,omitempty" bson:"options,omitempty"`
+	Pass    []string `json:"pass,omitempty" bson:"pass,omitempty"`
+}
+
+// ValidateCPItems checks the item declarations of a template with the given
+// item names. Names are normalized to the template spelling and an empty
+// type defaults to boolean.
+func ValidateCPItems(template []string, items []CPItem) error {
+	var errs []CPError
+
+	known := make(map[string]string, len(template))
+	for _, name := range template {
+		known[strings.ToLower(strings.TrimSpace(name))] = name
+	}
+
+	seen := make(map[string]bool, len(items))
+	for i := range items {
+		item := &items[i]
+		field := fmt.Sprintf("items[%d]", i)
+		key := strings.ToLower(strings.TrimSpace(item.Name))
+
+		name, ok := known[key]
+		if !ok {
+			errs = append(errs, CPError{Field: field + ".name", Name: item.Name, Message: "Unknown checkpoint"})
+			continue
+		}
+		if seen[key] {
+			errs = append(errs, CPError{Field: field + ".name", Name: item.Name, Message: "Duplicate checkpoint"})
+			continue
+		}
+		seen[key] = true
+		item.Name = name
+
+		item.Type = strings.ToLower(strings.TrimSpace(item.Type))
+		if item.Type == "" {
+			item.Type = _const.CPBoolean
+		}
+		if !_const.ValidCPType(item.Type) {
+			errs = append(errs, CPError{Field: field + ".type", Name: name, Message: "Invalid type"})
+			continue
+		}
+
+		if msg := item.validate(); msg != "" {
+			errs = append(errs, CPError{Field: field, Name: name, Message: msg})
+		}
+	}
+
+	if len(errs) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, CPValidationError{
+			Message: "Invalid checkpoint items",
+			Errors:  errs,
+		})
+	}
+	return nil
+}
+
+// validate checks the type specific fields, clearing the ones that do not
+// apply to the type.
+func (item *CPItem) validate() string {
+	item.Unit = strings.TrimSpace(item.Unit)
+
+	switch item.Type {
+	case _const.CPNumber:
+		item.Options, item.Pass = nil, nil
+		if item.Min != nil && item.Max != nil && *item.Min > *item.Max {
+			return "Min must not be greater than max"
+		}
+	case _const.CPEnum:
+		item.Unit, item.Min, item.Max = "", nil, nil
+		if len(item.Options) == 0 {
+			return "Options are required"
+		}
+
+		options := make(map[string]bool, len(item.Options))
+		for i, option := range item.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || options[strings.ToLower(option)] {
+				return "Options must be unique and not empty"
+			}
+			options[strings.ToLower(option)] = true
+			item.Options[i] = option
+		}
+		for i, pass := range item.Pass {
+			option, ok := item.option(pass)
+			if !ok {
+				return fmt.Sprintf("Pass option %q is not one of the options", pass)
+			}
+			item.Pass[i] = option
+		}
+	default:
+		item.Unit, item.Min, item.Max, item.Options, item.Pass = "", nil, nil, nil, nil
+	}
+	return ""
+}
+
+// option returns the option matching value in its declared spelling.
+func (item *CPItem) option(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range item.Options {
+		if strings.EqualFold(option, value) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+// apply normalizes the value of a result to the item type and derives OK from
+// the thresholds, returning an error message when the value does not fit.
+func (item *CPItem) apply(cp *CPDetail) string {
+	switch item.Type {
+	case _const.CPNumber:
+		v, ok := number(cp.Value)
+		if !ok {
+			return "Value must be a number"
+		}
+		cp.Value = v
+		cp.Unit = item.Unit
+		cp.OK = (item.Min == nil || v >= *item.Min) && (item.Max == nil || v <= *item.Max)
+	case _const.CPEnum:
+		s, _ := cp.Value.(string)
+		option, ok := item.option(s)
+		if !ok {
+			return "Value must be one of " + strings.Join(item.Options, ", ")
+		}
+		cp.Value = option
+		cp.Unit = ""
+		if len(item.Pass) > 0 {
+			cp.OK = false
+			for _, pass := range item.Pass {
+				if pass == option {
+					cp.OK = true
+					break
+				}
+			}
+		}
+	case _const.CPText:
+		s, _ := cp.Value.(string)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return "Value is required"
+		}
+		cp.Value = s
+		cp.Unit = ""
+	default:
+		cp.Value = nil
+		cp.Unit = ""
+	}
+	return ""
+}
+
+// number reads a submitted number, accepting numeric strings with a decimal
+// comma as they are typed on Indonesian keyboards. NaN and infinities, which
+// ParseFloat accepts, are not measurements and are refused.
+func number(value interface{}) (float64, bool) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case string:
+		var err error
+		f, err = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", "."), 64)
+		if err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// ValueText returns the value of a measured result with its unit, or an
+// empty string for boolean results.
+func (cp CPDetail) ValueText() string {
+	switch v := cp.Value.(type) {
+	case nil:
+		return ""
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if cp.Unit != "" {
+			s += " " + cp.Unit
+		}
+		return s
+	default:
+		return fmt.Sprint(v)
+	}
+}
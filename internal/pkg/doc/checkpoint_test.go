@@ -0,0 +1,71 @@
+package doc
+
+import (
+	"math"
+	"sipamit-be/internal/pkg/const"
+	"testing"
+)
+
+func TestCPItemApply(t *testing.T) {
+	voltage := CPItem{Type: _const.CPNumber, Unit: "V", Min: float(210), Max: float(230)}
+	atLeast := CPItem{Type: _const.CPNumber, Min: float(10)}
+	kondisi := CPItem{Type: _const.CPEnum, Options: []string{"Baik", "Rusak", "Kotor"}, Pass: []string{"Baik"}}
+	noPass := CPItem{Type: _const.CPEnum, Options: []string{"Ya", "Tidak"}}
+
+	tests := []struct {
+		name   string
+		item   CPItem
+		cp     CPDetail
+		msg    string
+		ok     bool
+		value  interface{}
+		unit   string
+		keepOK bool
+	}{
+		{name: "number within range", item: voltage, cp: CPDetail{Value: 220.0}, ok: true, value: 220.0, unit: "V"},
+		{name: "number at min", item: voltage, cp: CPDetail{Value: 210.0}, ok: true, value: 210.0, unit: "V"},
+		{name: "number at max", item: voltage, cp: CPDetail{Value: 230.0}, ok: true, value: 230.0, unit: "V"},
+		{name: "number below min", item: voltage, cp: CPDetail{Value: 209.9, OK: true}, ok: false, value: 209.9, unit: "V"},
+		{name: "number above max", item: voltage, cp: CPDetail{Value: 231.0, OK: true}, ok: false, value: 231.0, unit: "V"},
+		{name: "number without max", item: atLeast, cp: CPDetail{Value: 1e9}, ok: true, value: 1e9},
+		{name: "number from int", item: voltage, cp: CPDetail{Value: int64(220)}, ok: true, value: 220.0, unit: "V"},
+		{name: "number with decimal comma", item: voltage, cp: CPDetail{Value: " 220,5 "}, ok: true, value: 220.5, unit: "V"},
+		{name: "number not numeric", item: voltage, cp: CPDetail{Value: "abc"}, msg: "Value must be a number"},
+		{name: "number missing", item: voltage, cp: CPDetail{}, msg: "Value must be a number"},
+		{name: "number NaN", item: voltage, cp: CPDetail{Value: "NaN"}, msg: "Value must be a number"},
+		{name: "number infinite", item: atLeast, cp: CPDetail{Value: "+Inf"}, msg: "Value must be a number"},
+		{name: "number infinite float", item: atLeast, cp: CPDetail{Value: math.Inf(1)}, msg: "Value must be a number"},
+		{name: "enum pass option", item: kondisi, cp: CPDetail{Value: "baik"}, ok: true, value: "Baik"},
+		{name: "enum fail option", item: kondisi, cp: CPDetail{Value: "Rusak", OK: true}, ok: false, value: "Rusak"},
+		{name: "enum unknown option", item: kondisi, cp: CPDetail{Value: "Hilang"}, msg: "Value must be one of Baik, Rusak, Kotor"},
+		{name: "enum without pass keeps ok", item: noPass, cp: CPDetail{Value: "Tidak", OK: true}, ok: true, value: "Tidak", keepOK: true},
+		{name: "text trimmed", item: CPItem{Type: _const.CPText}, cp: CPDetail{Value: " catatan ", OK: true}, ok: true, value: "catatan", keepOK: true},
+		{name: "text empty", item: CPItem{Type: _const.CPText}, cp: CPDetail{Value: " "}, msg: "Value is required"},
+		{name: "boolean clears value", item: CPItem{Type: _const.CPBoolean}, cp: CPDetail{Value: "x", Unit: "V", OK: true}, ok: true, keepOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp := tt.cp
+			msg := tt.item.apply(&cp)
+			if msg != tt.msg {
+				t.Fatalf("apply() = %q, want %q", msg, tt.msg)
+			}
+			if msg != "" {
+				return
+			}
+			if cp.OK != tt.ok {
+				t.Errorf("OK = %v, want %v", cp.OK, tt.ok)
+			}
+			if tt.keepOK && cp.OK != tt.cp.OK {
+				t.Errorf("OK = %v, want the submitted %v", cp.OK, tt.cp.OK)
+			}
+			if cp.Value != tt.value {
+				t.Errorf("Value = %v, want %v", cp.Value, tt.value)
+			}
+			if cp.Unit != tt.unit {
+				t.Errorf("Unit = %q, want %q", cp.Unit, tt.unit)
+			}
+		})
+	}
+}
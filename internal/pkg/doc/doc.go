@@ -2,12 +2,15 @@ package doc
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"net/http"
 	"sipamit-be/api/app/repo"
 	_db "sipamit-be/internal/db"
+	"sipamit-be/internal/pkg/const"
 	"sipamit-be/internal/pkg/log"
+	"strings"
 	"time"
 )
 
@@ -38,15 +41,90 @@ func (u *ByAt) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// CPDetail is the result of one checkpoint item. Value holds the measured
+// number, enum option or text of typed items, with the unit of numbers.
 type CPDetail struct {
-	Name       string `json:"name" bson:"name"`
-	OK         bool   `json:"ok" bson:"ok"`
-	Keterangan string `json:"keterangan" bson:"keterangan"`
+	Name       string      `json:"name" bson:"name"`
+	OK         bool        `json:"ok" bson:"ok"`
+	Value      interface{} `json:"value,omitempty" bson:"value,omitempty"`
+	Unit       string      `json:"unit,omitempty" bson:"unit,omitempty"`
+	Keterangan string      `json:"keterangan" bson:"keterangan"`
+}
+
+type CPError struct {
+	Field   string `json:"field"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+type CPValidationError struct {
+	Message string    `json:"message"`
+	Errors  []CPError `json:"errors"`
+}
+
+// ValidateCheckpoint checks submitted checkpoint results against the device
+// type's template. Every template item is required, unknown and duplicated
+// items are rejected. Matching names are normalized to the template spelling
+// and the values of typed items are checked against their declaration in
+// items, deriving OK from the thresholds.
+func ValidateCheckpoint(template []string, items []CPItem, checkpoint []CPDetail) error {
+	var errs []CPError
+
+	known := make(map[string]string, len(template))
+	for _, name := range template {
+		known[strings.ToLower(strings.TrimSpace(name))] = name
+	}
+
+	declared := make(map[string]*CPItem, len(items))
+	for i := range items {
+		declared[strings.ToLower(strings.TrimSpace(items[i].Name))] = &items[i]
+	}
+
+	seen := make(map[string]bool, len(checkpoint))
+	for i, cp := range checkpoint {
+		field := fmt.Sprintf("checkpoint[%d].name", i)
+		key := strings.ToLower(strings.TrimSpace(cp.Name))
+
+		name, ok := known[key]
+		if !ok {
+			errs = append(errs, CPError{Field: field, Name: cp.Name, Message: "Unknown checkpoint"})
+			continue
+		}
+		if seen[key] {
+			errs = append(errs, CPError{Field: field, Name: cp.Name, Message: "Duplicate checkpoint"})
+			continue
+		}
+		seen[key] = true
+		checkpoint[i].Name = name
+
+		item, ok := declared[key]
+		if !ok {
+			item = &CPItem{Name: name, Type: _const.CPBoolean}
+		}
+		if msg := item.apply(&checkpoint[i]); msg != "" {
+			errs = append(errs, CPError{Field: fmt.Sprintf("checkpoint[%d].value", i), Name: name, Message: msg})
+		}
+	}
+
+	for _, name := range template {
+		if !seen[strings.ToLower(strings.TrimSpace(name))] {
+			errs = append(errs, CPError{Field: "checkpoint", Name: name, Message: "Checkpoint is required"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, CPValidationError{
+			Message: "Invalid checkpoint",
+			Errors:  errs,
+		})
+	}
+	return nil
 }
 
 type DeviceDocForm struct {
 	DeviceID   string     `form:"device_id" json:"device_id"`
 	Checkpoint []CPDetail `form:"checkpoint" json:"checkpoint"`
+	Submit     bool       `form:"submit" json:"submit"`
 
 	DeviceOID bson.ObjectID `form:"-" json:"-"`
 }
@@ -59,21 +137,77 @@ func NewDeviceDocForm(c echo.Context) (*DeviceDocForm, error) {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
 	}
 
+	err = f.validate()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *DeviceDocForm) validate() error {
+	var err error
 	if f.DeviceID == "" {
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
+		return echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
 
 	f.DeviceOID, err = bson.ObjectIDFromHex(f.DeviceID)
 	if err != nil {
-		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid device id")
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device id")
 	}
 
 	if len(f.Checkpoint) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
+	}
+	return nil
+}
+
+// MaxBulkDocs is the maximum number of docs in one bulk request.
+const MaxBulkDocs = 200
+
+type BulkDeviceDocForm struct {
+	Docs   []DeviceDocForm `form:"docs" json:"docs"`
+	Atomic bool            `form:"atomic" json:"atomic"`
+}
+
+// NewBulkDeviceDocForm binds a bulk form. Entries are validated by Validate
+// so that invalid ones can be reported per item.
+func NewBulkDeviceDocForm(c echo.Context) (*BulkDeviceDocForm, error) {
+	f := new(BulkDeviceDocForm)
+	err := c.Bind(f)
+	if err != nil {
+		log.Errorf("Failed to bind bulk doc form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	if len(f.Docs) == 0 {
 		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
 	}
+	if len(f.Docs) > MaxBulkDocs {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("At most %d docs per request", MaxBulkDocs))
+	}
 	return f, nil
 }
 
+// Validate validates the entry at index i.
+func (f *BulkDeviceDocForm) Validate(i int) error {
+	return f.Docs[i].validate()
+}
+
+type BulkItemResult struct {
+	Index int            `json:"index"`
+	ID    *bson.ObjectID `json:"_id,omitempty"`
+	Error interface{}    `json:"error,omitempty"`
+}
+
+// BulkResult reports the outcome of every entry of a bulk request, with the
+// created docs in Docs.
+type BulkResult struct {
+	Created int              `json:"created"`
+	Failed  int              `json:"failed"`
+	Results []BulkItemResult `json:"results"`
+	Docs    interface{}      `json:"docs"`
+}
+
 type UpdateDeviceDocForm struct {
 	Checkpoint []CPDetail `form:"checkpoint" json:"checkpoint"`
 }
@@ -91,3 +225,75 @@ func NewUpdateDeviceDocForm(c echo.Context) (*UpdateDeviceDocForm, error) {
 	}
 	return f, nil
 }
+
+// CanTransition reports whether a doc in status from may move to status to.
+// Rejected docs go back to review by being submitted again.
+func CanTransition(from, to string) bool {
+	switch to {
+	case _const.DocSubmitted:
+		return from == _const.DocDraft || from == _const.DocRejected
+	case _const.DocApproved, _const.DocRejected:
+		return from == _const.DocSubmitted
+	default:
+		return false
+	}
+}
+
+type RejectDocForm struct {
+	Reason string `form:"reason" json:"reason"`
+}
+
+func NewRejectDocForm(c echo.Context) (*RejectDocForm, error) {
+	f := new(RejectDocForm)
+	err := c.Bind(f)
+	if err != nil {
+		log.Errorf("Failed to bind reject form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	f.Reason = strings.TrimSpace(f.Reason)
+	if f.Reason == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Reason is required")
+	}
+	return f, nil
+}
+
+type CPChange struct {
+	Name   string    `json:"name"`
+	Change string    `json:"change"`
+	Before *CPDetail `json:"before,omitempty"`
+	After  *CPDetail `json:"after,omitempty"`
+}
+
+// DiffCheckpoint returns the checkpoint items that were added, removed or
+// changed between two versions of a doc, in the order of the newer version.
+func DiffCheckpoint(before, after []CPDetail) []CPChange {
+	changes := []CPChange{}
+
+	prev := make(map[string]CPDetail, len(before))
+	for _, cp := range before {
+		prev[strings.ToLower(cp.Name)] = cp
+	}
+
+	seen := make(map[string]bool, len(after))
+	for _, cp := range after {
+		key := strings.ToLower(cp.Name)
+		seen[key] = true
+
+		old, ok := prev[key]
+		if !ok {
+			changes = append(changes, CPChange{Name: cp.Name, Change: "added", After: &cp})
+			continue
+		}
+		if old.OK != cp.OK || old.Keterangan != cp.Keterangan || old.ValueText() != cp.ValueText() {
+			changes = append(changes, CPChange{Name: cp.Name, Change: "changed", Before: &old, After: &cp})
+		}
+	}
+
+	for _, cp := range before {
+		if !seen[strings.ToLower(cp.Name)] {
+			changes = append(changes, CPChange{Name: cp.Name, Change: "removed", Before: &cp})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,103 @@
+package doc
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"reflect"
+	"sipamit-be/internal/pkg/const"
+	"testing"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestValidateCheckpoint(t *testing.T) {
+	template := []string{"Kondisi Kamera", "Tegangan"}
+	items := []CPItem{{Name: "tegangan", Type: _const.CPNumber, Unit: "V", Min: float(210), Max: float(230)}}
+
+	tests := []struct {
+		name       string
+		checkpoint []CPDetail
+		errs       []CPError
+		want       []CPDetail
+	}{
+		{
+			name: "valid results normalized to the template",
+			checkpoint: []CPDetail{
+				{Name: " kondisi kamera ", OK: true},
+				{Name: "TEGANGAN", Value: "220", OK: false},
+			},
+			want: []CPDetail{
+				{Name: "Kondisi Kamera", OK: true},
+				{Name: "Tegangan", Value: 220.0, Unit: "V", OK: true},
+			},
+		},
+		{
+			name: "missing item",
+			checkpoint: []CPDetail{
+				{Name: "Kondisi Kamera", OK: true},
+			},
+			errs: []CPError{{Field: "checkpoint", Name: "Tegangan", Message: "Checkpoint is required"}},
+		},
+		{
+			name: "unknown item",
+			checkpoint: []CPDetail{
+				{Name: "Kondisi Kamera", OK: true},
+				{Name: "Tegangan", Value: 220.0},
+				{Name: "Lensa", OK: true},
+			},
+			errs: []CPError{{Field: "checkpoint[2].name", Name: "Lensa", Message: "Unknown checkpoint"}},
+		},
+		{
+			name: "duplicated item",
+			checkpoint: []CPDetail{
+				{Name: "Kondisi Kamera", OK: true},
+				{Name: "kondisi kamera", OK: false},
+				{Name: "Tegangan", Value: 220.0},
+			},
+			errs: []CPError{{Field: "checkpoint[1].name", Name: "kondisi kamera", Message: "Duplicate checkpoint"}},
+		},
+		{
+			name: "invalid typed value",
+			checkpoint: []CPDetail{
+				{Name: "Kondisi Kamera", OK: true},
+				{Name: "Tegangan", Value: "tinggi"},
+			},
+			errs: []CPError{{Field: "checkpoint[1].value", Name: "Tegangan", Message: "Value must be a number"}},
+		},
+		{
+			name:       "empty",
+			checkpoint: nil,
+			errs: []CPError{
+				{Field: "checkpoint", Name: "Kondisi Kamera", Message: "Checkpoint is required"},
+				{Field: "checkpoint", Name: "Tegangan", Message: "Checkpoint is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCheckpoint(template, items, tt.checkpoint)
+			if tt.errs == nil {
+				if err != nil {
+					t.Fatalf("ValidateCheckpoint() = %v, want nil", err)
+				}
+				if !reflect.DeepEqual(tt.checkpoint, tt.want) {
+					t.Errorf("checkpoint = %+v, want %+v", tt.checkpoint, tt.want)
+				}
+				return
+			}
+
+			var he *echo.HTTPError
+			if !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+				t.Fatalf("ValidateCheckpoint() = %v, want a 400 error", err)
+			}
+			ve, _ := he.Message.(CPValidationError)
+			if !reflect.DeepEqual(ve.Errors, tt.errs) {
+				t.Errorf("errors = %+v, want %+v", ve.Errors, tt.errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package doc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// UndoInsertMany removes the records an ordered InsertMany of ids into coll
+// wrote before failing with err, and returns err along with any failure to
+// remove them. A write error at an index means the records before it were
+// inserted and the rest were not, so a record refused as a duplicate of an
+// existing one is never removed.
+func UndoInsertMany(coll *mongo.Collection, ids []bson.ObjectID, err error) error {
+	inserted := ids
+
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) && len(bwe.WriteErrors) > 0 {
+		first := len(ids)
+		for _, we := range bwe.WriteErrors {
+			first = min(first, we.Index)
+		}
+		inserted = ids[:max(first, 0)]
+	}
+	if len(inserted) == 0 {
+		return err
+	}
+
+	_, derr := coll.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": inserted}})
+	if derr != nil {
+		return errors.Join(err, fmt.Errorf("remove %d inserted records: %w", len(inserted), derr))
+	}
+	return err
+}
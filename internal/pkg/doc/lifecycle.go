@@ -0,0 +1,110 @@
+package doc
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"sipamit-be/internal/pkg/const"
+)
+
+// StatusChange is a lifecycle transition of a device, kept in its status
+// history.
+type StatusChange struct {
+	From    string `json:"from" bson:"from"`
+	To      string `json:"to" bson:"to"`
+	Reason  string `json:"reason" bson:"reason"`
+	Changed ByAt   `json:"changed" bson:"changed"`
+}
+
+// DeviceStatus returns the lifecycle status of a device. Devices written
+// before the status was tracked are active.
+func DeviceStatus(status string) string {
+	if status == "" {
+		return _const.DeviceActive
+	}
+	return status
+}
+
+// StatusFilter returns the status filter of the devices in the given
+// lifecycle status.
+func StatusFilter(status string) interface{} {
+	if status == _const.DeviceActive {
+		return bson.M{"$in": bson.A{nil, "", _const.DeviceActive}}
+	}
+	return status
+}
+
+// InServiceFilter returns the status filter of the devices neither retired
+// nor disposed.
+func InServiceFilter() bson.M {
+	return bson.M{"$nin": bson.A{_const.DeviceRetired, _const.DeviceDisposed}}
+}
+
+// FindStatus returns the lifecycle status of the device with the given id.
+func FindStatus(coll *mongo.Collection, id bson.ObjectID) (string, error) {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+	}
+	findOptions := options.FindOne().SetProjection(bson.M{"status": 1})
+
+	var record struct {
+		Status string `bson:"status"`
+	}
+	err := coll.FindOne(context.TODO(), filter, findOptions).Decode(&record)
+	if err != nil {
+		return "", err
+	}
+	return DeviceStatus(record.Status), nil
+}
+
+// SetStatus moves the device with the given id to change.To and appends the
+// change to its status history. It returns mongo.ErrNoDocuments when the
+// device is no longer in change.From, so concurrent transitions cannot both
+// apply.
+func SetStatus(coll *mongo.Collection, id bson.ObjectID, change StatusChange) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": bson.M{"$ne": true},
+		"status":     StatusFilter(change.From),
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      change.To,
+			"modified_at": Stamp(),
+		},
+		"$push": bson.M{
+			"status_history": change,
+		},
+	}
+
+	res, err := coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// InitStatus marks the devices of coll written before the status was tracked
+// as active.
+func InitStatus(coll *mongo.Collection) (int64, error) {
+	filter := bson.M{
+		"status": bson.M{"$in": bson.A{nil, ""}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      _const.DeviceActive,
+			"modified_at": Stamp(),
+		},
+	}
+
+	res, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
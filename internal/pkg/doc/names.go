@@ -0,0 +1,30 @@
+package doc
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"sipamit-be/api/app/repo"
+	_db "sipamit-be/internal/db"
+)
+
+// UserNames caches the full names of users by id, so a batch looks up each
+// user once however many records they wrote.
+type UserNames map[bson.ObjectID]string
+
+// FullName returns the full name of the user of u, the way MarshalJSON
+// resolves it, or an empty string when there is none.
+func (n UserNames) FullName(u *ByAt) string {
+	if u == nil || u.ID == nil {
+		return ""
+	}
+	if name, ok := n[*u.ID]; ok {
+		return name
+	}
+
+	var name string
+	user, err := repo.NewUserRepository(_db.Client).FindByID(*u.ID)
+	if err == nil {
+		name = user.FullName
+	}
+	n[*u.ID] = name
+	return name
+}
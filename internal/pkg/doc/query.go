@@ -0,0 +1,158 @@
+package doc
+
+import (
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"net/http"
+	"regexp"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/util"
+	"strings"
+	"time"
+)
+
+const queryDateLayout = "2006-01-02"
+
+var sortFieldPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// DocQuery is the filter set shared by the doc lists of every device type.
+type DocQuery struct {
+	*util.CommonQuery
+	From             *time.Time
+	To               *time.Time
+	TechnicianID     *bson.ObjectID
+	Lokasi           string
+	Departemen       string
+	Failed           bool
+	FailedCheckpoint string
+	SortBy           string
+}
+
+func NewDocQuery(c echo.Context) (*DocQuery, error) {
+	cq, err := util.NewStatusQuery(c, _const.ValidDocStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	dq := &DocQuery{
+		CommonQuery:      cq,
+		Lokasi:           strings.TrimSpace(c.QueryParam("lokasi")),
+		Departemen:       strings.TrimSpace(c.QueryParam("departemen")),
+		Failed:           strings.ToLower(strings.TrimSpace(c.QueryParam("failed"))) == "true",
+		FailedCheckpoint: strings.TrimSpace(c.QueryParam("failed_checkpoint")),
+	}
+
+	if s := strings.TrimSpace(c.QueryParam("from")); s != "" {
+		t, err := time.ParseInLocation(queryDateLayout, s, time.Local)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid from date")
+		}
+		dq.From = &t
+	}
+
+	// to is inclusive, so the filter ends at the start of the next day
+	if s := strings.TrimSpace(c.QueryParam("to")); s != "" {
+		t, err := time.ParseInLocation(queryDateLayout, s, time.Local)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid to date")
+		}
+		t = t.AddDate(0, 0, 1)
+		dq.To = &t
+	}
+
+	if s := strings.TrimSpace(c.QueryParam("technician_id")); s != "" {
+		oId, err := bson.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid technician id")
+		}
+		dq.TechnicianID = &oId
+	}
+
+	sortBy := strings.ToLower(strings.TrimSpace(c.QueryParam("sort_by")))
+	if sortBy != "" && sortBy != "date" && !sortFieldPattern.MatchString(sortBy) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid sort field")
+	}
+	dq.SortBy = sortBy
+
+	return dq, nil
+}
+
+// Filter returns the doc filter, matching Q against the qField snapshot field.
+func (dq *DocQuery) Filter(qField string) bson.M {
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+
+	if len(dq.Q) > 0 {
+		var pattern = bson.Regex{Pattern: dq.Q, Options: "i"}
+		filter[qField] = bson.M{"$regex": pattern}
+	}
+
+	if dq.Status != "" {
+		filter["status"] = dq.Status
+	}
+
+	if dq.From != nil || dq.To != nil {
+		inserted := bson.M{}
+		if dq.From != nil {
+			inserted["$gte"] = *dq.From
+		}
+		if dq.To != nil {
+			inserted["$lt"] = *dq.To
+		}
+		filter["inserted.at"] = inserted
+	}
+
+	if dq.TechnicianID != nil {
+		filter["inserted._id"] = *dq.TechnicianID
+	}
+
+	if dq.Lokasi != "" {
+		filter["lokasi"] = bson.M{"$regex": bson.Regex{Pattern: regexp.QuoteMeta(dq.Lokasi), Options: "i"}}
+	}
+
+	if dq.Departemen != "" {
+		filter["departemen"] = bson.M{"$regex": bson.Regex{Pattern: regexp.QuoteMeta(dq.Departemen), Options: "i"}}
+	}
+
+	if dq.FailedCheckpoint != "" {
+		filter["checkpoint"] = bson.M{"$elemMatch": bson.M{
+			"name": bson.Regex{Pattern: "^" + regexp.QuoteMeta(dq.FailedCheckpoint) + "$", Options: "i"},
+			"ok":   false,
+		}}
+	} else if dq.Failed {
+		filter["checkpoint.ok"] = false
+	}
+	return filter
+}
+
+// FindOptions returns the pagination and sort options. Docs are sorted by _id
+// unless sort_by names the date or a snapshot field.
+func (dq *DocQuery) FindOptions() (*options.FindOptionsBuilder, error) {
+	findOptions, err := util.BuildPaginationAndOrderOptionByField(nil, dq.Page, dq.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions.SetSort(dq.sort())
+	return findOptions, nil
+}
+
+// ExportOptions returns the sort options of FindOptions without pagination,
+// for reading every matching doc.
+func (dq *DocQuery) ExportOptions() *options.FindOptionsBuilder {
+	return options.Find().SetSort(dq.sort())
+}
+
+func (dq *DocQuery) sort() bson.D {
+	order := dq.CommonQuery.Sort
+	switch dq.SortBy {
+	case "":
+		return bson.D{{Key: "_id", Value: order}}
+	case "date":
+		return bson.D{{Key: "inserted.at", Value: order}, {Key: "_id", Value: order}}
+	default:
+		return bson.D{{Key: dq.SortBy, Value: order}, {Key: "_id", Value: order}}
+	}
+}
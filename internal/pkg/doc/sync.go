@@ -0,0 +1,176 @@
+package doc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"net/http"
+	"sipamit-be/internal/pkg/const"
+	"sipamit-be/internal/pkg/log"
+	"strconv"
+	"time"
+)
+
+// Stamp returns the time stored in modified_at on every write. It is cut to
+// the millisecond precision of MongoDB dates, so a stamp read back from the
+// database compares equal to the one sent to the client.
+func Stamp() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+// ModifiedSince returns the filter of the records written at or after since,
+// or of every record when since is zero.
+func ModifiedSince(since time.Time) bson.M {
+	filter := bson.M{
+		"is_deleted": bson.M{"$ne": true},
+	}
+	if !since.IsZero() {
+		filter["modified_at"] = bson.M{"$gte": since}
+	}
+	return filter
+}
+
+// FindDeletedSince returns the ids of the records of coll soft deleted at or
+// after since.
+func FindDeletedSince(coll *mongo.Collection, since time.Time) ([]bson.ObjectID, error) {
+	filter := bson.M{
+		"is_deleted":  true,
+		"modified_at": bson.M{"$gte": since},
+	}
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cur, err := coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var records []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err = cur.All(context.TODO(), &records)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// InitModifiedAt stamps the records of coll written before modified_at was
+// tracked with their last update or insert date.
+func InitModifiedAt(coll *mongo.Collection) (int64, error) {
+	filter := bson.M{
+		"modified_at": bson.M{"$exists": false},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"modified_at": bson.M{"$ifNull": bson.A{
+				"$updated.at",
+				bson.M{"$ifNull": bson.A{"$inserted.at", "$$NOW"}},
+			}},
+		}}},
+	}
+
+	res, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// syncOverlap is subtracted from the time a pull started when issuing its
+// cursor, so writes stamped just before the pull but committed after it are
+// returned by the next pull as well.
+const syncOverlap = 5 * time.Second
+
+// NewSyncCursor returns the cursor of a pull that started at start.
+func NewSyncCursor(start time.Time) string {
+	ms := start.Add(-syncOverlap).UnixMilli()
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(ms, 10)))
+}
+
+// ParseSyncCursor returns the time a cursor issued by NewSyncCursor resumes
+// from, or the zero time for an empty cursor.
+func ParseSyncCursor(cursor string) (time.Time, error) {
+	if cursor == "" {
+		return time.Time{}, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+
+	ms, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// SyncDocForm is a doc created or edited offline. ID is generated by the
+// client so a retried push finds the doc it already created. Base is the
+// modified_at of the server version the edit started from and is empty for
+// docs created offline. Submit only applies when the doc is created.
+type SyncDocForm struct {
+	DeviceDocForm
+	Device string     `form:"device" json:"device"`
+	ID     string     `form:"_id" json:"_id"`
+	Base   *time.Time `form:"base" json:"base"`
+
+	OID bson.ObjectID `form:"-" json:"-"`
+}
+
+func (f *SyncDocForm) validate() error {
+	var err error
+	if !_const.ValidDevice(f.Device) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid device")
+	}
+
+	f.OID, err = bson.ObjectIDFromHex(f.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid document ID")
+	}
+
+	if f.Base != nil {
+		base := f.Base.Truncate(time.Millisecond)
+		f.Base = &base
+	}
+	return f.DeviceDocForm.validate()
+}
+
+type SyncPushForm struct {
+	Docs []SyncDocForm `form:"docs" json:"docs"`
+}
+
+// NewSyncPushForm binds a push form. Entries are validated by Validate so
+// that invalid ones can be reported per item.
+func NewSyncPushForm(c echo.Context) (*SyncPushForm, error) {
+	f := new(SyncPushForm)
+	err := c.Bind(f)
+	if err != nil {
+		log.Errorf("Failed to bind sync push form: %v", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid request")
+	}
+
+	if len(f.Docs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Please fill provided field")
+	}
+	if len(f.Docs) > MaxBulkDocs {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("At most %d docs per request", MaxBulkDocs))
+	}
+	return f, nil
+}
+
+// Validate validates the entry at index i.
+func (f *SyncPushForm) Validate(i int) error {
+	return f.Docs[i].validate()
+}
@@ -0,0 +1,156 @@
+package doc
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+// TrashRecord is a soft deleted record as listed in the trash. Name is the
+// first of the name fields the record has.
+type TrashRecord struct {
+	ID      bson.ObjectID `json:"_id" bson:"_id"`
+	Name    string        `json:"name" bson:"name"`
+	Deleted ByAt          `json:"deleted" bson:"deleted"`
+}
+
+// deletedAt is the date a record was deleted as listed. Records deleted
+// before the deleter was tracked fall back to their last write.
+var deletedAt = bson.M{"$ifNull": bson.A{
+	"$deleted.at",
+	bson.M{"$ifNull": bson.A{
+		"$modified_at",
+		bson.M{"$ifNull": bson.A{"$updated.at", "$inserted.at"}},
+	}},
+}}
+
+// FindTrash returns the soft deleted records of coll, latest deleted first.
+func FindTrash(coll *mongo.Collection, nameFields ...string) ([]TrashRecord, error) {
+	var name interface{} = ""
+	for i := len(nameFields) - 1; i >= 0; i-- {
+		name = bson.M{"$ifNull": bson.A{"$" + nameFields[i], name}}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"is_deleted": true}}},
+		{{Key: "$project", Value: bson.M{
+			"name": name,
+			"deleted": bson.M{
+				"_id": "$deleted._id",
+				"at":  deletedAt,
+			},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "deleted.at", Value: -1}, {Key: "_id", Value: -1}}}},
+	}
+
+	cur, err := coll.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var records []TrashRecord
+	err = cur.All(context.TODO(), &records)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		return []TrashRecord{}, nil
+	}
+	return records, nil
+}
+
+// FindTrashedBefore returns the ids of the records of coll deleted before
+// the given date. Records deleted before the deleter was tracked have no
+// deletion date to go by and are skipped until InitDeletedAt dates them.
+func FindTrashedBefore(coll *mongo.Collection, before time.Time) ([]bson.ObjectID, error) {
+	filter := bson.M{
+		"is_deleted": true,
+		"deleted.at": bson.M{"$lt": before},
+	}
+	findOptions := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cur, err := coll.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(context.TODO())
+
+	var records []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	err = cur.All(context.TODO(), &records)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// InitDeletedAt dates the records of coll deleted before the deleter was
+// tracked as deleted now, so the retention counts from the migration rather
+// than purging them at once on their last write.
+func InitDeletedAt(coll *mongo.Collection) (int64, error) {
+	filter := bson.M{
+		"is_deleted": true,
+		"deleted.at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"deleted.at": time.Now()},
+	}
+
+	res, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// Restore undoes the soft delete of a record of coll, returning
+// mongo.ErrNoDocuments when it is not in the trash.
+func Restore(coll *mongo.Collection, id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": true,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted":  false,
+			"modified_at": Stamp(),
+		},
+		"$unset": bson.M{"deleted": ""},
+	}
+
+	res, err := coll.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Purge permanently removes a soft deleted record of coll, returning
+// mongo.ErrNoDocuments when it is not in the trash.
+func Purge(coll *mongo.Collection, id bson.ObjectID) error {
+	filter := bson.M{
+		"_id":        id,
+		"is_deleted": true,
+	}
+
+	res, err := coll.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const uniqueSuffix = "_unique"
+
+// Index is an index a repository declares on its collection.
+type Index struct {
+	Name    string
+	Keys    bson.D
+	Unique  bool
+	Partial bson.M
+}
+
+// Unique returns a unique index on field, within the values of the scope
+// fields when given. Soft deleted documents and documents leaving the field
+// empty are not indexed, so they never conflict.
+//
+// Partial indexes can't filter on is_deleted != true, so they filter on
+// is_deleted == false, which Ensure sets on documents missing it.
+func Unique(field string, scope ...string) Index {
+	keys := bson.D{}
+	for _, key := range scope {
+		keys = append(keys, bson.E{Key: key, Value: 1})
+	}
+	keys = append(keys, bson.E{Key: field, Value: 1})
+
+	return Index{
+		Name:   field + uniqueSuffix,
+		Keys:   keys,
+		Unique: true,
+		Partial: bson.M{
+			"is_deleted": false,
+			field:        bson.M{"$gt": ""},
+		},
+	}
+}
+
+// Field returns a plain ascending index on the fields.
+func Field(fields ...string) Index {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return Index{
+		Name: strings.Join(fields, "_") + "_1",
+		Keys: keys,
+	}
+}
+
+// Key returns a unique index on the fields together, over every document of
+// the collection. It guards numbering, like a version or revision per parent.
+func Key(fields ...string) Index {
+	i := Field(fields...)
+	i.Name = strings.Join(fields, "_") + uniqueSuffix
+	i.Unique = true
+	return i
+}
+
+func (i Index) model() mongo.IndexModel {
+	opts := options.Index().SetName(i.Name)
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Partial != nil {
+		opts.SetPartialFilterExpression(i.Partial)
+	}
+	return mongo.IndexModel{Keys: i.Keys, Options: opts}
+}
+
+// conflicts reports whether err is the server refusing an index because one
+// with the same name or keys exists with other options.
+func conflicts(err error) bool {
+	var ce mongo.CommandError
+	if errors.As(err, &ce) {
+		return ce.HasErrorCode(85) || ce.HasErrorCode(86)
+	}
+	return false
+}
+
+// Ensure creates the indexes missing on the collection. An index declared
+// with other options than the existing one is dropped and created again, so
+// changing a declaration is applied on the next start. Creating a unique
+// index fails while documents share a value, the error naming it.
+func Ensure(coll *mongo.Collection, indexes ...Index) error {
+	filter := bson.M{
+		"is_deleted": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{"is_deleted": false},
+	}
+
+	_, err := coll.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		_, err = coll.Indexes().CreateOne(context.TODO(), index.model())
+		if err != nil && conflicts(err) {
+			err = coll.Indexes().DropOne(context.TODO(), index.Name)
+			if err == nil {
+				_, err = coll.Indexes().CreateOne(context.TODO(), index.model())
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("index %s: %w", index.Name, err)
+		}
+	}
+	return nil
+}
+
+var dupIndex = regexp.MustCompile(`index: (\S+)` + uniqueSuffix + ` dup key`)
+
+// DuplicateError returns a 409 error naming the field of the unique index err
+// violates, like "No seri already exists", or nil when err is not a duplicate
+// key error.
+func DuplicateError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	match := dupIndex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return echo.NewHTTPError(http.StatusConflict, "Duplicate value")
+	}
+	field := strings.ReplaceAll(match[1], "_", " ")
+	return echo.NewHTTPError(http.StatusConflict, strings.ToUpper(field[:1])+field[1:]+" already exists")
+}
@@ -0,0 +1,43 @@
+package lock
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+// Process identifies this process as the owner of the locks it takes.
+var Process = bson.NewObjectID().Hex()
+
+// Acquire takes or renews the named lock for this process until ttl from
+// now, reporting false while another process holds it. Background jobs take
+// a lock before every run so only one replica runs them, and a lock whose
+// holder stopped renewing it is taken over once it expires.
+func Acquire(db *mongo.Database, name string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": Process},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":      Process,
+			"expires_at": now.Add(ttl),
+		},
+	}
+
+	// the upsert collides on _id when another process holds the lock
+	_, err := db.Collection("locks").UpdateOne(context.TODO(), filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
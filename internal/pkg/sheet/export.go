@@ -0,0 +1,53 @@
+package sheet
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sipamit-be/internal/pkg/log"
+	"strings"
+	"time"
+)
+
+// Time formats a time for a cell in local time, a zero time as empty.
+func Time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// Export streams a sheet as the response, in the format of the format query
+// parameter, csv by default. each is called to write the rows after the
+// header. Once the first bytes are sent the status can no longer change, so
+// an error while writing is logged and leaves the file truncated.
+func Export(c echo.Context, name string, header []string, each func(write func(row []string) error) error) error {
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	if format == "" {
+		format = CSV
+	}
+	contentType, ok := ContentTypes[format]
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Format must be csv or xlsx")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	res.WriteHeader(http.StatusOK)
+
+	w, err := NewWriter(format, res)
+	if err == nil {
+		err = w.Write(header)
+	}
+	if err == nil {
+		err = each(w.Write)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Errorf("Failed to export %s: %v", name, err)
+	}
+	return nil
+}
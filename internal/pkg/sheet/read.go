@@ -0,0 +1,265 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxSize is the largest spreadsheet Read accepts.
+	MaxSize = 10 << 20
+	// MaxRows and MaxColumns bound the cell references of an XLSX worksheet,
+	// the columns being those of a spreadsheet, A to XFD.
+	MaxRows    = 100_000
+	MaxColumns = 16_384
+
+	// maxPartSize bounds a part of an XLSX file once decompressed, and
+	// maxCells the rows once padded to the same width.
+	maxPartSize = 100 << 20
+	maxCells    = 2 << 20
+)
+
+var (
+	ErrTooLarge = errors.New("sheet: file too large")
+	ErrType     = errors.New("sheet: only csv and xlsx files are supported")
+	ErrRange    = errors.New("sheet: cell reference out of range")
+)
+
+// Read returns the rows of a CSV file, or of the first worksheet of an XLSX
+// file, telling them apart by the extension of filename. Rows are padded to
+// the same width.
+func Read(filename string, r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		rows, err = readCSV(data)
+	case ".xlsx":
+		rows, err = readXLSX(data)
+	default:
+		return nil, ErrType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width*len(rows) > maxCells {
+		return nil, ErrTooLarge
+	}
+	for i := range rows {
+		for len(rows[i]) < width {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	return rows, nil
+}
+
+// readCSV reads comma or semicolon separated values, the latter being what a
+// spreadsheet saves with an Indonesian locale.
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	return r.ReadAll()
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	SI []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string   `xml:"r,attr"`
+			T  string   `xml:"t,attr"`
+			V  string   `xml:"v"`
+			Is xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cell values of the first worksheet as text. Numbers and
+// dates are returned as stored, formulas as their last computed value.
+func readXLSX(data []byte) ([][]string, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("sheet: invalid xlsx file: %w", err)
+	}
+
+	var workbook xlsxWorkbook
+	if err = decodeXML(z, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("sheet: xlsx file has no worksheet")
+	}
+
+	var rels xlsxRels
+	if err = decodeXML(z, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	target := ""
+	for _, rel := range rels.Rels {
+		if rel.ID == workbook.Sheets[0].ID {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared xlsxSharedStrings
+	if err = decodeXML(z, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, errMissing) {
+		return nil, err
+	}
+
+	var worksheet xlsxWorksheet
+	if err = decodeXML(z, target, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range worksheet.Rows {
+		index := row.R - 1
+		if row.R == 0 {
+			index = max(len(rows), i)
+		}
+		if index < 0 || index >= MaxRows {
+			return nil, fmt.Errorf("%w: row %d", ErrRange, row.R)
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.R != "" {
+				column, err = columnIndex(cell.R)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if column >= MaxColumns {
+				return nil, fmt.Errorf("%w: column %d", ErrRange, column+1)
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(cell.V)
+				if err != nil || n < 0 || n >= len(shared.SI) {
+					return nil, fmt.Errorf("sheet: invalid shared string in cell %s", cell.R)
+				}
+				values[column] = shared.SI[n].String()
+			case "inlineStr":
+				values[column] = cell.Is.String()
+			default:
+				values[column] = cell.V
+			}
+		}
+		rows[index] = values
+	}
+	return rows, nil
+}
+
+var errMissing = errors.New("sheet: missing xlsx part")
+
+// decodeXML decodes a part of the XLSX file, reading at most maxPartSize
+// bytes of it so a small file can't inflate to an unbounded one.
+func decodeXML(z *zip.Reader, name string, v interface{}) error {
+	f, err := z.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w %s", errMissing, name)
+	}
+	defer f.Close()
+
+	lr := &io.LimitedReader{R: f, N: maxPartSize}
+	if err = xml.NewDecoder(lr).Decode(v); err != nil {
+		if lr.N == 0 {
+			return ErrTooLarge
+		}
+		return fmt.Errorf("sheet: invalid xlsx part %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the zero based column of a cell reference like "AB12".
+// A reference without a column or beyond MaxColumns is an ErrRange.
+func columnIndex(ref string) (int, error) {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+		if n > MaxColumns {
+			return 0, fmt.Errorf("%w: cell %.16s", ErrRange, ref)
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%w: cell %.16s", ErrRange, ref)
+	}
+	return n - 1, nil
+}
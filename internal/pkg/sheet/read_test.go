@@ -0,0 +1,114 @@
+package sheet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{ref: "A1", want: 0},
+		{ref: "Z9", want: 25},
+		{ref: "AA10", want: 26},
+		{ref: "AB12", want: 27},
+		{ref: "AZ1", want: 51},
+		{ref: "ZZ1", want: 701},
+		{ref: "AAA1", want: 702},
+		{ref: "XFD1", want: MaxColumns - 1},
+		{ref: "B", want: 1},
+		{ref: "XFE1", wantErr: true},
+		{ref: "ZZZZ1", wantErr: true},
+		{ref: "ZZZZZZZZZZZZZZZZZZZZ1", wantErr: true},
+		{ref: "12", wantErr: true},
+		{ref: "a1", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := columnIndex(tt.ref)
+			if tt.wantErr {
+				if !errors.Is(err, ErrRange) {
+					t.Fatalf("columnIndex(%q) = %d, %v, want ErrRange", tt.ref, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("columnIndex(%q) = %d, %v, want %d", tt.ref, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "comma separated",
+			data: "nama,kode\nCCTV Lobby,CAM-01\n",
+			want: [][]string{{"nama", "kode"}, {"CCTV Lobby", "CAM-01"}},
+		},
+		{
+			name: "semicolon separated",
+			data: "nama;kode\nCCTV Lobby;CAM-01\n",
+			want: [][]string{{"nama", "kode"}, {"CCTV Lobby", "CAM-01"}},
+		},
+		{
+			name: "semicolon header with commas in values",
+			data: "nama;lokasi\nCCTV 1, Lobby;Gedung A, Lt 1\n",
+			want: [][]string{{"nama", "lokasi"}, {"CCTV 1, Lobby", "Gedung A, Lt 1"}},
+		},
+		{
+			name: "byte order mark",
+			data: "\xef\xbb\xbfnama,kode\r\nCCTV Lobby,CAM-01\r\n",
+			want: [][]string{{"nama", "kode"}, {"CCTV Lobby", "CAM-01"}},
+		},
+		{
+			name: "quoted values",
+			data: "nama,keterangan\n\"Printer, QA\",\"kata \"\"rusak\"\"\"\n",
+			want: [][]string{{"nama", "keterangan"}, {"Printer, QA", `kata "rusak"`}},
+		},
+		{
+			name: "ragged rows",
+			data: "nama,kode,lokasi\nCCTV Lobby\n",
+			want: [][]string{{"nama", "kode", "lokasi"}, {"CCTV Lobby"}},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: nil,
+		},
+		{
+			name:    "unterminated quote",
+			data:    "nama,kode\n\"CCTV Lobby,CAM-01\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCSV([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readCSV() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCSV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// ContentTypes are the media types of the formats a Writer writes.
+var ContentTypes = map[string]string{
+	CSV:  "text/csv; charset=utf-8",
+	XLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Writer writes rows one at a time, so a large sheet is streamed instead of
+// held in memory. Close must be called to complete the file.
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+// NewWriter returns a Writer of the format to w, the first row written being
+// the header.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w)
+	case XLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, ErrType
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// newCSVWriter starts the file with a byte order mark, without which Excel
+// reads UTF-8 text as the local code page.
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (w *csvWriter) Write(row []string) error {
+	cells := make([]string, len(row))
+	for i, value := range row {
+		cells[i] = escapeFormula(value)
+	}
+	return w.w.Write(cells)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+	// Style 1 is the bold header row.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+	// The header row is frozen so it stays visible while scrolling.
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes a one worksheet workbook. The other parts are fixed and
+// written first, the worksheet is written last as rows come, with every cell
+// an inline string so no shared string table has to be built in memory.
+type xlsxWriter struct {
+	z     *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	z := zip.NewWriter(w)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbookXML},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		f, err := z.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	if _, err = sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{z: z, sheet: sheet}, nil
+}
+
+func (w *xlsxWriter) Write(row []string) error {
+	w.row++
+	style := ""
+	if w.row == 1 {
+		style = ` s="1"`
+	}
+
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.row)
+	for i, value := range row {
+		fmt.Fprintf(w.sheet, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">`, columnName(i), w.row, style)
+		if err := xml.EscapeText(w.sheet, []byte(xmlText(escapeFormula(value)))); err != nil {
+			return err
+		}
+		w.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := w.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.z.Close()
+}
+
+// escapeFormula prefixes a value a spreadsheet would run as a formula with an
+// apostrophe, so a device named "=HYPERLINK(...)" is exported as text.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// xmlText drops the control characters XML can't hold, which would make the
+// worksheet unreadable.
+func xmlText(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r <= 0xD7FF ||
+			r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= 0x10FFFF {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// columnName returns the letters of a zero based column, e.g. 27 as "AB".
+func columnName(i int) string {
+	var b strings.Builder
+	for i++; i > 0; i = (i - 1) / 26 {
+		b.WriteByte(byte('A' + (i-1)%26))
+	}
+	name := []byte(b.String())
+	for l, r := 0, len(name)-1; l < r; l, r = l+1, r-1 {
+		name[l], name[r] = name[r], name[l]
+	}
+	return string(name)
+}
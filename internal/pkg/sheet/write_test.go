@@ -0,0 +1,49 @@
+package sheet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriterEscapes(t *testing.T) {
+	row := []string{"=HYPERLINK(\"http://x\")", "+62 21", "-1", "@SUM(A1)", "CCTV\x00 Lobby\x1b", "Lt 1\nRuang A", ""}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: CSV,
+			want:   []string{"'=HYPERLINK(\"http://x\")", "'+62 21", "'-1", "'@SUM(A1)", "CCTV\x00 Lobby\x1b", "Lt 1\nRuang A", ""},
+		},
+		{
+			format: XLSX,
+			want:   []string{"'=HYPERLINK(\"http://x\")", "'+62 21", "'-1", "'@SUM(A1)", "CCTV Lobby", "Lt 1\nRuang A", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(tt.format, &buf)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			if err = w.Write(row); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err = w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			rows, err := Read("export."+tt.format, &buf)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(rows) != 1 || !reflect.DeepEqual(rows[0], tt.want) {
+				t.Errorf("rows = %q, want [%q]", rows, tt.want)
+			}
+		})
+	}
+}
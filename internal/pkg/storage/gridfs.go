@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"io"
+)
+
+// GridFSBackend stores files in the "attachments" GridFS bucket, using the key
+// as the file ID.
+type GridFSBackend struct {
+	bucket *mongo.GridFSBucket
+}
+
+func NewGridFSBackend(db *mongo.Database) *GridFSBackend {
+	return &GridFSBackend{
+		bucket: db.GridFSBucket(options.GridFSBucket().SetName("attachments")),
+	}
+}
+
+func (b *GridFSBackend) Save(key string, r io.Reader) error {
+	return b.bucket.UploadFromStreamWithID(context.TODO(), key, key, r)
+}
+
+func (b *GridFSBackend) Open(key string) (io.ReadCloser, error) {
+	stream, err := b.bucket.OpenDownloadStream(context.TODO(), key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrFileNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (b *GridFSBackend) Delete(key string) error {
+	err := b.bucket.Delete(context.TODO(), key)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBackend stores files in a directory of the local filesystem.
+type LocalBackend struct {
+	dir string
+}
+
+func NewLocalBackend(dir string) *LocalBackend {
+	return &LocalBackend{
+		dir: dir,
+	}
+}
+
+func (b *LocalBackend) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.Contains(key, "..") {
+		return "", ErrNotFound
+	}
+	return filepath.Join(b.dir, key), nil
+}
+
+func (b *LocalBackend) Save(key string, r io.Reader) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(b.dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(p)
+		return err
+	}
+	return f.Close()
+}
+
+func (b *LocalBackend) Open(key string) (io.ReadCloser, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (b *LocalBackend) Delete(key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
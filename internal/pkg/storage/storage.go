@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"io"
+	"net/http"
+	"sipamit-be/internal/config"
+)
+
+var (
+	ErrNotFound = errors.New("file not found")
+	ErrTooLarge = errors.New("file is too large")
+	ErrType     = errors.New("file type is not allowed")
+)
+
+// AllowedTypes are the content types accepted by Put, detected from the file
+// content rather than the client supplied header.
+var AllowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// Backend stores files by key.
+type Backend interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+type Object struct {
+	Key         string
+	ThumbKey    string
+	ContentType string
+	Size        int64
+}
+
+// Storage enforces the upload limits on top of a Backend and creates image
+// thumbnails.
+type Storage struct {
+	backend Backend
+	maxSize int64
+}
+
+// New returns the storage configured by STORAGE_DRIVER.
+func New(db *mongo.Database) *Storage {
+	var backend Backend
+	switch config.Storage.Driver {
+	case "gridfs":
+		backend = NewGridFSBackend(db)
+	default:
+		backend = NewLocalBackend(config.Storage.Dir)
+	}
+
+	return &Storage{
+		backend: backend,
+		maxSize: config.Storage.MaxSize,
+	}
+}
+
+func (s *Storage) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Put stores the content of r under a new key. Images that can be decoded get
+// a JPEG thumbnail stored next to them.
+func (s *Storage) Put(r io.Reader) (*Object, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !AllowedTypes[contentType] {
+		return nil, ErrType
+	}
+
+	obj := &Object{
+		Key:         bson.NewObjectID().Hex(),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	err = s.backend.Save(obj.Key, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if thumb, ok := thumbnail(data); ok {
+		thumbKey := obj.Key + "_thumb"
+		err = s.backend.Save(thumbKey, bytes.NewReader(thumb))
+		if err != nil {
+			_ = s.backend.Delete(obj.Key)
+			return nil, err
+		}
+		obj.ThumbKey = thumbKey
+	}
+	return obj, nil
+}
+
+func (s *Storage) Open(key string) (io.ReadCloser, error) {
+	return s.backend.Open(key)
+}
+
+func (s *Storage) Delete(keys ...string) error {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		err := s.backend.Delete(key)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// ThumbnailSize is the maximum width and height of a thumbnail.
+const ThumbnailSize = 256
+
+// maxThumbnailPixels caps the size of the images thumbnailed, as a small
+// compressed file can decode to a huge image held in memory whole.
+const maxThumbnailPixels = 40_000_000
+
+// thumbnail decodes data as an image and returns it scaled to fit within
+// ThumbnailSize, encoded as JPEG. ok is false when data is not a supported
+// image or is larger than maxThumbnailPixels.
+func thumbnail(data []byte) ([]byte, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, false
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailPixels {
+		return nil, false
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, false
+	}
+
+	tw, th := w, h
+	if tw > ThumbnailSize || th > ThumbnailSize {
+		if w >= h {
+			tw, th = ThumbnailSize, h*ThumbnailSize/w
+		} else {
+			tw, th = w*ThumbnailSize/h, ThumbnailSize
+		}
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	// average the source pixels covered by each thumbnail pixel
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// colors are alpha-premultiplied, so this composites onto white
+			bg := 0xffff - a/n
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r/n + bg),
+				G: uint16(g/n + bg),
+				B: uint16(bl/n + bg),
+				A: 0xffff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
@@ -5,6 +5,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"math"
+	"net/http"
 	"sipamit-be/internal/pkg/const"
 	"strconv"
 	"strings"
@@ -13,8 +14,19 @@ import (
 type CommonQuery struct {
 	Q      string `query:"q"`
 	Device string `query:"device"`
+	Status string `query:"status"`
 	Sort   int8   `query:"sort"`
 
+	// Location is the id of the location filtered by, LocationIDs is set by
+	// the handler to the ids of the location and every location under it.
+	Location    string `query:"location"`
+	LocationIDs []bson.ObjectID
+
+	// Department is the id of the department filtered by, DepartmentID is
+	// set by the handler once the department is found.
+	Department   string `query:"department"`
+	DepartmentID *bson.ObjectID
+
 	Page  int `query:"page"`
 	Limit int `query:"limit"`
 }
@@ -25,6 +37,8 @@ func NewCommonQuery(c echo.Context) *CommonQuery {
 	page := strings.ToLower(strings.TrimSpace(c.QueryParam("page")))
 	limit := strings.ToLower(strings.TrimSpace(c.QueryParam("limit")))
 	sort := strings.ToLower(strings.TrimSpace(c.QueryParam("sort")))
+	location := strings.TrimSpace(c.QueryParam("location"))
+	department := strings.TrimSpace(c.QueryParam("department"))
 
 	if !_const.ValidDevice(device) {
 		device = ""
@@ -56,13 +70,31 @@ func NewCommonQuery(c echo.Context) *CommonQuery {
 		Page:   pageNum,
 		Limit:  limitNum,
 		Sort:   sortNum,
+
+		Location:   location,
+		Department: department,
 	}
 }
 
+// NewStatusQuery reads the CommonQuery of a list filtered by status, valid
+// telling the statuses of the listed records, e.g. _const.ValidDeviceStatus
+// for devices, so a status of another kind of record is refused.
+func NewStatusQuery(c echo.Context, valid func(string) bool) (*CommonQuery, error) {
+	cq := NewCommonQuery(c)
+
+	status := strings.ToLower(strings.TrimSpace(c.QueryParam("status")))
+	if status != "" && !valid(status) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid status")
+	}
+	cq.Status = status
+	return cq, nil
+}
+
 func NilCommonQuery() *CommonQuery {
 	return &CommonQuery{
 		Q:      "",
 		Device: "",
+		Status: "",
 		Page:   1,
 		Limit:  math.MaxInt,
 		Sort:   1,
@@ -100,6 +132,26 @@ func CalculateTotalPages(totalData, limit, page int) (int, bool) {
 		page > int(math.Ceil(float64(totalData)/float64(limit))) && int(math.Ceil(float64(totalData)/float64(limit))) != 0
 }
 
+// PageBounds returns the slice bounds of a page when paginating in memory.
+func PageBounds(total, page, limit int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	start := (page - 1) * limit
+	if start > total || start < 0 {
+		start = total
+	}
+	end := start + limit
+	if end > total || end < start {
+		end = total
+	}
+	return start, end
+}
+
 func MakeResult(data interface{}, totalData int64, page, limit int) *PaginationResult {
 	totalPages, pageOutOfRange := CalculateTotalPages(int(totalData), limit, page)
 	if pageOutOfRange {
@@ -4,7 +4,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"math/rand"
 	"sipamit-be/internal/pkg/log"
+	"strings"
 	"time"
+	"unicode"
 	"unsafe"
 )
 
@@ -51,3 +53,11 @@ func CryptPassword(text string) string {
 func TimeToMilis(t time.Time) int64 {
 	return t.UnixNano() / 1000000
 }
+
+// NameKey normalizes a name for matching, ignoring case, punctuation and
+// spacing, so "QA-Lab" matches "QA LAB".
+func NameKey(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
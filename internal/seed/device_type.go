@@ -0,0 +1,25 @@
+package seed
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/device_type/repo"
+	"sipamit-be/internal/pkg/doc"
+	"time"
+)
+
+// DeviceType registers the built-in device types, refreshing their schema
+// on every run.
+func DeviceType(db *mongo.Database) {
+	typeRepo := repo.NewDeviceTypeRepository(db)
+
+	for _, deviceType := range repo.BuiltInTypes {
+		deviceType.Inserted = doc.ByAt{At: time.Now()}
+		err := typeRepo.UpsertBuiltIn(&deviceType)
+		if err != nil {
+			log.Errorf("Failed to register %s device type: %v", deviceType.Slug, err)
+			continue
+		}
+	}
+	log.Info("Device types registered")
+}
@@ -508,9 +508,11 @@ var printers = []repo.Printer{
 		Nama:        "HANGGAR BC",
 		Departemen:  "HANGGAR BC",
 		TipePrinter: "BROTHERDCP-T700W-000",
-		NoSeri:      "PH1-BROTHERDCP-T700W-004",
-		Inserted:    doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted:   false,
+		// The source sheet repeats the serial number of another printer for
+		// this one, so it is left empty until checked on the device.
+		NoSeri:    "",
+		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
+		IsDeleted: false,
 	},
 	{
 		ID:          bson.NewObjectID(),
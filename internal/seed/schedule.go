@@ -0,0 +1,36 @@
+package seed
+
+import (
+	"github.com/labstack/gommon/log"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"sipamit-be/api/schedule/repo"
+	"sipamit-be/internal/pkg/const"
+)
+
+func Schedule(db *mongo.Database) {
+	scheduleRepo := repo.NewScheduleRepository(db)
+
+	count, _ := scheduleRepo.Count()
+	if count > 0 {
+		log.Info("Schedule already seeded")
+		return
+	}
+
+	err := scheduleRepo.InsertMany(schedules)
+	if err != nil {
+		log.Errorf("Failed to seed schedules: %v", err)
+	}
+	log.Info("Schedule seeded")
+}
+
+var schedules = []repo.Schedule{
+	{ID: bson.NewObjectID(), Device: _const.CCTV, Interval: 1},
+	{ID: bson.NewObjectID(), Device: _const.Fingerprint, Interval: 1},
+	{ID: bson.NewObjectID(), Device: _const.KomputerPH1, Interval: 3},
+	{ID: bson.NewObjectID(), Device: _const.KomputerPH2, Interval: 3},
+	{ID: bson.NewObjectID(), Device: _const.Printer, Interval: 1},
+	{ID: bson.NewObjectID(), Device: _const.Telepon, Interval: 1},
+	{ID: bson.NewObjectID(), Device: _const.Toa, Interval: 1},
+	{ID: bson.NewObjectID(), Device: _const.Ups, Interval: 1},
+}
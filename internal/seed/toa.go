@@ -269,249 +269,6 @@ var toas = []repo.TOA{
 		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
 		IsDeleted: false,
 	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "AMPLIFIER",
-		Lokasi:    "PH1",
-		Kode:      "PH1-AMPTOA-001",
-		Posisi:    "MEJA OPERATOR",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "AMPLIFIER",
-		Lokasi:    "PH1",
-		Kode:      "PH2-AMPTOA-002",
-		Posisi:    "MEJA OPERATOR",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "HALAMAN LUAR",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-003",
-		Posisi:    "POJOK BARAT GEDUNG LINE A",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LASTING B",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-004",
-		Posisi:    "DIATAS KONVEYOR LASTING B",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "RUANG BORDIR",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-005",
-		Posisi:    "DIATAS RUANG BORDIR SISI TIMUR",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LASTING AB",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-006",
-		Posisi:    "DIBELAKANG LUAR RUANG EXIM",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "GERBANG ATAS JAHIT A",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-007",
-		Posisi:    "DIATAS GERBANG SELATAN JAHIT A",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "DEPAN KANTOR PRODUKSI",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-008",
-		Posisi:    "DIATAS DEPAN KANTOR PRODUKSI",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "PACKING C. D. E",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-009",
-		Posisi:    "DIATAS PINTU LUAR LORONG CRIME",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LASTING C. D. E",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-010",
-		Posisi:    "DIBELAKANG LUAR RUANG HRD",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "GERBANG LINE E",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-011",
-		Posisi:    "DIATAS PANEL LISTRIK LINE E",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "DEPAN SABLON",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-012",
-		Posisi:    "DIATAS LEMARI TAS ( DEPAN SABLON)",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "GUDANG BAHAN",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-013",
-		Posisi:    "DIATAS POJOK BARAT LUAR OFFICE GUDANG BAHAN",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "GUDANG SOLE",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-014",
-		Posisi:    "DIATAS POJOK TIMUR OFFICE GUDANG OUTSOLE",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "SAMPAH",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-015",
-		Posisi:    "DIATAS POJOK UTARA GEDUNG OUTSOLE",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "GUDANG JADI",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-016",
-		Posisi:    "DIATAS LUAR GERBANG GUDANG JADI",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "SABLON INSOLE MIDSOLE",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-017",
-		Posisi:    "DIATAS TANGGA TIMUR INSOLE MIDSOLE",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "OPERATOR",
-		Lokasi:    "PH1",
-		Kode:      "PH1-MICROPHONETOA-001",
-		Posisi:    "MEJA OPERATOR",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LORONG CRIME",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-018",
-		Posisi:    "DIATAS UJUNG LORONG DEKAT PINTU CRIME",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LORONG OFFICE ( DEPAN DAPUR )",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-019",
-		Posisi:    "DEPAN PINTU DAPUR LORONG OFFICE",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "JAHIT DESIGN BAWAH",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-020",
-		Posisi:    "DIATAS PANEL JAHIT DESIGN BAWAH",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LORONG DEPAN K3",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-021",
-		Posisi:    "DEPAN PINTU K3 LORONG LAB DAN ACCOUNTING",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LINE H",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-022",
-		Posisi:    "DIATAS KABEL TRAY SISI TIMUR SELATAN LINE H",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "FOXING",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-023",
-		Posisi:    "DIATAS TEMBOK SISI UTARA FOXING MESIN PRESS",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "LINE F",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-024",
-		Posisi:    "DIATAS POJOK TEMBOK SISI UTARA",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "BENGKEL PISAU",
-		Lokasi:    "PH1",
-		Kode:      "PH1-TOA-025",
-		Posisi:    "DIATAS DEPAN PINTU BENGKEL PISAU",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
-	{
-		ID:        bson.NewObjectID(),
-		Nama:      "AMPLIFIER TOA",
-		Lokasi:    "PH2",
-		Kode:      "PH2-AMPTOA-001",
-		Posisi:    "RAK/MEJA OPERATOR",
-		Inserted:  doc.ByAt{At: time.Now(), ID: &SuperAdminID},
-		IsDeleted: false,
-	},
 	{
 		ID:        bson.NewObjectID(),
 		Nama:      "EQUALIZER TOA",
@@ -72,7 +72,12 @@ func main() {
 	docs.SwaggerInfo.Version = version.Version
 	docs.SwaggerInfo.Host = config.App.SwaggerHost
 
+	err := api.EnsureIndexes(_db.Client)
+	if err != nil {
+		log.Fatalf("Failed to ensure indexes: %v", err)
+	}
 	api.NewInitHandler(e, _db.Client)
+	api.StartJobs(_db.Client)
 
 	log.Fatal(e.Start(fmt.Sprintf(`%v:%v`, config.App.Host, config.App.Port)))
 }
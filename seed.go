@@ -2,6 +2,7 @@ package main
 
 import (
 	_db "sipamit-be/internal/db"
+	"sipamit-be/internal/migration"
 	"sipamit-be/internal/seed"
 )
 
@@ -16,6 +17,18 @@ func main() {
 	seed.Telepon(_db.Client)
 	seed.TOA(_db.Client)
 	seed.UPS(_db.Client)
+	seed.Schedule(_db.Client)
+	seed.DeviceType(_db.Client)
+
+	migration.DeviceDoc(_db.Client)
+	migration.CheckpointVersion(_db.Client)
+	migration.DocStatus(_db.Client)
+	migration.DeviceStatus(_db.Client)
+	migration.Location(_db.Client)
+	migration.Department(_db.Client)
+	migration.AssetTag(_db.Client)
+	migration.ModifiedAt(_db.Client)
+	migration.DeletedAt(_db.Client)
 
 	return
 }